@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
 
 	"mergen/database"
 
@@ -10,6 +12,13 @@ import (
 
 var AppVersion = "v0.0.0-dev"
 
+// Events emitted while a streamed query is running
+const (
+	eventQueryStreamBatch = "query:stream:batch"
+	eventQueryStreamDone  = "query:stream:done"
+	eventTunnelStatus     = "tunnel:status"
+)
+
 // App struct
 type App struct {
 	ctx     context.Context
@@ -32,49 +41,220 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.updater.SetContext(ctx)
+	a.db.SetHostKeyApprover(a.approveHostKey)
+	a.db.SetTunnelStatusHandler(func(status database.TunnelStatus) {
+		runtime.EventsEmit(a.ctx, eventTunnelStatus, status)
+	})
+}
+
+// approveHostKey asks the user whether to trust an SSH host seen for the first time
+func (a *App) approveHostKey(prompt database.HostKeyPrompt) bool {
+	answer, err := runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+		Type:  runtime.QuestionDialog,
+		Title: "Unknown SSH Host",
+		Message: fmt.Sprintf("The authenticity of host %s can't be established.\n%s key fingerprint is %s.\nTrust this host and remember its key?",
+			prompt.Host, prompt.KeyType, prompt.Fingerprint),
+		Buttons:       []string{"Trust", "Cancel"},
+		DefaultButton: "Cancel",
+		CancelButton:  "Cancel",
+	})
+	if err != nil {
+		return false
+	}
+	return answer == "Trust" || answer == "Yes"
+}
+
+// beforeClose is called when the window is about to close. Returning true
+// keeps the app open so open transactions are not rolled back unnoticed.
+func (a *App) beforeClose(ctx context.Context) bool {
+	open := a.db.OpenTransactions("")
+	if len(open) == 0 {
+		return false
+	}
+
+	answer, err := runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
+		Type:          runtime.QuestionDialog,
+		Title:         "Open Transactions",
+		Message:       fmt.Sprintf("%d transaction(s) are still open and will be rolled back. Quit anyway?", len(open)),
+		Buttons:       []string{"Quit", "Cancel"},
+		DefaultButton: "Cancel",
+		CancelButton:  "Cancel",
+	})
+	if err != nil {
+		return true
+	}
+	return answer != "Quit" && answer != "Yes"
 }
 
 // shutdown is called when the app quits
 func (a *App) shutdown(ctx context.Context) {
-	a.db.Disconnect()
+	a.db.DisconnectAll()
 }
 
 // ====================
 // Connection Methods
 // ====================
 
-// Connect establishes a connection to MySQL
-func (a *App) Connect(config database.ConnectionConfig) error {
-	return a.db.Connect(config)
+// Connect opens a session for the saved connection connID and makes it the
+// active one. Its read-only and production settings are taken from storage
+// rather than from config. A connection still missing secrets is refused
+// with a MissingSecretsError before anything is dialed, so the user can be
+// prompted for them.
+func (a *App) Connect(connID string, config database.ConnectionConfig) error {
+	config, err := a.storage.ConnectConfig(connID, config)
+	if err != nil {
+		return err
+	}
+	return a.db.Connect(a.ctx, connID, config)
+}
+
+// Disconnect closes the session for connID. Open transactions are rolled
+// back, but only when force is set; otherwise an error reports them.
+func (a *App) Disconnect(connID string, force bool) error {
+	if open := a.db.OpenTransactions(connID); len(open) > 0 && !force {
+		return fmt.Errorf("%d open transaction(s) on this connection; commit or roll back first", len(open))
+	}
+	return a.db.Disconnect(connID)
+}
+
+// TestConnection tests if a connection can be established and reports diagnostics
+func (a *App) TestConnection(config database.ConnectionConfig) (*database.ConnectionTestResult, error) {
+	return a.db.TestConnection(a.ctx, config)
+}
+
+// GetSSHConfigHosts returns the Host aliases defined in ~/.ssh/config
+func (a *App) GetSSHConfigHosts() ([]string, error) {
+	return database.ListSSHConfigHosts()
+}
+
+// ResolveSSHConfigHost returns the effective ~/.ssh/config settings for an alias
+func (a *App) ResolveSSHConfigHost(alias string) (*database.SSHConfigHost, error) {
+	return database.ResolveSSHConfigHost(alias)
+}
+
+// IsConnected returns whether a session is open for connID
+func (a *App) IsConnected(connID string) bool {
+	return a.db.IsConnected(connID)
+}
+
+// ====================
+// Session Methods
+// ====================
+
+// ListSessions returns all open sessions
+func (a *App) ListSessions() []database.SessionInfo {
+	return a.db.ListSessions()
 }
 
-// Disconnect closes the database connection
-func (a *App) Disconnect() error {
-	return a.db.Disconnect()
+// ActivateSession makes connID the active session
+func (a *App) ActivateSession(connID string) error {
+	return a.db.ActivateSession(connID)
 }
 
-// TestConnection tests if a connection can be established
-func (a *App) TestConnection(config database.ConnectionConfig) (bool, error) {
-	return a.db.TestConnection(config)
+// GetActiveSession returns the ID of the active session
+func (a *App) GetActiveSession() string {
+	return a.db.ActiveSession()
 }
 
-// IsConnected returns whether we're connected to a database
-func (a *App) IsConnected() bool {
-	return a.db.IsConnected()
+// CloseSession closes the session for connID
+func (a *App) CloseSession(connID string, force bool) error {
+	return a.Disconnect(connID, force)
 }
 
 // ====================
 // Query Methods
 // ====================
 
-// ExecuteQuery runs a SELECT query and returns results
-func (a *App) ExecuteQuery(query string) (*database.QueryResult, error) {
-	return a.db.ExecuteQuery(query)
+// ExecuteQuery runs a SELECT query and returns results.
+// editorID identifies the query editor tab (for manual-commit transactions);
+// queryID is chosen by the frontend and can be passed to CancelQuery.
+// Destructive statements on production connections need the token from
+// CheckStatement.
+func (a *App) ExecuteQuery(connID, editorID, queryID, query, confirmToken string) (*database.QueryResult, error) {
+	return a.db.ExecuteQuery(a.ctx, connID, editorID, queryID, query, confirmToken)
+}
+
+// ExecuteStatement runs an INSERT/UPDATE/DELETE statement. Destructive
+// statements on production connections need the token from CheckStatement.
+func (a *App) ExecuteStatement(connID, editorID, queryID, query, confirmToken string) (*database.ExecuteResult, error) {
+	return a.db.ExecuteStatement(a.ctx, connID, editorID, queryID, query, confirmToken)
+}
+
+// CheckStatement reports whether a statement is destructive and, on a
+// production connection, issues the token needed to run it
+func (a *App) CheckStatement(connID, query string) (*database.StatementCheck, error) {
+	return a.db.CheckStatement(connID, query)
+}
+
+// DryRunStatement runs a statement in a transaction that is rolled back and
+// reports the rows it affected
+func (a *App) DryRunStatement(connID, query string) (*database.DryRunResult, error) {
+	return a.db.DryRunStatement(a.ctx, connID, query)
+}
+
+// ExecuteScript runs a multi-statement script and returns per-statement results
+func (a *App) ExecuteScript(connID, editorID, queryID, script string, opts database.ScriptOptions) (*database.ScriptResult, error) {
+	return a.db.ExecuteScript(a.ctx, connID, editorID, queryID, script, opts)
+}
+
+// StreamQuery starts a query in the background and delivers its rows in
+// batches through the query:stream:batch event. Each batch carries the running
+// row count; query:stream:done reports the final count and whether rows were
+// left unfetched because of opts.MaxRows. Destructive statements on production
+// connections need the token from CheckStatement.
+func (a *App) StreamQuery(connID, editorID, queryID, query, confirmToken string, opts database.StreamOptions) error {
+	if queryID == "" {
+		return fmt.Errorf("query id is required")
+	}
+	if !a.db.IsConnected(connID) {
+		return fmt.Errorf("not connected to database")
+	}
+
+	go func() {
+		summary, err := a.db.StreamQuery(a.ctx, connID, editorID, queryID, query, confirmToken, opts, func(batch database.StreamBatch) {
+			runtime.EventsEmit(a.ctx, eventQueryStreamBatch, batch)
+		})
+		if err != nil {
+			summary.Error = err.Error()
+		}
+		runtime.EventsEmit(a.ctx, eventQueryStreamDone, summary)
+	}()
+
+	return nil
+}
+
+// CancelQuery cancels the in-flight query started with queryID
+func (a *App) CancelQuery(queryID string) error {
+	return a.db.CancelQuery(queryID)
+}
+
+// ====================
+// Transaction Methods
+// ====================
+
+// BeginTransaction switches an editor to manual-commit mode
+func (a *App) BeginTransaction(connID, editorID string) error {
+	return a.db.BeginTransaction(a.ctx, connID, editorID)
 }
 
-// ExecuteStatement runs an INSERT/UPDATE/DELETE statement
-func (a *App) ExecuteStatement(query string) (*database.ExecuteResult, error) {
-	return a.db.ExecuteStatement(query)
+// Commit commits the editor's open transaction
+func (a *App) Commit(connID, editorID string) error {
+	return a.db.Commit(connID, editorID)
+}
+
+// Rollback rolls back the editor's open transaction
+func (a *App) Rollback(connID, editorID string) error {
+	return a.db.Rollback(connID, editorID)
+}
+
+// IsTransactionOpen returns whether the editor has an open transaction
+func (a *App) IsTransactionOpen(connID, editorID string) bool {
+	return a.db.IsTransactionOpen(connID, editorID)
+}
+
+// GetOpenTransactions lists open transactions on connID, or on all sessions when empty
+func (a *App) GetOpenTransactions(connID string) []database.TransactionInfo {
+	return a.db.OpenTransactions(connID)
 }
 
 // ====================
@@ -82,37 +262,43 @@ func (a *App) ExecuteStatement(query string) (*database.ExecuteResult, error) {
 // ====================
 
 // GetDatabases returns list of all databases
-func (a *App) GetDatabases() ([]database.DatabaseInfo, error) {
-	return a.db.GetDatabases()
+func (a *App) GetDatabases(connID string) ([]database.DatabaseInfo, error) {
+	return a.db.GetDatabases(a.ctx, connID)
 }
 
-// GetTables returns list of tables in a database
-func (a *App) GetTables(dbName string) ([]database.TableInfo, error) {
-	return a.db.GetTables(dbName)
+// GetTables returns list of tables in a database. schema picks the Postgres
+// schema, see GetSchemas; "" means public.
+func (a *App) GetTables(connID, dbName, schema string) ([]database.TableInfo, error) {
+	return a.db.GetTables(a.ctx, connID, dbName, schema)
 }
 
 // GetColumns returns list of columns in a table
-func (a *App) GetColumns(dbName, table string) ([]database.ColumnInfo, error) {
-	return a.db.GetColumns(dbName, table)
+func (a *App) GetColumns(connID, dbName, schema, table string) ([]database.ColumnInfo, error) {
+	return a.db.GetColumns(a.ctx, connID, dbName, schema, table)
 }
 
 // GetTableInfo returns detailed information about a table
-func (a *App) GetTableInfo(dbName, table string) (*database.TableDetails, error) {
-	return a.db.GetTableInfo(dbName, table)
+func (a *App) GetTableInfo(connID, dbName, schema, table string) (*database.TableDetails, error) {
+	return a.db.GetTableInfo(a.ctx, connID, dbName, schema, table)
+}
+
+// GetSchemas returns the schemas of the connected database
+func (a *App) GetSchemas(connID string) ([]string, error) {
+	return a.db.GetSchemas(a.ctx, connID)
 }
 
 // UseDatabase switches to a specific database
-func (a *App) UseDatabase(dbName string) error {
-	return a.db.UseDatabase(dbName)
+func (a *App) UseDatabase(connID, dbName string) error {
+	return a.db.UseDatabase(a.ctx, connID, dbName)
 }
 
 // ====================
 // Storage Methods
 // ====================
 
-// SaveConnection saves a connection with a name
-func (a *App) SaveConnection(name string, config database.ConnectionConfig) error {
-	return a.storage.SaveConnection(name, config)
+// SaveConnection creates a saved connection, or updates it when conn.ID is set
+func (a *App) SaveConnection(conn database.SavedConnection) (*database.SavedConnection, error) {
+	return a.storage.SaveConnection(conn)
 }
 
 // LoadConnections loads all saved connections
@@ -121,18 +307,120 @@ func (a *App) LoadConnections() ([]database.SavedConnection, error) {
 }
 
 // DeleteConnection removes a saved connection
-func (a *App) DeleteConnection(name string) error {
-	return a.storage.DeleteConnection(name)
+func (a *App) DeleteConnection(id string) error {
+	return a.storage.DeleteConnection(id)
 }
 
 // RenameConnection renames a saved connection
-func (a *App) RenameConnection(oldName, newName string) error {
-	return a.storage.RenameConnection(oldName, newName)
+func (a *App) RenameConnection(id, newName string) error {
+	return a.storage.RenameConnection(id, newName)
 }
 
 // UpdateConnection updates an existing saved connection
-func (a *App) UpdateConnection(name string, config database.ConnectionConfig) error {
-	return a.storage.SaveConnection(name, config)
+func (a *App) UpdateConnection(conn database.SavedConnection) error {
+	if conn.ID == "" {
+		return fmt.Errorf("connection ID is required")
+	}
+	_, err := a.storage.SaveConnection(conn)
+	return err
+}
+
+// ParseConnectionURI turns a pasted connection URI or DSN into a connection config
+func (a *App) ParseConnectionURI(raw string) (*database.ConnectionConfig, error) {
+	config, err := database.ParseConnectionURI(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetConnectionURI generates a connection URI for a saved connection
+func (a *App) GetConnectionURI(id string, includePassword bool) (string, error) {
+	conn, err := a.storage.GetConnection(id)
+	if err != nil {
+		return "", err
+	}
+	return database.ConnectionURI(conn.Config, includePassword)
+}
+
+// MoveConnection moves a saved connection into a folder at the given position
+func (a *App) MoveConnection(id, folder string, position int) error {
+	return a.storage.MoveConnection(id, folder, position)
+}
+
+// DuplicateConnection copies a saved connection
+func (a *App) DuplicateConnection(id string) (*database.SavedConnection, error) {
+	return a.storage.DuplicateConnection(id)
+}
+
+// SearchConnections finds saved connections, e.g. "acme env:prod tag:billing"
+func (a *App) SearchConnections(query string) ([]database.SavedConnection, error) {
+	return a.storage.SearchConnections(query)
+}
+
+// MarkConnectionUsed records that a saved connection was just opened
+func (a *App) MarkConnectionUsed(id string) error {
+	return a.storage.TouchConnection(id)
+}
+
+// PreviewImport lists the connections an import would add, update or skip
+func (a *App) PreviewImport(req database.ImportRequest) (*database.ImportPreview, error) {
+	return a.storage.PreviewImport(req)
+}
+
+// ApplyImport imports connections from another client or file
+func (a *App) ApplyImport(req database.ImportRequest) (*database.ImportResult, error) {
+	return a.storage.ApplyImport(req)
+}
+
+// SelectImportFile opens a file dialog for choosing a file to import connections from
+func (a *App) SelectImportFile() (string, error) {
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:           "Import Connections",
+		ShowHiddenFiles: true,
+	})
+}
+
+// ExportConnections writes saved connections to a shareable bundle at outputPath
+func (a *App) ExportConnections(opts database.ExportOptions, outputPath string) error {
+	data, err := a.storage.ExportConnections(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// ListConnectionBackups returns the saved backups of the connections file, newest first
+func (a *App) ListConnectionBackups() ([]database.BackupInfo, error) {
+	return a.storage.ListBackups()
+}
+
+// RestoreConnectionBackup replaces the saved connections with a backup
+func (a *App) RestoreConnectionBackup(name string) error {
+	return a.storage.RestoreBackup(name)
+}
+
+// GetSecretBackend reports where connection secrets are kept: "keyring" or "vault"
+func (a *App) GetSecretBackend() string {
+	return a.storage.SecretBackend()
+}
+
+// IsVaultLocked reports whether the master password is needed to read secrets
+func (a *App) IsVaultLocked() bool {
+	return a.storage.IsLocked()
+}
+
+// UnlockVault unlocks the credential vault, creating it on first use
+func (a *App) UnlockVault(password string) error {
+	return a.storage.Unlock(password)
+}
+
+// LockVault locks the credential vault
+func (a *App) LockVault() {
+	a.storage.Lock()
 }
 
 // ====================
@@ -140,48 +428,63 @@ func (a *App) UpdateConnection(name string, config database.ConnectionConfig) er
 // ====================
 
 // GetTableData returns paginated table data
-func (a *App) GetTableData(req database.TableDataRequest) (*database.TableDataResponse, error) {
-	return a.db.GetTableData(req)
+func (a *App) GetTableData(connID string, req database.TableDataRequest) (*database.TableDataResponse, error) {
+	return a.db.GetTableData(a.ctx, connID, req)
 }
 
 // InsertRow inserts a new row into a table
-func (a *App) InsertRow(dbName, table string, data map[string]interface{}) (*database.ExecuteResult, error) {
-	return a.db.InsertRow(dbName, table, data)
+func (a *App) InsertRow(connID, dbName, schema, table string, data map[string]interface{}) (*database.ExecuteResult, error) {
+	return a.db.InsertRow(a.ctx, connID, dbName, schema, table, data)
+}
+
+// UpdateRow updates the row identified by key, one of GetTableData's row keys
+func (a *App) UpdateRow(connID, dbName, schema, table string, key, data map[string]interface{}) (*database.ExecuteResult, error) {
+	return a.db.UpdateRow(a.ctx, connID, dbName, schema, table, key, data)
 }
 
-// UpdateRow updates a row by primary key
-func (a *App) UpdateRow(dbName, table, primaryKey string, primaryValue interface{}, data map[string]interface{}) (*database.ExecuteResult, error) {
-	return a.db.UpdateRow(dbName, table, primaryKey, primaryValue, data)
+// DeleteRow deletes the row identified by key
+func (a *App) DeleteRow(connID, dbName, schema, table string, key map[string]interface{}) (*database.ExecuteResult, error) {
+	return a.db.DeleteRow(a.ctx, connID, dbName, schema, table, key)
+}
+
+// DeleteRows deletes the rows identified by keys
+func (a *App) DeleteRows(connID, dbName, schema, table string, keys []map[string]interface{}) (*database.ExecuteResult, error) {
+	rowKeys := make([]database.RowKey, len(keys))
+	for i, key := range keys {
+		rowKeys[i] = key
+	}
+	return a.db.DeleteRows(a.ctx, connID, dbName, schema, table, rowKeys)
 }
 
-// DeleteRow deletes a row by primary key
-func (a *App) DeleteRow(dbName, table, primaryKey string, primaryValue interface{}) (*database.ExecuteResult, error) {
-	return a.db.DeleteRow(dbName, table, primaryKey, primaryValue)
+// ApplyChangeSet commits staged inserts, updates and deletes in one transaction
+func (a *App) ApplyChangeSet(connID string, changes database.ChangeSet) (*database.ChangeSetResult, error) {
+	return a.db.ApplyChangeSet(a.ctx, connID, changes)
 }
 
-// DeleteRows deletes multiple rows by primary key values
-func (a *App) DeleteRows(dbName, table, primaryKey string, primaryValues []interface{}) (*database.ExecuteResult, error) {
-	return a.db.DeleteRows(dbName, table, primaryKey, primaryValues)
+// UndoChangeSet reverts the last committed change set
+func (a *App) UndoChangeSet(connID, undoID string) (*database.ChangeSetResult, error) {
+	return a.db.UndoChangeSet(a.ctx, connID, undoID)
 }
 
 // GetDistinctValues returns distinct values for a column to support frontend auto-completion
-func (a *App) GetDistinctValues(dbName, table, column string) ([]string, error) {
-	return a.db.GetDistinctValues(dbName, table, column)
+func (a *App) GetDistinctValues(connID, dbName, schema, table, column string) ([]string, error) {
+	return a.db.GetDistinctValues(a.ctx, connID, dbName, schema, table, column)
 }
 
-// AlterTable performs schema modifications on a table
-func (a *App) AlterTable(dbName, table string, alteration database.TableAlteration) error {
-	return a.db.AlterTable(dbName, table, alteration)
+// AlterTable performs schema modifications on a table. On production
+// connections it needs the token from the error of a first attempt.
+func (a *App) AlterTable(connID, dbName, schema, table string, alteration database.TableAlteration, confirmToken string) error {
+	return a.db.AlterTable(a.ctx, connID, dbName, schema, table, alteration, confirmToken)
 }
 
 // TruncateTable removes all rows from a table
-func (a *App) TruncateTable(dbName, table string) error {
-	return a.db.TruncateTable(dbName, table)
+func (a *App) TruncateTable(connID, dbName, schema, table, confirmToken string) error {
+	return a.db.TruncateTable(a.ctx, connID, dbName, schema, table, confirmToken)
 }
 
 // DropTable deletes a table
-func (a *App) DropTable(dbName, table string) error {
-	return a.db.DropTable(dbName, table)
+func (a *App) DropTable(connID, dbName, schema, table, confirmToken string) error {
+	return a.db.DropTable(a.ctx, connID, dbName, schema, table, confirmToken)
 }
 
 // ====================
@@ -250,6 +553,9 @@ func (a *App) SelectExportPath(format string) (string, error) {
 	case "json":
 		filters = []runtime.FileFilter{{DisplayName: "JSON File (*.json)", Pattern: "*.json"}}
 		defaultExt = "*.json"
+	case "yaml":
+		filters = []runtime.FileFilter{{DisplayName: "YAML File (*.yaml)", Pattern: "*.yaml;*.yml"}}
+		defaultExt = "*.yaml"
 	}
 
 	return runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
@@ -260,6 +566,6 @@ func (a *App) SelectExportPath(format string) (string, error) {
 }
 
 // ExportTable exports the table data to a file
-func (a *App) ExportTable(dbName, tableName, format, outputPath string) error {
-	return a.db.ExportTable(dbName, tableName, format, outputPath)
+func (a *App) ExportTable(connID, queryID, dbName, schema, tableName, format, outputPath string) error {
+	return a.db.ExportTable(a.ctx, connID, queryID, dbName, schema, tableName, format, outputPath)
 }
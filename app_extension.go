@@ -1,5 +1,5 @@
 package main
 
-func (a *App) GetDatabaseSchema(dbName string) (map[string][]string, error) {
-	return a.db.GetDatabaseSchema(dbName)
+func (a *App) GetDatabaseSchema(connID, dbName, schema string) (map[string][]string, error) {
+	return a.db.GetDatabaseSchema(a.ctx, connID, dbName, schema)
 }
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle identification
+const (
+	bundleFormat  = "mergen-connections"
+	bundleVersion = 1
+)
+
+// ExportOptions selects what ExportConnections writes
+type ExportOptions struct {
+	IDs        []string `json:"ids,omitempty"`        // Connections to export; all when empty
+	Format     string   `json:"format"`               // json or yaml
+	Passphrase string   `json:"passphrase,omitempty"` // Encrypts secrets into the bundle; without it they are left out
+}
+
+// connectionBundle is a portable set of connections meant to be shared, e.g.
+// through a repository. Secrets are either left out or sealed with a
+// passphrase; ${NAME} environment references are kept as they are.
+type connectionBundle struct {
+	Format      string             `json:"format"`
+	Version     int                `json:"version"`
+	ExportedAt  time.Time          `json:"exportedAt"`
+	Connections []bundleConnection `json:"connections"`
+
+	// Sealed JSON object of connection ID -> secrets, when exported with a passphrase
+	Secrets *vaultFile `json:"secrets,omitempty"`
+}
+
+type bundleConnection struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Folder      string           `json:"folder,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
+	Environment string           `json:"environment,omitempty"`
+	Notes       string           `json:"notes,omitempty"`
+	Config      ConnectionConfig `json:"config"`
+
+	// Secret fields not in Config, whether sealed in Secrets or dropped
+	OmittedSecrets []string `json:"omittedSecrets,omitempty"`
+}
+
+// ExportConnections writes connections to a bundle without their secrets,
+// or with them encrypted under opts.Passphrase
+func (s *Storage) ExportConnections(opts ExportOptions) ([]byte, error) {
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "yaml" && format != "yml" {
+		return nil, fmt.Errorf("unsupported export format: %s", opts.Format)
+	}
+	// Secrets decide what is omitted, so they must be readable
+	if s.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	connections, err := s.LoadConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.IDs) > 0 {
+		var selected []SavedConnection
+		for _, id := range opts.IDs {
+			index := findConnection(connections, id)
+			if index < 0 {
+				return nil, fmt.Errorf("connection not found: %s", id)
+			}
+			selected = append(selected, connections[index])
+		}
+		connections = selected
+	}
+
+	bundle := connectionBundle{
+		Format:      bundleFormat,
+		Version:     bundleVersion,
+		ExportedAt:  time.Now().UTC(),
+		Connections: make([]bundleConnection, 0, len(connections)),
+	}
+	sealed := map[string]connectionSecrets{}
+
+	for _, c := range connections {
+		config := c.Config
+		secrets := extractSecrets(&config)
+
+		// References name a secret rather than hold one, so they travel
+		reference := func(name, v string) bool { return c.Config.EnvReferences && envSecret(name) && isEnvReference(v) }
+		secrets.filter(reference).apply(&config)
+		real := secrets.filter(func(name, v string) bool { return !reference(name, v) })
+
+		if opts.Passphrase != "" && !real.empty() {
+			sealed[c.ID] = real
+		}
+
+		bundle.Connections = append(bundle.Connections, bundleConnection{
+			ID:             c.ID,
+			Name:           c.Name,
+			Folder:         c.Folder,
+			Tags:           c.Tags,
+			Environment:    c.Environment,
+			Notes:          c.Notes,
+			Config:         config,
+			OmittedSecrets: real.fieldNames(),
+		})
+	}
+
+	if len(sealed) > 0 {
+		plain, err := json.Marshal(sealed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal secrets: %w", err)
+		}
+		if bundle.Secrets, err = sealWithPassword(opts.Passphrase, plain); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	if format == "json" {
+		return data, nil
+	}
+	return jsonToYAML(data)
+}
+
+// bundleParser returns the import parser for bundles sealed with passphrase
+func bundleParser(passphrase string) importParser {
+	return func(data []byte, _ string) ([]importCandidate, []string, error) {
+		return parseBundle(data, passphrase)
+	}
+}
+
+// parseBundle reads a JSON or YAML bundle written by ExportConnections
+func parseBundle(data []byte, passphrase string) ([]importCandidate, []string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' {
+		var err error
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var bundle connectionBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, nil, err
+	}
+	if bundle.Format != bundleFormat {
+		return nil, nil, fmt.Errorf("not a connection bundle")
+	}
+	if bundle.Version > bundleVersion {
+		return nil, nil, fmt.Errorf("bundle version %d is newer than this app supports", bundle.Version)
+	}
+
+	var warnings []string
+	sealed := map[string]connectionSecrets{}
+	if bundle.Secrets != nil {
+		if passphrase == "" {
+			warnings = append(warnings, "the bundle's secrets are encrypted; enter its passphrase to import them")
+		} else {
+			plain, err := openWithPassword(passphrase, bundle.Secrets)
+			if errors.Is(err, ErrWrongMasterPassword) {
+				return nil, nil, fmt.Errorf("incorrect bundle passphrase")
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := json.Unmarshal(plain, &sealed); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse bundle secrets: %w", err)
+			}
+		}
+	}
+
+	candidates := make([]importCandidate, 0, len(bundle.Connections))
+	for _, bc := range bundle.Connections {
+		conn := SavedConnection{
+			ID:          bc.ID,
+			Name:        bc.Name,
+			Folder:      bc.Folder,
+			Tags:        bc.Tags,
+			Environment: bc.Environment,
+			Notes:       bc.Notes,
+			Config:      bc.Config,
+		}
+		conn.Config.EnvReferences = usesEnvReferences(bc.Config)
+		if conn.ID == "" {
+			candidates = append(candidates, importCandidate{conn: conn, skip: "connection has no ID"})
+			continue
+		}
+
+		if secrets, ok := sealed[bc.ID]; ok {
+			conn.Config = mergeBundleConfig(secrets.config(conn.Config), conn.Config)
+		}
+		conn.MissingSecrets = missingSecrets(conn.Config, bc.OmittedSecrets)
+		if hasStrayEnvReferences(conn.Config) {
+			warnings = append(warnings, fmt.Sprintf("%s: ${NAME} references are only expanded in passwords and passphrases", conn.Name))
+		}
+
+		candidates = append(candidates, importCandidate{conn: conn})
+	}
+
+	return candidates, warnings, nil
+}
+
+// config returns base with these secrets in place of its own
+func (s connectionSecrets) config(base ConnectionConfig) ConnectionConfig {
+	base.SSHJumpHosts = append([]SSHJumpHost(nil), base.SSHJumpHosts...)
+	s.apply(&base)
+	return base
+}
+
+// jsonToYAML re-encodes JSON as YAML, keeping the JSON field names
+func jsonToYAML(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// yamlToJSON re-encodes YAML as JSON so it can be decoded with the JSON tags
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// saveBundleConnections saves the connections the bundle tests export
+func saveBundleConnections(t *testing.T, s *Storage) map[string]*SavedConnection {
+	t.Helper()
+	saved := map[string]*SavedConnection{}
+	for _, conn := range []SavedConnection{
+		{Name: "plain", Config: ConnectionConfig{Type: "postgres", Host: "db1", Port: 5432, User: "app", Password: "pw"}},
+		{Name: "reference", Config: ConnectionConfig{Type: "postgres", Host: "db2", Port: 5432, User: "app", Password: "${DB_PASS}", EnvReferences: true}},
+		{Name: "literal", Config: ConnectionConfig{Type: "mysql", Host: "db3", Port: 3306, User: "app", Password: "${X}"}},
+	} {
+		c, err := s.SaveConnection(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved[c.Name] = c
+	}
+	return saved
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		passphrase    string // Used for export and import
+		wantPasswords map[string]string
+		wantMissing   map[string]bool
+	}{
+		{
+			name:          "sealed JSON",
+			format:        "json",
+			passphrase:    "correct horse",
+			wantPasswords: map[string]string{"plain": "pw", "reference": "${DB_PASS}", "literal": "${X}"},
+		},
+		{
+			name:          "sealed YAML",
+			format:        "yaml",
+			passphrase:    "correct horse",
+			wantPasswords: map[string]string{"plain": "pw", "reference": "${DB_PASS}", "literal": "${X}"},
+		},
+		{
+			name:          "without secrets",
+			format:        "json",
+			wantPasswords: map[string]string{"plain": "", "reference": "${DB_PASS}", "literal": ""},
+			wantMissing:   map[string]bool{"plain": true, "literal": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, _ := newTestStorage(t)
+			saved := saveBundleConnections(t, from)
+
+			data, err := from.ExportConnections(ExportOptions{Format: tt.format, Passphrase: tt.passphrase})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), `"pw"`) || strings.Contains(string(data), "${X}") {
+				t.Fatalf("bundle holds a secret in the clear:\n%s", data)
+			}
+
+			to, _ := newTestStorage(t)
+			result, err := to.ApplyImport(ImportRequest{Source: ImportBundle, Content: string(data), Passphrase: tt.passphrase})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Added != 3 {
+				t.Fatalf("result = %+v, want 3 added", result)
+			}
+
+			for name, want := range tt.wantPasswords {
+				got, err := to.GetConnection(saved[name].ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Config.Password != want {
+					t.Errorf("%s password = %q, want %q", name, got.Config.Password, want)
+				}
+				// Only references from the exporting side are expanded later
+				if got.Config.EnvReferences != (name == "reference") {
+					t.Errorf("%s envReferences = %v", name, got.Config.EnvReferences)
+				}
+				if missing := len(got.MissingSecrets) > 0; missing != tt.wantMissing[name] {
+					t.Errorf("%s missing secrets = %v", name, got.MissingSecrets)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBundleWrongPassphrase(t *testing.T) {
+	s, _ := newTestStorage(t)
+	saveBundleConnections(t, s)
+	data, err := s.ExportConnections(ExportOptions{Passphrase: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := parseBundle(data, "wrong"); err == nil || !strings.Contains(err.Error(), "incorrect bundle passphrase") {
+		t.Errorf("error = %v, want an incorrect passphrase", err)
+	}
+	// Without a passphrase the connections still import, without secrets
+	candidates, warnings, err := parseBundle(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 3 || len(warnings) != 1 {
+		t.Errorf("got %d candidates and warnings %q", len(candidates), warnings)
+	}
+}
+
+func TestParseBundleHostileKDF(t *testing.T) {
+	sealed, err := sealWithPassword("correct horse", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(f *vaultFile)
+		wantErr string
+	}{
+		{name: "zero time", modify: func(f *vaultFile) { f.Time = 0 }, wantErr: "time 0"},
+		{name: "huge time", modify: func(f *vaultFile) { f.Time = 1 << 30 }, wantErr: "time"},
+		{name: "huge memory", modify: func(f *vaultFile) { f.Memory = 1 << 31 }, wantErr: "memory"},
+		{name: "too little memory", modify: func(f *vaultFile) { f.Memory = 1 }, wantErr: "memory"},
+		{name: "zero threads", modify: func(f *vaultFile) { f.Threads = 0 }, wantErr: "threads 0"},
+		{name: "many threads", modify: func(f *vaultFile) { f.Threads = 255; f.Memory = vaultMaxArgonMemory }, wantErr: "threads"},
+		{name: "other KDF", modify: func(f *vaultFile) { f.KDF = "scrypt" }, wantErr: "unsupported key derivation"},
+		{name: "short nonce", modify: func(f *vaultFile) { f.Nonce = f.Nonce[:4] }, wantErr: "nonce"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := *sealed
+			tt.modify(&secrets)
+			bundle := connectionBundle{
+				Format:      bundleFormat,
+				Version:     bundleVersion,
+				Connections: []bundleConnection{{ID: "x", Name: "a", Config: ConnectionConfig{Type: "mysql"}}},
+				Secrets:     &secrets,
+			}
+			data, err := json.Marshal(bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Rejected before any key is derived, so it fails fast and without a panic
+			start := time.Now()
+			_, _, err = parseBundle(data, "correct horse")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("rejecting the bundle took %s", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// cancelTimeout bounds the server-side cancel statement issued by CancelQuery
+const cancelTimeout = 5 * time.Second
+
+// queryer is the subset of *sql.DB / *sql.Conn / *sql.Tx used to run statements
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// runningQuery tracks an in-flight statement so it can be cancelled
+type runningQuery struct {
+	cancel       context.CancelFunc
+	session      *session
+	connectionID int64
+	inTx         bool
+}
+
+// trackQuery prepares a cancellable context for a statement identified by queryID.
+// Statements from an editor with an open transaction run inside that transaction.
+// Otherwise, when queryID is set the statement is pinned to a dedicated
+// connection whose server-side ID is recorded, so CancelQuery can also stop it
+// on the server. The returned release func must be called once the statement
+// has finished.
+func (m *Manager) trackQuery(ctx context.Context, s *session, editorID, queryID string) (context.Context, queryer, func(), error) {
+	if et := s.getTx(editorID); et != nil {
+		return m.txQuery(ctx, s, et, queryID)
+	}
+	if queryID == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, s.db, cancel, nil
+	}
+	return m.pinQuery(ctx, s, queryID)
+}
+
+// trackScript is like trackQuery but never falls back to the pool, for work
+// that spans several statements and needs them on one connection
+func (m *Manager) trackScript(ctx context.Context, s *session, editorID, queryID string) (context.Context, queryer, func(), error) {
+	if et := s.getTx(editorID); et != nil {
+		return m.txQuery(ctx, s, et, queryID)
+	}
+	return m.pinQuery(ctx, s, queryID)
+}
+
+// pinQuery runs on a dedicated connection. It registers for cancellation only
+// when queryID is set.
+func (m *Manager) pinQuery(ctx context.Context, s *session, queryID string) (context.Context, queryer, func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	if queryID == "" {
+		return ctx, conn, func() {
+			cancel()
+			conn.Close()
+		}, nil
+	}
+
+	// A missing connection ID only disables the server-side cancel
+	connectionID, _ := s.driver.ConnectionID(ctx, conn)
+	m.registerQuery(queryID, &runningQuery{
+		cancel:       cancel,
+		session:      s,
+		connectionID: connectionID,
+	})
+
+	release := func() {
+		m.unregisterQuery(queryID)
+		cancel()
+		conn.Close()
+	}
+
+	return ctx, conn, release, nil
+}
+
+// txQuery runs on an editor's open transaction. Statements on the same
+// transaction are serialised.
+func (m *Manager) txQuery(ctx context.Context, s *session, et *editorTx, queryID string) (context.Context, queryer, func(), error) {
+	et.mu.Lock()
+	if et.tx == nil {
+		et.mu.Unlock()
+		return nil, nil, nil, fmt.Errorf("transaction is no longer open")
+	}
+	et.statements.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	if queryID != "" {
+		m.registerQuery(queryID, &runningQuery{
+			cancel:       cancel,
+			session:      s,
+			connectionID: et.connectionID,
+			inTx:         true,
+		})
+	}
+
+	release := func() {
+		if queryID != "" {
+			m.unregisterQuery(queryID)
+		}
+		cancel()
+		et.mu.Unlock()
+	}
+
+	return ctx, et.tx, release, nil
+}
+
+func (m *Manager) registerQuery(queryID string, rq *runningQuery) {
+	m.queriesMu.Lock()
+	defer m.queriesMu.Unlock()
+	m.queries[queryID] = rq
+}
+
+func (m *Manager) unregisterQuery(queryID string) {
+	m.queriesMu.Lock()
+	defer m.queriesMu.Unlock()
+	delete(m.queries, queryID)
+}
+
+// CancelQuery stops the in-flight statement registered under queryID
+func (m *Manager) CancelQuery(queryID string) error {
+	m.queriesMu.Lock()
+	rq, ok := m.queries[queryID]
+	m.queriesMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("query not found: %s", queryID)
+	}
+
+	// Stop the statement on the server first; cancelling the context alone
+	// only abandons the client side of the connection.
+	var killErr error
+	if rq.connectionID != 0 && rq.session.db != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+		defer cancel()
+		if _, err := rq.session.db.ExecContext(ctx, rq.session.driver.BuildCancelQuery(rq.connectionID)); err != nil {
+			killErr = fmt.Errorf("failed to cancel query on server: %w", err)
+		} else if rq.inTx {
+			// Cancelling the context would tear down the connection and with it
+			// the open transaction; the server-side cancel is enough.
+			return nil
+		}
+	}
+
+	rq.cancel()
+	return killErr
+}
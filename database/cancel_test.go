@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCancelQuery(t *testing.T) {
+	m := NewManager()
+	_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.ExecuteQuery(context.Background(), "a", "", "q1", "SLEEP", "")
+		done <- err
+	}()
+	server.waitSleeping(t)
+
+	if err := m.CancelQuery("q1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("cancelled query succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("query still running after CancelQuery")
+	}
+
+	// The pinned connection asked for its ID first, so it is connection 1
+	if !slices.Contains(server.statements(), "KILL QUERY 1") {
+		t.Errorf("statements = %q, want the query killed on the server", server.statements())
+	}
+	if err := m.CancelQuery("q1"); err == nil {
+		t.Error("a finished query could still be cancelled")
+	}
+}
+
+func TestCancelQueryByContext(t *testing.T) {
+	m := NewManager()
+	_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.ExecuteStatement(ctx, "a", "", "", "SLEEP", "")
+		done <- err
+	}()
+	server.waitSleeping(t)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("statement succeeded after its context was cancelled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("statement ignored its context")
+	}
+}
+
+func TestCancelQueryUnknown(t *testing.T) {
+	if err := NewManager().CancelQuery("missing"); err == nil {
+		t.Error("cancelling an unknown query succeeded")
+	}
+}
@@ -0,0 +1,427 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of row changes
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// RowChange is one staged edit to a table
+type RowChange struct {
+	Kind string  `json:"kind"`
+	Key  RowKey  `json:"key,omitempty"`  // Row to update or delete, as returned by GetTableData
+	Data RowData `json:"data,omitempty"` // Values to insert, or columns to update
+}
+
+// ChangeSet is a list of staged edits to one table, applied atomically
+type ChangeSet struct {
+	Database string      `json:"database"`
+	Schema   string      `json:"schema,omitempty"` // Postgres schema; "" means public
+	Table    string      `json:"table"`
+	Changes  []RowChange `json:"changes"`
+}
+
+// ChangeResult is the outcome of one change in a change set
+type ChangeResult struct {
+	Index        int    `json:"index"`
+	Kind         string `json:"kind"`
+	RowsAffected int64  `json:"rowsAffected"`
+	LastInsertId int64  `json:"lastInsertId,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Skipped      bool   `json:"skipped"` // Not run because an earlier change failed
+}
+
+// ChangeSetResult is the outcome of applying a change set. When any change
+// fails the whole set is rolled back and Committed is false.
+type ChangeSetResult struct {
+	Changes   []ChangeResult `json:"changes"`
+	Committed bool           `json:"committed"`
+	ElapsedMs int64          `json:"elapsedMs"`
+
+	// Set when the commit can be undone with UndoChangeSet
+	UndoID string `json:"undoId,omitempty"`
+	// Why the commit can't be undone, e.g. an insert whose key is unknown
+	UndoUnavailable string `json:"undoUnavailable,omitempty"`
+}
+
+// appliedChangeSet is the inverse of a committed change set, kept for undo
+type appliedChangeSet struct {
+	id      string
+	inverse ChangeSet
+}
+
+// ApplyChangeSet applies a change set in one transaction and keeps its
+// inverse so the commit can be undone. Updates and deletes must each match at
+// most one row.
+func (m *Manager) ApplyChangeSet(ctx context.Context, connID string, changes ChangeSet) (*ChangeSetResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable("change set"); err != nil {
+		return nil, err
+	}
+
+	result, inverse, err := m.applyChangeSet(ctx, s, connID, changes, false)
+	if err != nil || !result.Committed {
+		return result, err
+	}
+
+	s.rememberChangeSet(result, inverse)
+	return result, nil
+}
+
+// UndoChangeSet reverts the last change set committed on a connection,
+// identified by its UndoID. It is refused if a row it touched has changed
+// since. The undo can itself be undone through the UndoID it returns.
+func (m *Manager) UndoChangeSet(ctx context.Context, connID, undoID string) (*ChangeSetResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable("undo"); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	last := s.lastChangeSet
+	s.mu.RUnlock()
+	if last == nil || last.id != undoID {
+		return nil, fmt.Errorf("nothing to undo: only the last committed change set can be undone")
+	}
+
+	result, inverse, err := m.applyChangeSet(ctx, s, connID, last.inverse, true)
+	if err != nil || !result.Committed {
+		return result, err
+	}
+
+	s.rememberChangeSet(result, inverse)
+	return result, nil
+}
+
+// rememberChangeSet keeps the inverse of a committed change set as the one
+// to undo, replacing the previous one
+func (s *session) rememberChangeSet(result *ChangeSetResult, inverse *ChangeSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastChangeSet = nil
+	if result.UndoUnavailable == "" {
+		result.UndoID = uuid.NewString()
+		s.lastChangeSet = &appliedChangeSet{id: result.UndoID, inverse: *inverse}
+	}
+}
+
+// applyChangeSet runs changes in a transaction and builds their inverse. With
+// strict set, updates and deletes that match no row fail, so an undo can't
+// silently skip rows that changed since.
+func (m *Manager) applyChangeSet(ctx context.Context, s *session, connID string, changes ChangeSet, strict bool) (*ChangeSetResult, *ChangeSet, error) {
+	if len(changes.Changes) == 0 {
+		return nil, nil, fmt.Errorf("change set is empty")
+	}
+
+	namespace, err := s.namespace(changes.Database, changes.Schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	details, err := m.GetTableInfo(ctx, connID, changes.Database, changes.Schema, changes.Table)
+	if err != nil {
+		return nil, nil, err
+	}
+	identity := tableRowIdentity(s.driver, details)
+
+	// Keys of an undo were built here, and also match the changed columns
+	if !strict {
+		for i, change := range changes.Changes {
+			if change.Kind == ChangeInsert {
+				continue
+			}
+			if err := checkRowKey(identity, change.Key); err != nil {
+				return nil, nil, fmt.Errorf("change %d: %w", i+1, err)
+			}
+		}
+	}
+
+	// Columns an undo may match on besides the key
+	matchable := make(map[string]bool, len(details.Columns))
+	for _, col := range details.Columns {
+		matchable[col.Name] = comparableType(col.Type)
+	}
+	for _, name := range identity.Columns {
+		matchable[name] = true
+	}
+
+	start := time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &ChangeSetResult{Changes: make([]ChangeResult, len(changes.Changes))}
+	inverse := &ChangeSet{Database: changes.Database, Schema: changes.Schema, Table: changes.Table}
+	a := &changeApplier{driver: s.driver, tx: tx, changes: changes, namespace: namespace, identity: identity, matchable: matchable, strict: strict}
+
+	failed := false
+	for i, change := range changes.Changes {
+		res := &result.Changes[i]
+		res.Index = i
+		res.Kind = change.Kind
+		if failed {
+			res.Skipped = true
+			continue
+		}
+
+		undo, unavailable, err := a.apply(ctx, change, res)
+		if err != nil {
+			res.Error = err.Error()
+			failed = true
+			continue
+		}
+		if unavailable != "" && result.UndoUnavailable == "" {
+			result.UndoUnavailable = fmt.Sprintf("change %d: %s", i+1, unavailable)
+		}
+		if undo != nil {
+			// Undo runs in reverse order
+			inverse.Changes = append([]RowChange{*undo}, inverse.Changes...)
+		}
+	}
+
+	if !failed {
+		if err := tx.Commit(); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit: %w", err)
+		}
+		result.Committed = true
+	}
+	result.ElapsedMs = time.Since(start).Milliseconds()
+
+	return result, inverse, nil
+}
+
+// changeApplier applies row changes to one table within a transaction
+type changeApplier struct {
+	driver    Driver
+	tx        *sql.Tx
+	changes   ChangeSet
+	namespace string // What driver calls address for the table, see session.namespace
+	identity  RowIdentity
+	matchable map[string]bool // Columns that can be matched with =
+	strict    bool            // Rows to update or delete must exist
+}
+
+// apply runs change, fills res and returns the change that reverts it, or
+// why there is none
+func (a *changeApplier) apply(ctx context.Context, change RowChange, res *ChangeResult) (*RowChange, string, error) {
+	switch change.Kind {
+	case ChangeInsert:
+		return a.insert(ctx, change, res)
+	case ChangeUpdate:
+		return a.update(ctx, change, res)
+	case ChangeDelete:
+		return a.delete(ctx, change, res)
+	}
+	return nil, "", fmt.Errorf("unknown change kind: %s", change.Kind)
+}
+
+func (a *changeApplier) insert(ctx context.Context, change RowChange, res *ChangeResult) (*RowChange, string, error) {
+	if len(change.Data) == 0 {
+		return nil, "", fmt.Errorf("no data provided")
+	}
+
+	var columns []string
+	var values []interface{}
+	for col, val := range change.Data {
+		columns = append(columns, col)
+		values = append(values, val)
+	}
+
+	query := a.driver.BuildInsertQuery(a.namespace, a.changes.Table, columns)
+	if a.driver.SupportsReturning() && len(a.identity.Columns) > 0 {
+		return a.insertReturning(ctx, query, values, res)
+	}
+
+	r, err := a.tx.ExecContext(ctx, query, values...)
+	if err != nil {
+		return nil, "", fmt.Errorf("insert failed: %w", err)
+	}
+	res.RowsAffected, _ = r.RowsAffected()
+	res.LastInsertId, _ = r.LastInsertId()
+
+	key := a.insertedKey(change.Data, res.LastInsertId)
+	if key == nil {
+		return nil, "the inserted row's key is unknown", nil
+	}
+	return &RowChange{Kind: ChangeDelete, Key: key}, "", nil
+}
+
+// insertReturning runs an insert that reports the key of the inserted row,
+// including generated and default values
+func (a *changeApplier) insertReturning(ctx context.Context, query string, values []interface{}, res *ChangeResult) (*RowChange, string, error) {
+	quoted := make([]string, len(a.identity.Columns))
+	for i, col := range a.identity.Columns {
+		quoted[i] = a.driver.QuoteIdentifier(col)
+	}
+
+	rows, err := a.tx.QueryContext(ctx, query+" RETURNING "+strings.Join(quoted, ", "), values...)
+	if err != nil {
+		return nil, "", fmt.Errorf("insert failed: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := collectRows(rows)
+	if err != nil {
+		return nil, "", fmt.Errorf("insert failed: %w", err)
+	}
+	if len(result.Rows) != 1 {
+		return nil, "", fmt.Errorf("insert reported %d rows instead of one", len(result.Rows))
+	}
+	res.RowsAffected = 1
+
+	key := make(RowKey, len(a.identity.Columns))
+	for i, col := range a.identity.Columns {
+		key[col] = result.Rows[0][i]
+	}
+	if id, ok := result.Rows[0][0].(int64); ok && len(key) == 1 {
+		res.LastInsertId = id
+	}
+	return &RowChange{Kind: ChangeDelete, Key: key}, "", nil
+}
+
+// insertedKey returns the key of a row inserted with data, or nil if it
+// can't be told
+func (a *changeApplier) insertedKey(data RowData, lastInsertId int64) RowKey {
+	key := make(RowKey, len(a.identity.Columns))
+	for _, col := range a.identity.Columns {
+		if val, ok := data[col]; ok {
+			key[col] = val
+		}
+	}
+	if a.identity.Kind == RowKeyFullRow && len(key) > 0 {
+		// Columns left to their defaults aren't matched
+		return key
+	}
+	if len(key) > 0 && len(key) == len(a.identity.Columns) {
+		return key
+	}
+	if len(a.identity.Columns) == 1 && lastInsertId != 0 {
+		// Auto-increment column or SQLite rowid
+		return RowKey{a.identity.Columns[0]: lastInsertId}
+	}
+	return nil
+}
+
+func (a *changeApplier) update(ctx context.Context, change RowChange, res *ChangeResult) (*RowChange, string, error) {
+	if len(change.Data) == 0 {
+		return nil, "", fmt.Errorf("no data provided")
+	}
+
+	before, err := a.currentRow(ctx, change.Key)
+	if err != nil || before == nil {
+		return nil, "", err
+	}
+
+	var columns []string
+	var values []interface{}
+	for col, val := range change.Data {
+		columns = append(columns, col)
+		values = append(values, val)
+	}
+	where, keyArgs, err := buildKeyCondition(a.driver, change.Key, len(values))
+	if err != nil {
+		return nil, "", err
+	}
+	values = append(values, keyArgs...)
+
+	res.RowsAffected, err = execSingleRow(ctx, a.tx, a.driver.BuildUpdateQuery(a.namespace, a.changes.Table, columns, where), values...)
+	if err != nil {
+		return nil, "", fmt.Errorf("update failed: %w", err)
+	}
+
+	// Find the row by its new values, which also refuses the undo if they
+	// have changed since. A locator may move with the update, so the whole
+	// row is matched instead.
+	undo := &RowChange{Kind: ChangeUpdate, Key: RowKey{}, Data: RowData{}}
+	if locator := a.driver.RowLocator(); locator != "" && change.Key[locator] != nil {
+		for col, val := range before {
+			if a.matchable[col] {
+				undo.Key[col] = val
+			}
+		}
+	} else {
+		for col, val := range change.Key {
+			undo.Key[col] = val
+		}
+	}
+	for col, val := range change.Data {
+		if a.matchable[col] {
+			undo.Key[col] = val
+		}
+		undo.Data[col] = before[col]
+	}
+	return undo, "", nil
+}
+
+func (a *changeApplier) delete(ctx context.Context, change RowChange, res *ChangeResult) (*RowChange, string, error) {
+	before, err := a.currentRow(ctx, change.Key)
+	if err != nil || before == nil {
+		return nil, "", err
+	}
+
+	where, args, err := buildKeyCondition(a.driver, change.Key, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	res.RowsAffected, err = execSingleRow(ctx, a.tx, a.driver.BuildDeleteQuery(a.namespace, a.changes.Table, where), args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("delete failed: %w", err)
+	}
+
+	return &RowChange{Kind: ChangeInsert, Data: before}, "", nil
+}
+
+// currentRow reads the row matching key within the transaction, or nil if
+// there is none. In strict mode a missing row is an error.
+func (a *changeApplier) currentRow(ctx context.Context, key RowKey) (RowData, error) {
+	where, args, err := buildKeyCondition(a.driver, key, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	req := TableDataRequest{Database: a.changes.Database, Schema: a.changes.Schema, Table: a.changes.Table, Page: 1, PageSize: 2}
+	rows, err := a.tx.QueryContext(ctx, a.driver.BuildTableDataQuery(req, RowIdentity{}, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read row: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := collectRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	switch len(result.Rows) {
+	case 0:
+		if a.strict {
+			return nil, fmt.Errorf("row no longer matches; it has changed since the commit")
+		}
+		return nil, nil
+	case 1:
+	default:
+		return nil, ErrAmbiguousRowKey
+	}
+
+	row := make(RowData, len(result.Columns))
+	for i, col := range result.Columns {
+		row[col] = result.Rows[0][i]
+	}
+	return row, nil
+}
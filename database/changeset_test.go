@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChangeSetInverse(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       []string
+		changes     []RowChange
+		wantInverse []RowChange
+		query       string
+	}{
+		{
+			name:  "update by primary key",
+			setup: []string{"CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, price REAL)", "INSERT INTO t VALUES (1, 'a', 1.5)"},
+			changes: []RowChange{
+				{Kind: ChangeUpdate, Key: RowKey{"id": 1}, Data: RowData{"name": "b", "price": 2.5}},
+			},
+			// The REAL column is restored but not matched on
+			wantInverse: []RowChange{
+				{Kind: ChangeUpdate, Key: RowKey{"id": 1, "name": "b"}, Data: RowData{"name": "a", "price": 1.5}},
+			},
+			query: "SELECT * FROM t ORDER BY id",
+		},
+		{
+			name:  "delete restores the row",
+			setup: []string{"CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)", "INSERT INTO t VALUES (1, 'a'), (2, 'b')"},
+			changes: []RowChange{
+				{Kind: ChangeDelete, Key: RowKey{"id": 2}},
+			},
+			wantInverse: []RowChange{
+				{Kind: ChangeInsert, Data: RowData{"id": int64(2), "name": "b"}},
+			},
+			query: "SELECT * FROM t ORDER BY id",
+		},
+		{
+			name:  "insert reads the generated key",
+			setup: []string{"CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)", "INSERT INTO t (name) VALUES ('a')"},
+			changes: []RowChange{
+				{Kind: ChangeInsert, Data: RowData{"name": "b"}},
+			},
+			wantInverse: []RowChange{
+				{Kind: ChangeDelete, Key: RowKey{"id": int64(2)}},
+			},
+			query: "SELECT * FROM t ORDER BY id",
+		},
+		{
+			name:  "insert into a rowid table",
+			setup: []string{"CREATE TABLE t (name TEXT)", "INSERT INTO t VALUES ('a')"},
+			changes: []RowChange{
+				{Kind: ChangeInsert, Data: RowData{"name": "a"}},
+			},
+			wantInverse: []RowChange{
+				{Kind: ChangeDelete, Key: RowKey{"rowid": int64(2)}},
+			},
+			query: "SELECT rowid, * FROM t ORDER BY rowid",
+		},
+		{
+			name:  "inverse runs in reverse order",
+			setup: []string{"CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)", "INSERT INTO t VALUES (1, 'a')"},
+			changes: []RowChange{
+				{Kind: ChangeUpdate, Key: RowKey{"id": 1}, Data: RowData{"id": 5}},
+				{Kind: ChangeUpdate, Key: RowKey{"id": 5}, Data: RowData{"name": "z"}},
+			},
+			wantInverse: []RowChange{
+				{Kind: ChangeUpdate, Key: RowKey{"id": 5, "name": "z"}, Data: RowData{"name": "a"}},
+				{Kind: ChangeUpdate, Key: RowKey{"id": 5}, Data: RowData{"id": int64(1)}},
+			},
+			query: "SELECT * FROM t ORDER BY id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m, s := openTestSQLite(t, tt.setup...)
+			before := tableContents(t, s, tt.query)
+
+			result, err := m.ApplyChangeSet(ctx, "test", ChangeSet{Database: "main", Table: "t", Changes: tt.changes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Committed || result.UndoID == "" {
+				t.Fatalf("change set not committed with an undo: %+v", result)
+			}
+			if got := s.lastChangeSet.inverse.Changes; !reflect.DeepEqual(got, tt.wantInverse) {
+				t.Errorf("inverse\n got %+v\nwant %+v", got, tt.wantInverse)
+			}
+			after := tableContents(t, s, tt.query)
+
+			undo, err := m.UndoChangeSet(ctx, "test", result.UndoID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !undo.Committed {
+				t.Fatalf("undo not committed: %+v", undo)
+			}
+			if got := tableContents(t, s, tt.query); !reflect.DeepEqual(got, before) {
+				t.Errorf("after undo\n got %v\nwant %v", got, before)
+			}
+
+			// The undo can itself be undone
+			redo, err := m.UndoChangeSet(ctx, "test", undo.UndoID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !redo.Committed {
+				t.Fatalf("redo not committed: %+v", redo)
+			}
+			if got := tableContents(t, s, tt.query); !reflect.DeepEqual(got, after) {
+				t.Errorf("after redo\n got %v\nwant %v", got, after)
+			}
+		})
+	}
+}
+
+func TestUndoChangeSetRefusesChangedRows(t *testing.T) {
+	ctx := context.Background()
+	m, s := openTestSQLite(t, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)", "INSERT INTO t VALUES (1, 'a')")
+
+	result, err := m.ApplyChangeSet(ctx, "test", ChangeSet{Database: "main", Table: "t", Changes: []RowChange{
+		{Kind: ChangeUpdate, Key: RowKey{"id": 1}, Data: RowData{"name": "b"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec("UPDATE t SET name = 'c' WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	undo, err := m.UndoChangeSet(ctx, "test", result.UndoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undo.Committed || !strings.Contains(undo.Changes[0].Error, "changed since") {
+		t.Errorf("undo of a changed row = %+v, want it refused", undo)
+	}
+	if got := tableContents(t, s, "SELECT name FROM t"); !reflect.DeepEqual(got, [][]interface{}{{"c"}}) {
+		t.Errorf("table changed by a refused undo: %v", got)
+	}
+}
+
+func TestApplyChangeSetRollsBack(t *testing.T) {
+	ctx := context.Background()
+	m, s := openTestSQLite(t, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT NOT NULL)", "INSERT INTO t VALUES (1, 'a')")
+
+	result, err := m.ApplyChangeSet(ctx, "test", ChangeSet{Database: "main", Table: "t", Changes: []RowChange{
+		{Kind: ChangeUpdate, Key: RowKey{"id": 1}, Data: RowData{"name": "b"}},
+		{Kind: ChangeInsert, Data: RowData{"name": nil}},
+		{Kind: ChangeDelete, Key: RowKey{"id": 1}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed || result.UndoID != "" {
+		t.Fatalf("failed change set reported as committed: %+v", result)
+	}
+	if result.Changes[1].Error == "" || !result.Changes[2].Skipped {
+		t.Errorf("change results = %+v, want the insert failed and the delete skipped", result.Changes)
+	}
+	if got := tableContents(t, s, "SELECT * FROM t"); !reflect.DeepEqual(got, [][]interface{}{{int64(1), "a"}}) {
+		t.Errorf("table after rollback = %v", got)
+	}
+}
+
+func TestApplyChangeSetChecksKeys(t *testing.T) {
+	m, _ := openTestSQLite(t, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+
+	_, err := m.ApplyChangeSet(context.Background(), "test", ChangeSet{Database: "main", Table: "t", Changes: []RowChange{
+		{Kind: ChangeDelete, Key: RowKey{"name": "a"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "change 1") {
+		t.Fatalf("error = %v, want the key of change 1 refused", err)
+	}
+}
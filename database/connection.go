@@ -1,7 +1,7 @@
 package database
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"net"
 	"strconv"
@@ -9,18 +9,38 @@ import (
 	"sync"
 )
 
-// Manager handles all database operations
+// Manager handles all database operations across open sessions
 type Manager struct {
-	db     *sql.DB
-	config *ConnectionConfig
-	driver Driver
-	tunnel *SSHTunnel
-	mu     sync.RWMutex
+	sessions  map[string]*session
+	activeID  string
+	mu        sync.RWMutex
+	queries   map[string]*runningQuery
+	queriesMu sync.Mutex
+
+	approveHostKey HostKeyApprover
+	tunnelStatus   func(TunnelStatus)
 }
 
 // NewManager creates a new database manager
 func NewManager() *Manager {
-	return &Manager{}
+	return &Manager{
+		sessions: make(map[string]*session),
+		queries:  make(map[string]*runningQuery),
+	}
+}
+
+// SetHostKeyApprover sets the callback asked to trust SSH hosts seen for the first time
+func (m *Manager) SetHostKeyApprover(approve HostKeyApprover) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.approveHostKey = approve
+}
+
+// SetTunnelStatusHandler sets the callback told about SSH tunnel state changes
+func (m *Manager) SetTunnelStatusHandler(handler func(TunnelStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tunnelStatus = handler
 }
 
 // getDriver returns the appropriate driver for the config
@@ -30,140 +50,258 @@ func (m *Manager) getDriver(config ConnectionConfig) (Driver, error) {
 		return &MySQLDriver{}, nil
 	case "postgres":
 		return &PostgresDriver{}, nil
+	case "sqlite":
+		return &SQLiteDriver{}, nil
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", config.Type)
 	}
 }
 
-// Connect establishes a connection to the database
-func (m *Manager) Connect(config ConnectionConfig) error {
+// Connect opens a new session under connID and makes it the active one.
+// An existing session with the same ID is closed first.
+func (m *Manager) Connect(ctx context.Context, connID string, config ConnectionConfig) error {
+	if connID == "" {
+		return fmt.Errorf("connection id is required")
+	}
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	existing := m.sessions[connID]
+	delete(m.sessions, connID)
+	m.mu.Unlock()
+
+	if existing != nil {
+		existing.close()
+	}
+
+	s, err := m.openSession(ctx, connID, config)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.sessions[connID] = s
+	m.activeID = connID
+	m.mu.Unlock()
 
-	// Close existing connection if any
-	if m.db != nil {
-		m.db.Close()
+	return nil
+}
+
+// openSession dials the SSH tunnel (if any) and the database pool for a new session
+func (m *Manager) openSession(ctx context.Context, connID string, config ConnectionConfig) (*session, error) {
+	s := &session{
+		id:            connID,
+		txs:           make(map[string]*editorTx),
+		confirmations: make(map[string]pendingConfirmation),
 	}
-	if m.tunnel != nil {
-		m.tunnel.Close()
-		m.tunnel = nil
+
+	// ${NAME} references in passwords from a shared bundle are resolved now,
+	// so changes to the environment apply on reconnect
+	if config.EnvReferences {
+		var err error
+		if config, err = resolveEnvReferences(config); err != nil {
+			return nil, err
+		}
 	}
 
-	// Setup SSH tunnel if configured
-	if config.UseSSHTunnel {
-		tunnel, err := NewSSHTunnel(config)
+	// Dial through a copy so the session keeps the configured host and port
+	dialConfig := config
+
+	// Setup SSH tunnel if configured; file-based databases have no host to reach
+	if config.UseSSHTunnel && !strings.EqualFold(config.Type, "sqlite") {
+		m.mu.RLock()
+		approve := m.approveHostKey
+		onStatus := m.tunnelStatus
+		m.mu.RUnlock()
+
+		tunnel, err := NewSSHTunnel(config, approve)
 		if err != nil {
-			return fmt.Errorf("failed to create SSH tunnel: %w", err)
+			return nil, fmt.Errorf("failed to create SSH tunnel: %w", err)
+		}
+
+		// Test connections have no ID and nothing in the UI to update
+		if onStatus != nil && connID != "" {
+			tunnel.onStatus = func(state string, attempt int, err error) {
+				status := TunnelStatus{ConnID: connID, State: state, Attempt: attempt}
+				if err != nil {
+					status.Error = err.Error()
+				}
+				onStatus(status)
+			}
 		}
 
 		localAddr, err := tunnel.Start(config)
 		if err != nil {
-			return fmt.Errorf("failed to start SSH tunnel: %w", err)
+			// Start may already hold an ssh-agent connection
+			tunnel.Close()
+			return nil, fmt.Errorf("failed to start SSH tunnel: %w", err)
 		}
 
-		m.tunnel = tunnel
+		s.tunnel = tunnel
 
 		// Update config to use tunnel's local address
 		host, portStr, err := net.SplitHostPort(localAddr)
 		if err != nil {
 			tunnel.Close()
-			return fmt.Errorf("failed to parse tunnel address: %w", err)
+			return nil, fmt.Errorf("failed to parse tunnel address: %w", err)
 		}
 		port, _ := strconv.Atoi(portStr)
-		config.Host = host
-		config.Port = port
+		dialConfig.Host = host
+		dialConfig.Port = port
+		dialConfig.tlsServerName = config.Host
 	}
 
 	driver, err := m.getDriver(config)
 	if err != nil {
-		if m.tunnel != nil {
-			m.tunnel.Close()
-			m.tunnel = nil
-		}
-		return err
+		s.close()
+		return nil, err
 	}
 
-	db, err := driver.Connect(config)
+	db, err := driver.Connect(ctx, dialConfig)
 	if err != nil {
-		if m.tunnel != nil {
-			m.tunnel.Close()
-			m.tunnel = nil
-		}
-		return err
+		s.close()
+		return nil, err
 	}
 
-	m.db = db
-	m.config = &config
-	m.driver = driver
-	return nil
+	s.db = db
+	s.config = &config
+	s.driver = driver
+	return s, nil
 }
 
-// Disconnect closes the database connection
-func (m *Manager) Disconnect() error {
+// reopenSession replaces s with a new session connected to another database,
+// for dialects whose connections are bound to a single database
+func (m *Manager) reopenSession(ctx context.Context, s *session, database string) error {
+	if len(s.transactions()) > 0 {
+		return fmt.Errorf("cannot switch database while transactions are open")
+	}
+
+	config := *s.getConfig()
+	config.Database = database
+
+	next, err := m.openSession(ctx, s.id, config)
+	if err != nil {
+		return fmt.Errorf("failed to switch database: %w", err)
+	}
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	if m.sessions[s.id] != s {
+		m.mu.Unlock()
+		next.close()
+		return fmt.Errorf("session was closed while switching database")
+	}
+	m.sessions[s.id] = next
+	m.mu.Unlock()
 
-	var errs []error
+	return s.close()
+}
 
-	if m.db != nil {
-		if err := m.db.Close(); err != nil {
-			errs = append(errs, err)
-		}
-		m.db = nil
-		m.config = nil
-		m.driver = nil
+// Disconnect closes the session registered under connID
+func (m *Manager) Disconnect(connID string) error {
+	m.mu.Lock()
+	s, ok := m.sessions[connID]
+	if !ok {
+		m.mu.Unlock()
+		return nil
+	}
+	delete(m.sessions, connID)
+	if m.activeID == connID {
+		m.activeID = ""
 	}
+	m.mu.Unlock()
+
+	return s.close()
+}
 
-	if m.tunnel != nil {
-		if err := m.tunnel.Close(); err != nil {
-			errs = append(errs, err)
+// DisconnectAll closes every open session
+func (m *Manager) DisconnectAll() error {
+	m.mu.Lock()
+	sessions := m.sessions
+	m.sessions = make(map[string]*session)
+	m.activeID = ""
+	m.mu.Unlock()
+
+	var firstErr error
+	for _, s := range sessions {
+		if err := s.close(); err != nil && firstErr == nil {
+			firstErr = err
 		}
-		m.tunnel = nil
 	}
+	return firstErr
+}
 
-	if len(errs) > 0 {
-		return errs[0]
-	}
-	return nil
+// ConnectionTestResult reports what TestConnection checked along the way
+type ConnectionTestResult struct {
+	Success     bool     `json:"success"`
+	Diagnostics []string `json:"diagnostics"`
+	Error       string   `json:"error,omitempty"`
 }
 
-// TestConnection tests if a connection can be established
-func (m *Manager) TestConnection(config ConnectionConfig) (bool, error) {
-	driver, err := m.getDriver(config)
-	if err != nil {
-		return false, err
+// TestConnection tests if a connection can be established, going through the
+// SSH tunnel when one is configured. The result is always non-nil so the
+// diagnostics gathered before a failure can still be shown.
+func (m *Manager) TestConnection(ctx context.Context, config ConnectionConfig) (*ConnectionTestResult, error) {
+	result := &ConnectionTestResult{Diagnostics: []string{}}
+	fail := func(err error) (*ConnectionTestResult, error) {
+		result.Error = err.Error()
+		return result, err
 	}
 
-	db, err := driver.Connect(config)
+	if config.UseSSHTunnel && !strings.EqualFold(config.Type, "sqlite") {
+		_, diagnostics, err := applySSHConfigHost(config)
+		if err != nil {
+			return fail(err)
+		}
+		result.Diagnostics = append(result.Diagnostics, diagnostics...)
+	}
+
+	s, err := m.openSession(ctx, "", config)
 	if err != nil {
-		return false, err
+		return fail(describeTLSError(err))
+	}
+	defer s.close()
+
+	if s.tunnel != nil {
+		result.Diagnostics = append(result.Diagnostics, fmt.Sprintf("SSH tunnel open on %s", s.tunnel.LocalAddr()))
 	}
-	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		return false, fmt.Errorf("failed to ping: %w", err)
+	if err := s.db.PingContext(ctx); err != nil {
+		return fail(fmt.Errorf("failed to ping: %w", describeTLSError(err)))
 	}
+	result.Diagnostics = append(result.Diagnostics, "Database connection succeeded")
 
-	return true, nil
+	result.Success = true
+	return result, nil
 }
 
-// IsConnected returns whether we're connected to a database
-func (m *Manager) IsConnected() bool {
+// IsConnected returns whether a session is open under connID
+func (m *Manager) IsConnected(connID string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.db != nil
+	_, ok := m.sessions[connID]
+	return ok
 }
 
-// GetCurrentConfig returns the current connection config
-func (m *Manager) GetCurrentConfig() *ConnectionConfig {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.config
+// GetCurrentConfig returns the connection config of the given session
+func (m *Manager) GetCurrentConfig(connID string) *ConnectionConfig {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil
+	}
+	return s.getConfig()
 }
 
-// getDB returns the database connection for internal use
-func (m *Manager) getDB() *sql.DB {
+// getSession resolves a session by ID. An empty ID resolves to the active session.
+func (m *Manager) getSession(connID string) (*session, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.db
+
+	if connID == "" {
+		connID = m.activeID
+	}
+
+	s, ok := m.sessions[connID]
+	if !ok || s.db == nil {
+		return nil, fmt.Errorf("not connected to database")
+	}
+	return s, nil
 }
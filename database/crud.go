@@ -1,18 +1,24 @@
 package database
 
 import (
+	"context"
 	"fmt"
 )
 
 // TableDataRequest represents a request for paginated table data
 type TableDataRequest struct {
-	Database string `json:"database"`
-	Table    string `json:"table"`
-	Page     int    `json:"page"`
-	PageSize int    `json:"pageSize"`
-	OrderBy  string `json:"orderBy"`
-	OrderDir string `json:"orderDir"`
-	Filters  string `json:"filters"`
+	Database string  `json:"database"`
+	Schema   string  `json:"schema,omitempty"` // Postgres schema; "" means public
+	Table    string  `json:"table"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"pageSize"`
+	OrderBy  string  `json:"orderBy"`
+	OrderDir string  `json:"orderDir"`
+	Filter   *Filter `json:"filter,omitempty"` // Compiled to parameterized SQL
+
+	// SQL pasted into WHERE as it is. Explicit opt-in for conditions the
+	// structured filter can't express; can't be combined with Filter.
+	RawWhere string `json:"rawWhere,omitempty"`
 }
 
 // TableDataResponse represents paginated table data with metadata
@@ -23,38 +29,51 @@ type TableDataResponse struct {
 	Page       int             `json:"page"`
 	PageSize   int             `json:"pageSize"`
 	TotalPages int             `json:"totalPages"`
-	PrimaryKey string          `json:"primaryKey"`
+	Key        RowIdentity     `json:"key"`     // How rows are identified for editing
+	RowKeys    []RowKey        `json:"rowKeys"` // Key of each row, to pass back to UpdateRow and DeleteRow
 }
 
 // RowData represents a single row with column-value pairs
 type RowData map[string]interface{}
 
 // GetTableData returns paginated table data
-func (m *Manager) GetTableData(req TableDataRequest) (*TableDataResponse, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+func (m *Manager) GetTableData(ctx context.Context, connID string, req TableDataRequest) (*TableDataResponse, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get columns info
-	columns, err := m.GetColumns(req.Database, req.Table)
+	namespace, err := s.namespace(req.Database, req.Schema)
 	if err != nil {
 		return nil, err
 	}
 
-	// Find primary key
-	var primaryKey string
-	for _, col := range columns {
-		if col.Key == "PRI" {
-			primaryKey = col.Name
-			break
+	// Get columns and indexes
+	details, err := m.GetTableInfo(ctx, connID, req.Database, req.Schema, req.Table)
+	if err != nil {
+		return nil, err
+	}
+	columns := details.Columns
+	key := tableRowIdentity(s.driver, details)
+
+	where, args, err := compileFilter(s.driver, columns, req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	if req.RawWhere != "" {
+		if where != "" {
+			return nil, fmt.Errorf("use either a structured filter or a raw WHERE clause, not both")
+		}
+		if len(s.driver.SplitStatements("SELECT 1 WHERE "+req.RawWhere)) > 1 {
+			return nil, fmt.Errorf("raw WHERE clause must not contain more than one statement")
 		}
+		where = req.RawWhere
 	}
 
 	// Get total row count
 	var totalRows int64
-	countQuery := m.driver.BuildCountQuery(req.Database, req.Table, req.Filters)
-	if err := db.QueryRow(countQuery).Scan(&totalRows); err != nil {
+	countQuery := s.driver.BuildCountQuery(namespace, req.Table, where)
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalRows); err != nil {
 		return nil, fmt.Errorf("failed to count rows: %w", err)
 	}
 
@@ -68,14 +87,28 @@ func (m *Manager) GetTableData(req TableDataRequest) (*TableDataResponse, error)
 		page = 1
 	}
 
-	query := m.driver.BuildTableDataQuery(req, primaryKey)
+	query := s.driver.BuildTableDataQuery(req, key, where)
 
 	// Execute query
-	result, err := m.ExecuteQuery(query)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := collectRows(rows)
 	if err != nil {
 		return nil, err
 	}
 
+	rowKeys := rowKeys(key, result)
+	if key.Kind == RowKeyLocator {
+		// The locator is selected last and isn't one of the table's columns
+		for i, row := range result.Rows {
+			result.Rows[i] = row[:len(row)-1]
+		}
+	}
+
 	totalPages := int(totalRows) / pageSize
 	if int(totalRows)%pageSize != 0 {
 		totalPages++
@@ -88,15 +121,23 @@ func (m *Manager) GetTableData(req TableDataRequest) (*TableDataResponse, error)
 		Page:       page,
 		PageSize:   pageSize,
 		TotalPages: totalPages,
-		PrimaryKey: primaryKey,
+		Key:        key,
+		RowKeys:    rowKeys,
 	}, nil
 }
 
 // InsertRow inserts a new row into a table
-func (m *Manager) InsertRow(database, table string, data RowData) (*ExecuteResult, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+func (m *Manager) InsertRow(ctx context.Context, connID, database, schema, table string, data RowData) (*ExecuteResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable("insert"); err != nil {
+		return nil, err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(data) == 0 {
@@ -111,9 +152,9 @@ func (m *Manager) InsertRow(database, table string, data RowData) (*ExecuteResul
 		values = append(values, val)
 	}
 
-	query := m.driver.BuildInsertQuery(database, table, columns)
+	query := s.driver.BuildInsertQuery(namespace, table, columns)
 
-	res, err := db.Exec(query, values...)
+	res, err := s.db.ExecContext(ctx, query, values...)
 	if err != nil {
 		return nil, fmt.Errorf("insert failed: %w", err)
 	}
@@ -127,16 +168,27 @@ func (m *Manager) InsertRow(database, table string, data RowData) (*ExecuteResul
 	}, nil
 }
 
-// UpdateRow updates a row by primary key
-func (m *Manager) UpdateRow(database, table, primaryKey string, primaryValue interface{}, data RowData) (*ExecuteResult, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+// UpdateRow updates the row identified by key. It is refused, and nothing
+// changes, if key matches more than one row.
+func (m *Manager) UpdateRow(ctx context.Context, connID, database, schema, table string, key RowKey, data RowData) (*ExecuteResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable("update"); err != nil {
+		return nil, err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(data) == 0 {
 		return nil, fmt.Errorf("no data provided")
 	}
+	if err := m.checkRowKeys(ctx, s, connID, database, schema, table, key); err != nil {
+		return nil, err
+	}
 
 	var columns []string
 	var values []interface{}
@@ -145,62 +197,85 @@ func (m *Manager) UpdateRow(database, table, primaryKey string, primaryValue int
 		columns = append(columns, col)
 		values = append(values, val)
 	}
-	values = append(values, primaryValue)
 
-	query := m.driver.BuildUpdateQuery(database, table, primaryKey, columns)
-
-	res, err := db.Exec(query, values...)
+	where, keyArgs, err := buildKeyCondition(s.driver, key, len(values))
 	if err != nil {
-		return nil, fmt.Errorf("update failed: %w", err)
+		return nil, err
 	}
+	values = append(values, keyArgs...)
 
-	rowsAffected, _ := res.RowsAffected()
+	query := s.driver.BuildUpdateQuery(namespace, table, columns, where)
 
-	return &ExecuteResult{
-		RowsAffected: rowsAffected,
-	}, nil
-}
-
-// DeleteRow deletes a row by primary key
-func (m *Manager) DeleteRow(database, table, primaryKey string, primaryValue interface{}) (*ExecuteResult, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	query := m.driver.BuildDeleteQuery(database, table, primaryKey)
-
-	res, err := db.Exec(query, primaryValue)
+	rowsAffected, err := execSingleRow(ctx, tx, query, values...)
 	if err != nil {
-		return nil, fmt.Errorf("delete failed: %w", err)
+		return nil, fmt.Errorf("update failed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
 	}
-
-	rowsAffected, _ := res.RowsAffected()
 
 	return &ExecuteResult{
 		RowsAffected: rowsAffected,
 	}, nil
 }
 
-// DeleteRows deletes multiple rows by primary key values
-func (m *Manager) DeleteRows(database, table, primaryKey string, primaryValues []interface{}) (*ExecuteResult, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+// DeleteRow deletes the row identified by key. It is refused, and nothing
+// changes, if key matches more than one row.
+func (m *Manager) DeleteRow(ctx context.Context, connID, database, schema, table string, key RowKey) (*ExecuteResult, error) {
+	return m.DeleteRows(ctx, connID, database, schema, table, []RowKey{key})
+}
+
+// DeleteRows deletes the rows identified by keys in one transaction. It is
+// refused, and nothing changes, if any key matches more than one row.
+func (m *Manager) DeleteRows(ctx context.Context, connID, database, schema, table string, keys []RowKey) (*ExecuteResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable("delete"); err != nil {
+		return nil, err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(primaryValues) == 0 {
+	if len(keys) == 0 {
 		return &ExecuteResult{}, nil
 	}
+	if err := m.checkRowKeys(ctx, s, connID, database, schema, table, keys...); err != nil {
+		return nil, err
+	}
 
-	query := m.driver.BuildBatchDeleteQuery(database, table, primaryKey, len(primaryValues))
-
-	res, err := db.Exec(query, primaryValues...)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("delete failed: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// One statement per key, so a key matching several rows can't hide
+	// behind one matching none
+	var rowsAffected int64
+	for _, key := range keys {
+		where, args, err := buildKeyCondition(s.driver, key, 0)
+		if err != nil {
+			return nil, err
+		}
+		n, err := execSingleRow(ctx, tx, s.driver.BuildDeleteQuery(namespace, table, where), args...)
+		if err != nil {
+			return nil, fmt.Errorf("delete failed: %w", err)
+		}
+		rowsAffected += n
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
 	}
-
-	rowsAffected, _ := res.RowsAffected()
 
 	return &ExecuteResult{
 		RowsAffected: rowsAffected,
@@ -208,14 +283,18 @@ func (m *Manager) DeleteRows(database, table, primaryKey string, primaryValues [
 }
 
 // GetDistinctValues returns distinct values for a column
-func (m *Manager) GetDistinctValues(database, table, column string) ([]string, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+func (m *Manager) GetDistinctValues(ctx context.Context, connID, database, schema, table, column string) ([]string, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return nil, err
 	}
 
-	query := m.driver.BuildDistinctValuesQuery(database, table, column)
-	rows, err := db.Query(query)
+	query := s.driver.BuildDistinctValuesQuery(namespace, table, column)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,36 +1,147 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"sort"
+	"strings"
 )
 
 // Driver defines the behavior for different database dialects
 type Driver interface {
 	// Connection
-	Connect(config ConnectionConfig) (*sql.DB, error)
+	Connect(ctx context.Context, config ConnectionConfig) (*sql.DB, error)
 
-	// Schema Inspection
-	GetDatabases(db *sql.DB) ([]string, error)
-	GetTables(db *sql.DB, database string) ([]TableInfo, error)
-	GetColumns(db *sql.DB, database, table string) ([]ColumnInfo, error)
-	GetIndexes(db *sql.DB, database, table string) ([]IndexInfo, error)
+	// Schema Inspection. Table-level calls take the namespace holding the
+	// table: the database on MySQL, the attached database on SQLite and the
+	// schema on Postgres, see session.namespace.
+	GetDatabases(ctx context.Context, db *sql.DB) ([]string, error)
+	GetSchemas(ctx context.Context, db *sql.DB) ([]string, error)
+	GetTables(ctx context.Context, db *sql.DB, namespace string) ([]TableInfo, error)
+	GetColumns(ctx context.Context, db *sql.DB, namespace, table string) ([]ColumnInfo, error)
+	GetIndexes(ctx context.Context, db *sql.DB, namespace, table string) ([]IndexInfo, error)
 
 	// Query Building & Dialect Specifics
-	BuildTableDataQuery(req TableDataRequest, primaryKey string) string
-	BuildCountQuery(database, table, filters string) string
+	BuildTableDataQuery(req TableDataRequest, key RowIdentity, where string) string // where is a compiled filter
+	BuildCountQuery(database, table, where string) string
 	BuildDistinctValuesQuery(database, table, column string) string
 
 	// Table Operations
-	BuildAlterTableQuery(database, table string, alteration TableAlteration) ([]string, error)
+	// cleanup runs on the same connection after the statements however they end
+	BuildAlterTableQuery(database, table string, current *TableDetails, alteration TableAlteration) (statements, cleanup []string, err error)
 	BuildTruncateTableQuery(database, table string) string
 	BuildDropTableQuery(database, table string) string
+	BuildUseDatabaseQuery(database string) string // Empty when the dialect has nothing to run
+	ReconnectsOnUseDatabase() bool                // Switching databases needs a new connection
+	TransactionalDDL() bool                       // DDL can be rolled back rather than committing implicitly
 
 	// CRUD Operations
 	BuildInsertQuery(database, table string, columns []string) string
-	BuildUpdateQuery(database, table, primaryKey string, columns []string) string
-	BuildDeleteQuery(database, table, primaryKey string) string
-	BuildBatchDeleteQuery(database, table, primaryKey string, count int) string
+	BuildUpdateQuery(database, table string, columns []string, where string) string // where binds after the SET values
+	BuildDeleteQuery(database, table, where string) string
+	RowLocator() string      // Pseudo-column locating a physical row, e.g. ctid; empty if none
+	SupportsReturning() bool // INSERT ... RETURNING reports the inserted row
+
+	// Script Handling
+	SplitStatements(script string) []ScriptStatement
+
+	// Query Cancellation
+	ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error)
+	BuildCancelQuery(connectionID int64) string
 
 	// Quote identifiers (backticks for MySQL, double quotes for Postgres)
 	QuoteIdentifier(name string) string
+	QualifyTable(namespace, table string) string // Quoted table name within its namespace
+
+	// Filters
+	Placeholder(index int) string // Bind parameter marker for the 1-based index
+	// Text value at path in a JSON column expression, reading the path from
+	// the bind parameter at placeholder, and the argument to bind there
+	JSONPathExpr(column string, path []string, placeholder string) (string, interface{})
+}
+
+// tableDataClauses returns the select list and ORDER BY clause of a table data
+// query: the row locator is selected when it identifies rows, and rows are
+// ordered by their key unless the request orders them
+func tableDataClauses(d Driver, req TableDataRequest, key RowIdentity) (selectList, orderBy string) {
+	selectList = "*"
+	if key.Kind == RowKeyLocator {
+		selectList += ", " + d.QuoteIdentifier(key.Columns[0])
+	}
+
+	orderDir := strings.ToUpper(req.OrderDir)
+	if orderDir != "DESC" {
+		orderDir = "ASC"
+	}
+
+	var columns []string
+	switch {
+	case req.OrderBy != "":
+		columns = []string{req.OrderBy}
+	case key.Kind == RowKeyPrimary || key.Kind == RowKeyUnique:
+		columns = key.Columns
+	}
+	if len(columns) == 0 {
+		return selectList, ""
+	}
+
+	parts := make([]string, len(columns))
+	for i, col := range columns {
+		parts[i] = d.QuoteIdentifier(col) + " " + orderDir
+	}
+	return selectList, " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// applyIndexKeys fills ColumnInfo.Key from a table's indexes the way MySQL
+// reports it: PRI for primary key columns, UNI for single-column unique
+// indexes and MUL for the leading column of any other index
+func applyIndexKeys(columns []ColumnInfo, indexes []IndexInfo) {
+	for _, idx := range indexes {
+		if !idx.IsPrimary {
+			continue
+		}
+		for _, name := range idx.Columns {
+			for i := range columns {
+				if columns[i].Name == name {
+					columns[i].Key = "PRI"
+				}
+			}
+		}
+	}
+
+	for _, idx := range indexes {
+		if idx.IsPrimary || len(idx.Columns) == 0 {
+			continue
+		}
+		for i := range columns {
+			if columns[i].Name != idx.Columns[0] || columns[i].Key == "PRI" {
+				continue
+			}
+			if idx.IsUnique && idx.Predicate == "" && len(idx.Columns) == 1 {
+				columns[i].Key = "UNI"
+			} else if columns[i].Key == "" {
+				columns[i].Key = "MUL"
+			}
+		}
+	}
+}
+
+// extraParamKeys returns the keys of config.ExtraParams in order, leaving out
+// those the driver sets itself so a pasted URI cannot override them
+func extraParamKeys(config ConnectionConfig, reserved ...string) []string {
+	var keys []string
+	for key := range config.ExtraParams {
+		skip := false
+		for _, r := range reserved {
+			if strings.EqualFold(key, r) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
 }
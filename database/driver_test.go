@@ -0,0 +1,62 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyIndexKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes []IndexInfo
+		want    []string // Key of columns a, b and c
+	}{
+		{name: "no indexes", want: []string{"", "", ""}},
+		{
+			name:    "composite primary key",
+			indexes: []IndexInfo{{Name: "pk", Columns: []string{"b", "a"}, IsPrimary: true, IsUnique: true}},
+			want:    []string{"PRI", "PRI", ""},
+		},
+		{
+			name:    "single column unique",
+			indexes: []IndexInfo{{Name: "u", Columns: []string{"c"}, IsUnique: true}},
+			want:    []string{"", "", "UNI"},
+		},
+		{
+			name:    "leading column of a composite unique index",
+			indexes: []IndexInfo{{Name: "u", Columns: []string{"b", "c"}, IsUnique: true}},
+			want:    []string{"", "MUL", ""},
+		},
+		{
+			name:    "partial unique index",
+			indexes: []IndexInfo{{Name: "u", Columns: []string{"a"}, IsUnique: true, Predicate: "a > 0"}},
+			want:    []string{"MUL", "", ""},
+		},
+		{
+			name: "primary key wins",
+			indexes: []IndexInfo{
+				{Name: "u", Columns: []string{"a"}, IsUnique: true},
+				{Name: "pk", Columns: []string{"a"}, IsPrimary: true, IsUnique: true},
+				{Name: "i", Columns: []string{"b"}},
+				{Name: "u2", Columns: []string{"b"}, IsUnique: true},
+			},
+			want: []string{"PRI", "UNI", ""},
+		},
+		{name: "expression index", indexes: []IndexInfo{{Name: "e", Columns: []string{}}}, want: []string{"", "", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns := []ColumnInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+			applyIndexKeys(columns, tt.indexes)
+
+			got := make([]string, len(columns))
+			for i, c := range columns {
+				got[i] = c.Key
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
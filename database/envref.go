@@ -0,0 +1,90 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envReference matches ${NAME} references to environment variables
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// isEnvReference reports whether value consists only of ${NAME} references,
+// so it names a secret rather than holding one
+func isEnvReference(value string) bool {
+	return value != "" && envReference.ReplaceAllString(value, "") == ""
+}
+
+// expandEnvReferences replaces the ${NAME} references in value. Unlike
+// os.ExpandEnv it leaves a bare $ alone, since passwords may contain one.
+func expandEnvReferences(value string) (string, error) {
+	var missing string
+	expanded := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok && missing == "" {
+			missing = name
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("environment variable %s is not set", missing)
+	}
+	return expanded, nil
+}
+
+// envSecret reports whether the secret field name, as listed by
+// connectionSecrets.fieldNames, may hold ${NAME} references. Only passwords
+// and passphrases do: a reference in an address or path would let a shared
+// bundle send environment values out, e.g. in a DNS lookup.
+func envSecret(name string) bool {
+	switch name {
+	case "password", "sshPassword", "sshPassphrase":
+		return true
+	}
+	return strings.HasPrefix(name, "jumpHosts.") &&
+		(strings.HasSuffix(name, ".password") || strings.HasSuffix(name, ".passphrase"))
+}
+
+// resolveEnvReferences returns config with the ${NAME} references in its
+// passwords and passphrases replaced from the environment
+func resolveEnvReferences(config ConnectionConfig) (ConnectionConfig, error) {
+	secrets := extractSecrets(&config)
+
+	var err error
+	secrets.each(func(name string, value *string) {
+		if err != nil || !envSecret(name) {
+			return
+		}
+		*value, err = expandEnvReferences(*value)
+	})
+	if err != nil {
+		return config, err
+	}
+
+	secrets.apply(&config)
+	return config, nil
+}
+
+// usesEnvReferences reports whether a password or passphrase of config holds
+// ${NAME} references
+func usesEnvReferences(config ConnectionConfig) bool {
+	found := false
+	secrets := extractSecrets(&config)
+	secrets.each(func(name string, value *string) {
+		found = found || (envSecret(name) && envReference.MatchString(*value))
+	})
+	return found
+}
+
+// hasStrayEnvReferences reports whether config has ${NAME} references in
+// fields they are not expanded in
+func hasStrayEnvReferences(config ConnectionConfig) bool {
+	secrets := extractSecrets(&config)
+	secrets.filter(func(name, _ string) bool { return !envSecret(name) }).apply(&config)
+
+	data, err := json.Marshal(config)
+	return err == nil && envReference.Match(data)
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnvReferences(t *testing.T) {
+	t.Setenv("DB_PASS", "s3cret")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{value: "${DB_PASS}", want: "s3cret"},
+		{value: "pre-${DB_PASS}-${EMPTY}post", want: "pre-s3cret-post"},
+		{value: "pa$$word$", want: "pa$$word$"},
+		{value: "$DB_PASS", want: "$DB_PASS"},
+		{value: "${1BAD}", want: "${1BAD}"},
+		{value: "${MERGEN_TEST_UNSET}", wantErr: "MERGEN_TEST_UNSET is not set"},
+	}
+
+	for _, tt := range tests {
+		got, err := expandEnvReferences(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: error = %v, want one containing %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolveEnvReferences(t *testing.T) {
+	t.Setenv("DB_PASS", "s3cret")
+	t.Setenv("JUMP_PASS", "hop")
+
+	config := ConnectionConfig{
+		Host:         "${DB_HOST}",
+		Password:     "${DB_PASS}",
+		SSLClientKey: "${DB_PASS}",
+		SSHJumpHosts: []SSHJumpHost{{Host: "bastion", Password: "${JUMP_PASS}"}},
+	}
+	if !usesEnvReferences(config) || !hasStrayEnvReferences(config) {
+		t.Fatal("references not found")
+	}
+
+	got, err := resolveEnvReferences(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Password != "s3cret" || got.SSHJumpHosts[0].Password != "hop" {
+		t.Errorf("passwords = %q, %q", got.Password, got.SSHJumpHosts[0].Password)
+	}
+	// Only passwords and passphrases are expanded
+	if got.Host != "${DB_HOST}" || got.SSLClientKey != "${DB_PASS}" {
+		t.Errorf("host %q, client key %q expanded", got.Host, got.SSLClientKey)
+	}
+	if config.SSHJumpHosts[0].Password != "${JUMP_PASS}" {
+		t.Error("resolving changed the original config")
+	}
+}
+
+func TestConnectEnvReferences(t *testing.T) {
+	tests := []struct {
+		name          string
+		envReferences bool
+		wantErr       string
+	}{
+		// A password that happens to look like a reference is used as typed
+		{name: "literal password", envReferences: false},
+		{name: "marked as a reference", envReferences: true, wantErr: "MERGEN_TEST_UNSET is not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager()
+			defer m.DisconnectAll()
+			config := ConnectionConfig{
+				Type:          "sqlite",
+				FilePath:      filepath.Join(t.TempDir(), "test.db"),
+				Password:      "${MERGEN_TEST_UNSET}",
+				EnvReferences: tt.envReferences,
+			}
+
+			err := m.Connect(context.Background(), "test", config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
@@ -11,15 +12,20 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
-// ExportTable exports the entire table to the specified file format
-func (m *Manager) ExportTable(dbName, tableName, format, outputPath string) error {
-	db := m.getDB()
-	if db == nil {
-		return fmt.Errorf("not connected to database")
+// ExportTable exports the entire table to the specified file format.
+// A non-empty queryID makes the export cancellable through CancelQuery.
+func (m *Manager) ExportTable(ctx context.Context, connID, queryID, dbName, schema, tableName, format, outputPath string) error {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
+	}
+	namespace, err := s.namespace(dbName, schema)
+	if err != nil {
+		return err
 	}
 
 	// 1. Get Columns to ensure order and headers
-	columns, err := m.GetColumns(dbName, tableName)
+	columns, err := m.GetColumns(ctx, connID, dbName, schema, tableName)
 	if err != nil {
 		return fmt.Errorf("failed to get columns: %w", err)
 	}
@@ -30,25 +36,15 @@ func (m *Manager) ExportTable(dbName, tableName, format, outputPath string) erro
 	}
 
 	// 2. Query All Data (No Pagination)
-	// We construct a simple SELECT * query using the driver's quoting
-	quotedDb := m.driver.QuoteIdentifier(dbName)
-	quotedTable := m.driver.QuoteIdentifier(tableName)
+	query := fmt.Sprintf("SELECT * FROM %s", s.driver.QualifyTable(namespace, tableName))
 
-	// Postgres uses "db"."schema"."table" or just "schema"."table" but our abstraction
-	// often treats dbName/Schema loosely.
-	// For simpler logic, we rely on the fact that existing drivers use:
-	// MySQL: `db`.`table`
-	// Postgres: "schema"."table" (where dbName param is usually schema)
-
-	// We'll trust the driver to quote correctly if we use individual calls,
-	// but we need to join them.
-	// Actually, let's look at how BuildTableDataQuery does it.
-	// MySQL: fmt.Sprintf("SELECT * FROM `%s`.`%s` ...", req.Database, req.Table, ...)
-
-	// We will try to rely on a generic query.
-	query := fmt.Sprintf("SELECT * FROM %s.%s", quotedDb, quotedTable)
+	ctx, q, release, err := m.trackQuery(ctx, s, "", queryID)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	rows, err := db.Query(query)
+	rows, err := q.QueryContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("query failed: %w", err)
 	}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter is a node in a table data filter: either a group combining its
+// children with AND/OR, or a condition on one column
+type Filter struct {
+	// Group
+	Logic    string   `json:"logic,omitempty"` // "and" (default) or "or"
+	Children []Filter `json:"children,omitempty"`
+
+	// Condition
+	Column   string        `json:"column,omitempty"`
+	JSONPath []string      `json:"jsonPath,omitempty"` // Keys into a JSON column, e.g. ["address", "city"] or ["tags", "0"]
+	Operator string        `json:"operator,omitempty"` // =, !=, <, <=, >, >=, like, not like, in, not in, between, is null, is not null
+	Value    interface{}   `json:"value,omitempty"`
+	Values   []interface{} `json:"values,omitempty"` // For in / not in, and the two bounds of between
+}
+
+// comparisonOperators maps filter operators to their SQL form
+var comparisonOperators = map[string]string{
+	"=":        "=",
+	"!=":       "<>",
+	"<>":       "<>",
+	"<":        "<",
+	"<=":       "<=",
+	">":        ">",
+	">=":       ">=",
+	"like":     "LIKE",
+	"not like": "NOT LIKE",
+}
+
+// filterCompiler turns a Filter into a parameterized WHERE clause for a
+// dialect. Values only ever reach the query as bind arguments.
+type filterCompiler struct {
+	driver  Driver
+	columns map[string]bool // Columns of the table; others are rejected
+	args    []interface{}
+}
+
+// compileFilter compiles f into a WHERE clause without the keyword and the
+// arguments it binds. An empty filter compiles to an empty clause.
+func compileFilter(driver Driver, columns []ColumnInfo, f *Filter) (string, []interface{}, error) {
+	if f == nil {
+		return "", nil, nil
+	}
+
+	c := &filterCompiler{driver: driver, columns: make(map[string]bool, len(columns))}
+	for _, col := range columns {
+		c.columns[col.Name] = true
+	}
+
+	where, err := c.compile(*f)
+	if err != nil {
+		return "", nil, err
+	}
+	return where, c.args, nil
+}
+
+func (c *filterCompiler) compile(f Filter) (string, error) {
+	if f.Column == "" {
+		return c.compileGroup(f)
+	}
+	return c.compileCondition(f)
+}
+
+func (c *filterCompiler) compileGroup(f Filter) (string, error) {
+	var joiner string
+	switch strings.ToLower(f.Logic) {
+	case "", "and":
+		joiner = " AND "
+	case "or":
+		joiner = " OR "
+	default:
+		return "", fmt.Errorf("unknown filter logic: %s", f.Logic)
+	}
+
+	var parts []string
+	for _, child := range f.Children {
+		part, err := c.compile(child)
+		if err != nil {
+			return "", err
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, joiner) + ")", nil
+}
+
+func (c *filterCompiler) compileCondition(f Filter) (string, error) {
+	if !c.columns[f.Column] {
+		return "", fmt.Errorf("unknown column: %s", f.Column)
+	}
+
+	expr := c.driver.QuoteIdentifier(f.Column)
+	if len(f.JSONPath) > 0 {
+		var path interface{}
+		expr, path = c.driver.JSONPathExpr(expr, f.JSONPath, c.driver.Placeholder(len(c.args)+1))
+		c.args = append(c.args, path)
+	}
+
+	op := strings.ToLower(strings.Join(strings.Fields(f.Operator), " "))
+	if sqlOp, ok := comparisonOperators[op]; ok {
+		if f.Value == nil {
+			return "", fmt.Errorf("operator %s on %s needs a value; use is null to match NULL", f.Operator, f.Column)
+		}
+		return fmt.Sprintf("%s %s %s", expr, sqlOp, c.bind(f.Value)), nil
+	}
+
+	switch op {
+	case "in", "not in":
+		if len(f.Values) == 0 {
+			return "", fmt.Errorf("operator %s on %s needs at least one value", op, f.Column)
+		}
+		placeholders := make([]string, len(f.Values))
+		for i, v := range f.Values {
+			placeholders[i] = c.bind(v)
+		}
+		return fmt.Sprintf("%s %s (%s)", expr, strings.ToUpper(op), strings.Join(placeholders, ", ")), nil
+	case "between":
+		if len(f.Values) != 2 {
+			return "", fmt.Errorf("operator between on %s needs two values", f.Column)
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", expr, c.bind(f.Values[0]), c.bind(f.Values[1])), nil
+	case "is null", "is not null":
+		return fmt.Sprintf("%s %s", expr, strings.ToUpper(op)), nil
+	}
+	return "", fmt.Errorf("unknown filter operator: %s", f.Operator)
+}
+
+// bind adds a bind argument and returns its placeholder
+func (c *filterCompiler) bind(v interface{}) string {
+	c.args = append(c.args, v)
+	return c.driver.Placeholder(len(c.args))
+}
+
+// jsonPathString builds a $."key"[0] style path as understood by MySQL and
+// SQLite, quoting keys so they can't alter the path
+func jsonPathString(path []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, key := range path {
+		if isArrayIndex(key) {
+			b.WriteString("[" + key + "]")
+			continue
+		}
+		key = strings.ReplaceAll(key, `\`, `\\`)
+		key = strings.ReplaceAll(key, `"`, `\"`)
+		b.WriteString(`."` + key + `"`)
+	}
+	return b.String()
+}
+
+// isArrayIndex reports whether a JSON path key is an array index
+func isArrayIndex(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestCompileFilter(t *testing.T) {
+	columns := []ColumnInfo{{Name: "id"}, {Name: "name"}, {Name: "data"}}
+
+	tests := []struct {
+		name      string
+		driver    Driver
+		filter    *Filter
+		wantWhere string
+		wantArgs  []interface{}
+		wantErr   string
+	}{
+		{
+			name:   "nil filter",
+			driver: &MySQLDriver{},
+		},
+		{
+			name:   "empty group",
+			driver: &MySQLDriver{},
+			filter: &Filter{Logic: "or"},
+		},
+		{
+			name:      "comparison",
+			driver:    &MySQLDriver{},
+			filter:    &Filter{Column: "id", Operator: ">=", Value: 5},
+			wantWhere: "`id` >= ?",
+			wantArgs:  []interface{}{5},
+		},
+		{
+			name:      "Postgres placeholders",
+			driver:    &PostgresDriver{},
+			filter:    &Filter{Children: []Filter{{Column: "id", Operator: "!=", Value: 1}, {Column: "name", Operator: "LIKE", Value: "a%"}}},
+			wantWhere: `("id" <> $1 AND "name" LIKE $2)`,
+			wantArgs:  []interface{}{1, "a%"},
+		},
+		{
+			name:   "nested groups",
+			driver: &SQLiteDriver{},
+			filter: &Filter{Logic: "or", Children: []Filter{
+				{Column: "name", Operator: "is null"},
+				{Children: []Filter{{Column: "id", Operator: "between", Values: []interface{}{1, 9}}, {Column: "name", Operator: "not  like", Value: "x"}}},
+			}},
+			wantWhere: `("name" IS NULL OR ("id" BETWEEN ? AND ? AND "name" NOT LIKE ?))`,
+			wantArgs:  []interface{}{1, 9, "x"},
+		},
+		{
+			name:      "single child is not parenthesized",
+			driver:    &MySQLDriver{},
+			filter:    &Filter{Children: []Filter{{Column: "name", Operator: "is not null"}, {Children: nil}}},
+			wantWhere: "`name` IS NOT NULL",
+		},
+		{
+			name:      "in",
+			driver:    &PostgresDriver{},
+			filter:    &Filter{Column: "id", Operator: "not in", Values: []interface{}{1, 2, 3}},
+			wantWhere: `"id" NOT IN ($1, $2, $3)`,
+			wantArgs:  []interface{}{1, 2, 3},
+		},
+		{
+			name:      "MySQL JSON path",
+			driver:    &MySQLDriver{},
+			filter:    &Filter{Column: "data", JSONPath: []string{"address", "0", `c"ity`}, Operator: "=", Value: "Oslo"},
+			wantWhere: "JSON_UNQUOTE(JSON_EXTRACT(`data`, ?)) = ?",
+			wantArgs:  []interface{}{`$."address"[0]."c\"ity"`, "Oslo"},
+		},
+		{
+			name:      "Postgres JSON path",
+			driver:    &PostgresDriver{},
+			filter:    &Filter{Column: "data", JSONPath: []string{"tags", "0"}, Operator: "=", Value: "x"},
+			wantWhere: `("data" #>> $1) = $2`,
+			wantArgs:  []interface{}{pq.Array([]string{"tags", "0"}), "x"},
+		},
+		{
+			name:      "values never reach the query",
+			driver:    &MySQLDriver{},
+			filter:    &Filter{Column: "name", Operator: "=", Value: "'; DROP TABLE t; --"},
+			wantWhere: "`name` = ?",
+			wantArgs:  []interface{}{"'; DROP TABLE t; --"},
+		},
+		{
+			name:    "unknown column",
+			driver:  &MySQLDriver{},
+			filter:  &Filter{Column: "id` = 1 OR `1", Operator: "is null"},
+			wantErr: "unknown column",
+		},
+		{
+			name:    "unknown operator",
+			driver:  &MySQLDriver{},
+			filter:  &Filter{Column: "id", Operator: "regexp", Value: "x"},
+			wantErr: "unknown filter operator",
+		},
+		{
+			name:    "unknown logic",
+			driver:  &MySQLDriver{},
+			filter:  &Filter{Logic: "xor"},
+			wantErr: "unknown filter logic",
+		},
+		{
+			name:    "comparison without value",
+			driver:  &MySQLDriver{},
+			filter:  &Filter{Column: "id", Operator: "="},
+			wantErr: "needs a value",
+		},
+		{
+			name:    "in without values",
+			driver:  &MySQLDriver{},
+			filter:  &Filter{Column: "id", Operator: "in"},
+			wantErr: "at least one value",
+		},
+		{
+			name:    "between with one bound",
+			driver:  &MySQLDriver{},
+			filter:  &Filter{Column: "id", Operator: "between", Values: []interface{}{1}},
+			wantErr: "needs two values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, err := compileFilter(tt.driver, columns, tt.filter)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
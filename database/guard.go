@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kinds of destructive statements
+const (
+	DestructiveUpdateAll = "update-without-where"
+	DestructiveDeleteAll = "delete-without-where"
+	DestructiveDrop      = "drop"
+	DestructiveTruncate  = "truncate"
+	DestructiveAlter     = "alter"
+)
+
+// productionColor is the color the connection dialog offers for production
+const productionColor = "#ef4444"
+
+// confirmationTTL is how long a confirmation token stays valid
+const confirmationTTL = 5 * time.Minute
+
+// StatementCheck describes what a statement would do to a connection
+type StatementCheck struct {
+	Destructive          bool   `json:"destructive"`
+	Kind                 string `json:"kind,omitempty"`
+	Reason               string `json:"reason,omitempty"`
+	RequiresConfirmation bool   `json:"requiresConfirmation"`   // Production connection; pass ConfirmToken to run it
+	ConfirmToken         string `json:"confirmToken,omitempty"` // Single use, bound to this statement
+}
+
+// DryRunResult reports what a statement did before it was rolled back
+type DryRunResult struct {
+	RowsAffected int64  `json:"rowsAffected"`
+	Kind         string `json:"kind,omitempty"` // Destructive kind, if any
+	Reason       string `json:"reason,omitempty"`
+	ElapsedMs    int64  `json:"elapsedMs"`
+}
+
+// ConfirmationRequiredError is returned when a destructive statement is run
+// on a production connection without a valid confirmation token. Running it
+// again with Token confirms it.
+type ConfirmationRequiredError struct {
+	Kind   string
+	Reason string
+	Token  string
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("confirmation required on a production connection: %s (confirm with token %s)", e.Reason, e.Token)
+}
+
+// pendingConfirmation is a token issued for one statement
+type pendingConfirmation struct {
+	statement string
+	expires   time.Time
+}
+
+// classifyStatement reports whether stmt destroys data or schema, and why.
+// mysqlComments treats # as starting a comment, as in the session's dialect.
+func classifyStatement(stmt string, mysqlComments bool) (kind, reason string) {
+	switch statementKeyword(stmt, mysqlComments) {
+	case "UPDATE":
+		if !hasTopLevelKeyword(stmt, "WHERE", mysqlComments) {
+			return DestructiveUpdateAll, "UPDATE without WHERE changes every row in the table"
+		}
+	case "DELETE":
+		if !hasTopLevelKeyword(stmt, "WHERE", mysqlComments) {
+			return DestructiveDeleteAll, "DELETE without WHERE removes every row in the table"
+		}
+	case "DROP":
+		return DestructiveDrop, "DROP removes the object and everything in it"
+	case "TRUNCATE":
+		return DestructiveTruncate, "TRUNCATE removes every row in the table"
+	case "ALTER":
+		return DestructiveAlter, "ALTER changes the schema and may drop columns or data"
+	}
+	return "", ""
+}
+
+// isProduction reports whether config is marked as production, either
+// explicitly or by the production color
+func isProduction(config *ConnectionConfig) bool {
+	return config != nil && (config.Production || strings.EqualFold(config.Color, productionColor))
+}
+
+// production reports whether the session was found to be production when it
+// connected
+func (s *session) production() bool {
+	config := s.getConfig()
+	return config != nil && config.Production
+}
+
+// dryRunnable lists the statements a dry run can roll back on dialects whose
+// DDL commits implicitly
+var dryRunnable = map[string]bool{
+	"INSERT":  true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"REPLACE": true,
+	"MERGE":   true,
+}
+
+// CheckStatement classifies a statement for the given session. On a
+// production connection a destructive statement gets a confirmation token
+// to pass when running it.
+func (m *Manager) CheckStatement(connID, query string) (*StatementCheck, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &StatementCheck{}
+	check.Kind, check.Reason = classifyStatement(query, hashComments(s.driver))
+	check.Destructive = check.Kind != ""
+	if check.Destructive && s.production() {
+		check.RequiresConfirmation = true
+		check.ConfirmToken = s.issueConfirmation(query)
+	}
+	return check, nil
+}
+
+// DryRunStatement runs a statement inside a transaction, reports the rows it
+// affected and rolls it back. Dialects whose DDL commits implicitly only dry
+// run DML, since anything else might not be undone.
+func (m *Manager) DryRunStatement(ctx context.Context, connID, query string) (*DryRunResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkStatement(query); err != nil {
+		return nil, err
+	}
+
+	result := &DryRunResult{}
+	result.Kind, result.Reason = classifyStatement(query, hashComments(s.driver))
+
+	if keyword := statementKeyword(query, hashComments(s.driver)); !s.driver.TransactionalDDL() && !dryRunnable[keyword] {
+		return nil, fmt.Errorf("dry run is not possible: only INSERT, UPDATE, DELETE, REPLACE and MERGE can be rolled back on this database, not %s", keyword)
+	}
+	if transactionControl(query) != "" {
+		return nil, fmt.Errorf("dry run is not possible for transaction control statements")
+	}
+
+	start := time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("statement failed: %w", err)
+	}
+	result.RowsAffected, _ = res.RowsAffected()
+
+	if err := tx.Rollback(); err != nil {
+		return nil, fmt.Errorf("failed to roll back dry run: %w", err)
+	}
+	result.ElapsedMs = time.Since(start).Milliseconds()
+
+	return result, nil
+}
+
+// confirmDestructive lets stmt run unless it is destructive on a production
+// connection and token doesn't confirm it. Tokens are single use.
+func (s *session) confirmDestructive(stmt, token string) error {
+	kind, reason := classifyStatement(stmt, hashComments(s.driver))
+	if kind == "" || !s.production() {
+		return nil
+	}
+	return s.confirm(stmt, token, kind, reason)
+}
+
+// confirmScript is confirmDestructive for a script, confirmed as a whole by
+// one token
+func (s *session) confirmScript(script string, statements []ScriptStatement, token string) error {
+	if !s.production() {
+		return nil
+	}
+	for _, stmt := range statements {
+		if kind, reason := classifyStatement(stmt.Text, hashComments(s.driver)); kind != "" {
+			return s.confirm(script, token, kind, fmt.Sprintf("line %d: %s", stmt.Line, reason))
+		}
+	}
+	return nil
+}
+
+// confirm consumes token if it was issued for stmt, and otherwise issues a
+// new one in a ConfirmationRequiredError
+func (s *session) confirm(stmt, token, kind, reason string) error {
+	s.mu.Lock()
+	pending, ok := s.confirmations[token]
+	if ok {
+		delete(s.confirmations, token)
+	}
+	s.mu.Unlock()
+
+	if ok && pending.statement == strings.TrimSpace(stmt) && time.Now().Before(pending.expires) {
+		return nil
+	}
+	return &ConfirmationRequiredError{Kind: kind, Reason: reason, Token: s.issueConfirmation(stmt)}
+}
+
+// issueConfirmation returns a new token confirming stmt
+func (s *session) issueConfirmation(stmt string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, pending := range s.confirmations {
+		if now.After(pending.expires) {
+			delete(s.confirmations, t)
+		}
+	}
+	s.confirmations[token] = pendingConfirmation{
+		statement: strings.TrimSpace(stmt),
+		expires:   now.Add(confirmationTTL),
+	}
+	return token
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassifyStatement(t *testing.T) {
+	tests := []struct {
+		stmt          string
+		mysqlComments bool
+		want          string
+	}{
+		{stmt: "UPDATE t SET a = 1", want: DestructiveUpdateAll},
+		{stmt: "UPDATE t SET a = 1 WHERE id = 2"},
+		{stmt: "update t set a = (select max(b) from u where u.id = 1)", want: DestructiveUpdateAll},
+		{stmt: "DELETE FROM t", want: DestructiveDeleteAll},
+		{stmt: "DELETE FROM t WHERE id = 1"},
+		{stmt: "DELETE FROM t -- WHERE id = 1", want: DestructiveDeleteAll},
+		{stmt: "DELETE FROM t # WHERE id = 1", mysqlComments: true, want: DestructiveDeleteAll},
+		// Postgres' JSON operators aren't comments
+		{stmt: "UPDATE t SET doc = doc #- '{a}' WHERE id = 1"},
+		{stmt: "UPDATE t SET doc = doc #>> '{a}' WHERE id = 1"},
+		{stmt: "WITH old AS (SELECT id FROM t) DELETE FROM t", want: DestructiveDeleteAll},
+		{stmt: "WITH old AS (SELECT id FROM t WHERE x) DELETE FROM t WHERE id IN (SELECT id FROM old)"},
+		{stmt: "WITH x AS (SELECT 1) SELECT * FROM x"},
+		{stmt: "drop table t", want: DestructiveDrop},
+		{stmt: "TRUNCATE t", want: DestructiveTruncate},
+		{stmt: "ALTER TABLE t DROP COLUMN a", want: DestructiveAlter},
+		{stmt: "INSERT INTO t VALUES (1)"},
+		{stmt: "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		if got, _ := classifyStatement(tt.stmt, tt.mysqlComments); got != tt.want {
+			t.Errorf("classifyStatement(%q, %v) = %q, want %q", tt.stmt, tt.mysqlComments, got, tt.want)
+		}
+	}
+}
+
+func TestProductionConfirmation(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	_, server := addFakeSession(t, m, "prod", ConnectionConfig{Type: "mysql", Production: true})
+	addFakeSession(t, m, "dev", ConnectionConfig{Type: "mysql"})
+
+	const stmt = "DELETE FROM t"
+	run := func(connID, query, token string) error {
+		_, err := m.ExecuteStatement(ctx, connID, "editor", "", query, token)
+		return err
+	}
+	tokenFrom := func(err error) string {
+		t.Helper()
+		var confirm *ConfirmationRequiredError
+		if !errors.As(err, &confirm) || confirm.Kind != DestructiveDeleteAll || confirm.Token == "" {
+			t.Fatalf("error = %v, want a confirmation request", err)
+		}
+		return confirm.Token
+	}
+
+	if err := run("dev", stmt, ""); err != nil {
+		t.Fatalf("development connection: %v", err)
+	}
+	if err := run("prod", "DELETE FROM t WHERE id = 1", ""); err != nil {
+		t.Fatalf("harmless statement: %v", err)
+	}
+
+	token := tokenFrom(run("prod", stmt, ""))
+	if err := run("prod", "DROP TABLE t", token); err == nil {
+		t.Fatal("a token confirmed another statement")
+	}
+	// The mismatch used it up
+	tokenFrom(run("prod", stmt, token))
+
+	check, err := m.CheckStatement("prod", stmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.Destructive || !check.RequiresConfirmation || check.ConfirmToken == "" {
+		t.Fatalf("CheckStatement = %+v", check)
+	}
+	if err := run("prod", "  "+stmt+"\n", check.ConfirmToken); err != nil {
+		t.Fatalf("confirmed statement: %v", err)
+	}
+	tokenFrom(run("prod", stmt, check.ConfirmToken))
+
+	// A script is confirmed as a whole
+	script := "INSERT INTO t VALUES (1);\nDELETE FROM t;"
+	_, err = m.ExecuteScript(ctx, "prod", "editor", "", script, ScriptOptions{})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("script error = %v, want line 2 named", err)
+	}
+	if _, err := m.ExecuteScript(ctx, "prod", "editor", "", script, ScriptOptions{ConfirmToken: tokenFrom(err)}); err != nil {
+		t.Fatalf("confirmed script: %v", err)
+	}
+
+	want := []string{"DELETE FROM t WHERE id = 1", "  " + stmt + "\n", "INSERT INTO t VALUES (1)", stmt}
+	if got := server.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements on production = %q, want %q", got, want)
+	}
+}
+
+func TestDryRunStatement(t *testing.T) {
+	ctx := context.Background()
+	m, s := openTestSQLite(t,
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT)",
+		"INSERT INTO t VALUES (1, 'x'), (2, 'y'), (3, 'z')",
+	)
+
+	result, err := m.DryRunStatement(ctx, "test", "DELETE FROM t WHERE id > 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 2 || result.Kind != "" {
+		t.Errorf("dry run = %+v, want 2 rows", result)
+	}
+	result, err = m.DryRunStatement(ctx, "test", "UPDATE t SET a = 'q'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 3 || result.Kind != DestructiveUpdateAll {
+		t.Errorf("dry run = %+v, want 3 rows and the update flagged", result)
+	}
+	// SQLite DDL is transactional, so it can be dry run too
+	if _, err := m.DryRunStatement(ctx, "test", "DROP TABLE t"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]interface{}{{int64(1), "x"}, {int64(2), "y"}, {int64(3), "z"}}
+	if got := tableContents(t, s, "SELECT * FROM t"); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows after dry runs = %v, want them unchanged", got)
+	}
+
+	if _, err := m.DryRunStatement(ctx, "test", "COMMIT"); err == nil {
+		t.Error("dry ran a transaction control statement")
+	}
+}
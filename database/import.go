@@ -0,0 +1,811 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Import sources
+const (
+	ImportDBeaver   = "dbeaver"   // DBeaver data-sources.json
+	ImportDataGrip  = "datagrip"  // DataGrip / JetBrains dataSources.xml
+	ImportTablePlus = "tableplus" // TablePlus Connections.plist
+	ImportPgpass    = "pgpass"    // ~/.pgpass
+	ImportMyCnf     = "mycnf"     // ~/.my.cnf
+	ImportURI       = "uri"       // Connection URIs, one per line or NAME=uri
+	ImportBundle    = "bundle"    // Bundle written by ExportConnections
+)
+
+// Import actions
+const (
+	ImportActionAdd    = "add"
+	ImportActionUpdate = "update"
+	ImportActionSkip   = "skip"
+
+	// The connection exists locally but differs in a way that needs a
+	// decision; applied only when listed in ImportRequest.Overwrite
+	ImportActionConflict = "conflict"
+)
+
+// ImportRequest describes connections to import
+type ImportRequest struct {
+	Source  string `json:"source"`
+	Path    string `json:"path,omitempty"`    // File to read; defaults to the source's usual location
+	Content string `json:"content,omitempty"` // Inline content used instead of Path, e.g. pasted URIs
+	Folder  string `json:"folder,omitempty"`  // Folder for new connections, below any folder the source has
+	Exclude []int  `json:"exclude,omitempty"` // Preview item indexes to leave out when applying
+
+	// Bundle imports only
+	Passphrase string `json:"passphrase,omitempty"` // Decrypts secrets in the bundle
+	Overwrite  []int  `json:"overwrite,omitempty"`  // Conflict item indexes to apply anyway
+}
+
+// ImportItem is one connection found by an importer and what importing it does
+type ImportItem struct {
+	Index       int             `json:"index"`
+	Action      string          `json:"action"`
+	Reason      string          `json:"reason,omitempty"`
+	ExistingID  string          `json:"existingId,omitempty"` // Connection updated or matched
+	HasPassword bool            `json:"hasPassword"`          // The import carries a password or key
+	Connection  SavedConnection `json:"connection"`           // Without secrets
+}
+
+// ImportPreview lists what an import would add, update or skip
+type ImportPreview struct {
+	Source   string       `json:"source"`
+	Path     string       `json:"path,omitempty"`
+	Items    []ImportItem `json:"items"`
+	Warnings []string     `json:"warnings,omitempty"`
+}
+
+// ImportResult summarizes an applied import
+type ImportResult struct {
+	Added    int      `json:"added"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// importCandidate is a connection parsed from a source, or the reason one
+// entry of the source can't be imported
+type importCandidate struct {
+	conn SavedConnection
+	skip string
+}
+
+// importParser turns the content of a source into candidates. path is the
+// file the content came from, if any, for formats that keep data in
+// neighbouring files.
+type importParser func(data []byte, path string) ([]importCandidate, []string, error)
+
+var importParsers = map[string]importParser{
+	ImportDBeaver:   parseDBeaverSources,
+	ImportDataGrip:  parseDataGripSources,
+	ImportTablePlus: parseTablePlusConnections,
+	ImportPgpass:    parsePgpass,
+	ImportMyCnf:     parseMyCnf,
+	ImportURI:       parseURIList,
+}
+
+// defaultImportPath returns where source usually keeps its connections
+func defaultImportPath(source string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	switch source {
+	case ImportPgpass:
+		if path := os.Getenv("PGPASSFILE"); path != "" {
+			return path, nil
+		}
+		if runtime.GOOS == "windows" {
+			return filepath.Join(os.Getenv("APPDATA"), "postgresql", "pgpass.conf"), nil
+		}
+		return filepath.Join(home, ".pgpass"), nil
+	case ImportMyCnf:
+		return filepath.Join(home, ".my.cnf"), nil
+	case ImportDBeaver:
+		workspace := filepath.Join("workspace6", "General", ".dbeaver", "data-sources.json")
+		switch runtime.GOOS {
+		case "darwin":
+			return filepath.Join(home, "Library", "DBeaverData", workspace), nil
+		case "windows":
+			return filepath.Join(os.Getenv("APPDATA"), "DBeaverData", workspace), nil
+		default:
+			return filepath.Join(home, ".local", "share", "DBeaverData", workspace), nil
+		}
+	case ImportTablePlus:
+		return filepath.Join(home, "Library", "Application Support", "com.tinyapp.TablePlus", "Data", "Connections.plist"), nil
+	default:
+		return "", fmt.Errorf("%s import needs a file path", source)
+	}
+}
+
+// readImport parses the source of req
+func readImport(req ImportRequest) ([]importCandidate, []string, string, error) {
+	parse, ok := importParsers[req.Source]
+	if req.Source == ImportBundle {
+		parse, ok = bundleParser(req.Passphrase), true
+	}
+	if !ok {
+		return nil, nil, "", fmt.Errorf("unknown import source: %s", req.Source)
+	}
+
+	if req.Content != "" {
+		candidates, warnings, err := parse([]byte(req.Content), "")
+		return candidates, warnings, "", err
+	}
+
+	path := req.Path
+	if path == "" {
+		var err error
+		if path, err = defaultImportPath(req.Source); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, path, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	candidates, warnings, err := parse(data, path)
+	if err != nil {
+		return nil, nil, path, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return candidates, warnings, path, nil
+}
+
+// planImport decides for each candidate whether it adds a connection,
+// updates the saved connection it matches, or is skipped. It returns the
+// items with secrets stripped and, in parallel, the full connections.
+func planImport(existing []SavedConnection, candidates []importCandidate, folder string) ([]ImportItem, []SavedConnection) {
+	items := make([]ImportItem, 0, len(candidates))
+	full := make([]SavedConnection, 0, len(candidates))
+	seen := make(map[string]int)
+
+	for i, candidate := range candidates {
+		conn := candidate.conn
+		conn.Folder = normalizeFolder(folder + "/" + conn.Folder)
+		if conn.Name == "" {
+			conn.Name = describeConnection(conn.Config)
+		}
+
+		item := ImportItem{Index: i}
+		switch {
+		case candidate.skip != "":
+			item.Action = ImportActionSkip
+			item.Reason = candidate.skip
+		default:
+			key := connectionIdentity(conn.Config)
+			if first, dup := seen[key]; dup {
+				item.Action = ImportActionSkip
+				item.Reason = fmt.Sprintf("same connection as item %d", first)
+				break
+			}
+			seen[key] = i
+
+			item.Action = ImportActionAdd
+			if conn.ID != "" {
+				planBundleItem(&item, existing, conn)
+				break
+			}
+			for _, e := range existing {
+				if connectionIdentity(e.Config) != key {
+					continue
+				}
+				item.ExistingID = e.ID
+				merged := mergeImportedConfig(e.Config, conn.Config)
+				changes := configChanges(e.Config, merged)
+				if len(changes) == 0 {
+					item.Action = ImportActionSkip
+					item.Reason = fmt.Sprintf("already saved as %q", e.Name)
+				} else {
+					item.Action = ImportActionUpdate
+					item.Reason = fmt.Sprintf("updates %q: %s", e.Name, strings.Join(changes, ", "))
+				}
+				break
+			}
+		}
+
+		full = append(full, conn)
+		stripped := conn
+		item.HasPassword = !extractSecrets(&stripped.Config).empty()
+		item.Connection = stripped
+		items = append(items, item)
+	}
+
+	return items, full
+}
+
+// planBundleItem plans a connection from a bundle, which carries its ID: a
+// connection with that ID is updated unless it now points elsewhere, and one
+// saved under another ID for the same server is a conflict
+func planBundleItem(item *ImportItem, existing []SavedConnection, conn SavedConnection) {
+	key := connectionIdentity(conn.Config)
+
+	for _, e := range existing {
+		if e.ID != conn.ID {
+			continue
+		}
+		item.ExistingID = e.ID
+		if connectionIdentity(e.Config) != key {
+			item.Action = ImportActionConflict
+			item.Reason = fmt.Sprintf("%q points to %s here but %s in the bundle",
+				e.Name, describeConnection(e.Config), describeConnection(conn.Config))
+			return
+		}
+
+		changes := metadataChanges(e, conn)
+		changes = append(changes, configChanges(e.Config, mergeBundleConfig(e.Config, conn.Config))...)
+		if len(changes) == 0 {
+			item.Action = ImportActionSkip
+			item.Reason = "up to date"
+		} else {
+			item.Action = ImportActionUpdate
+			item.Reason = fmt.Sprintf("updates %q: %s", e.Name, strings.Join(changes, ", "))
+		}
+		return
+	}
+
+	for _, e := range existing {
+		if connectionIdentity(e.Config) == key {
+			item.ExistingID = e.ID
+			item.Action = ImportActionConflict
+			item.Reason = fmt.Sprintf("the same server is already saved as %q", e.Name)
+			return
+		}
+	}
+}
+
+// metadataChanges names the descriptive fields that differ between a and b
+func metadataChanges(a, b SavedConnection) []string {
+	var changes []string
+	if a.Name != b.Name {
+		changes = append(changes, "name")
+	}
+	if a.Folder != b.Folder {
+		changes = append(changes, "folder")
+	}
+	if strings.Join(a.Tags, "\x00") != strings.Join(b.Tags, "\x00") {
+		changes = append(changes, "tags")
+	}
+	if a.Environment != b.Environment {
+		changes = append(changes, "environment")
+	}
+	if a.Notes != b.Notes {
+		changes = append(changes, "notes")
+	}
+	return changes
+}
+
+// connectionIdentity is what makes two configs the same connection for imports
+func connectionIdentity(c ConnectionConfig) string {
+	typ := c.Type
+	if typ == "" {
+		typ = "mysql"
+	}
+	if typ == "sqlite" {
+		path, err := expandHome(c.FilePath)
+		if err != nil {
+			path = c.FilePath
+		}
+		return typ + "|" + filepath.Clean(path)
+	}
+	return strings.Join([]string{typ, strings.ToLower(c.Host), strconv.Itoa(c.Port), c.User, c.Database,
+		strings.ToLower(c.SSHHost)}, "|")
+}
+
+// mergeImportedConfig applies an imported config over a saved one, keeping
+// saved secrets the import doesn't carry
+func mergeImportedConfig(saved, imported ConnectionConfig) ConnectionConfig {
+	merged := imported
+	merged.Color = saved.Color
+	merged.SSHConfigHost = saved.SSHConfigHost
+	merged.SSHJumpHosts = saved.SSHJumpHosts
+	if merged.Password == "" {
+		merged.Password = saved.Password
+	}
+	if merged.SSHPassword == "" {
+		merged.SSHPassword = saved.SSHPassword
+	}
+	if merged.SSHPrivateKey == "" {
+		merged.SSHPrivateKey = saved.SSHPrivateKey
+		merged.SSHPassphrase = saved.SSHPassphrase
+	}
+	if merged.SSLClientKey == "" {
+		merged.SSLClientKey = saved.SSLClientKey
+	}
+	if merged.ExtraParams == nil {
+		merged.ExtraParams = saved.ExtraParams
+	}
+	// Clients don't export pool or session settings; URIs carry a few
+	merged.MaxOpenConns, merged.MaxIdleConns = saved.MaxOpenConns, saved.MaxIdleConns
+	merged.ConnMaxLifetime, merged.StatementTimeout = saved.ConnMaxLifetime, saved.StatementTimeout
+	merged.TimeZone, merged.SearchPath, merged.InitSQL = saved.TimeZone, saved.SearchPath, saved.InitSQL
+	if merged.ConnectTimeout == 0 {
+		merged.ConnectTimeout = saved.ConnectTimeout
+	}
+	if merged.ApplicationName == "" {
+		merged.ApplicationName = saved.ApplicationName
+	}
+	if merged.Charset == "" {
+		merged.Charset, merged.Collation = saved.Charset, saved.Collation
+	}
+	if !merged.UseSSL && saved.UseSSL {
+		merged.UseSSL, merged.SSLMode = saved.UseSSL, saved.SSLMode
+		merged.SSLCACert, merged.SSLClientCert = saved.SSLCACert, saved.SSLClientCert
+	}
+	if !merged.UseSSHTunnel && saved.UseSSHTunnel {
+		merged.UseSSHTunnel = true
+		merged.SSHHost, merged.SSHPort, merged.SSHUser = saved.SSHHost, saved.SSHPort, saved.SSHUser
+		merged.SSHUseAgent = saved.SSHUseAgent
+	}
+	return merged
+}
+
+// mergeBundleConfig takes every setting from a bundle, filling secrets the
+// bundle left out from the saved config
+func mergeBundleConfig(saved, imported ConnectionConfig) ConnectionConfig {
+	values := map[string]string{}
+	have := extractSecrets(&saved)
+	have.each(func(name string, value *string) {
+		if *value != "" {
+			values[name] = *value
+		}
+	})
+
+	merged := imported
+	secrets := extractSecrets(&merged)
+	for len(secrets.JumpHosts) < len(merged.SSHJumpHosts) {
+		secrets.JumpHosts = append(secrets.JumpHosts, jumpHostSecrets{})
+	}
+	secrets.each(func(name string, value *string) {
+		if *value == "" {
+			*value = values[name]
+		}
+	})
+	secrets.apply(&merged)
+	return merged
+}
+
+// configChanges names the groups of settings that differ between a and b
+func configChanges(a, b ConnectionConfig) []string {
+	var changes []string
+	if a.Password != b.Password {
+		changes = append(changes, "password")
+	}
+	if a.FileReadOnly != b.FileReadOnly || a.ReadOnly != b.ReadOnly {
+		changes = append(changes, "read-only")
+	}
+	if a.UseSSL != b.UseSSL || a.SSLMode != b.SSLMode || a.SSLCACert != b.SSLCACert ||
+		a.SSLClientCert != b.SSLClientCert || a.SSLClientKey != b.SSLClientKey {
+		changes = append(changes, "SSL")
+	}
+	if a.UseSSHTunnel != b.UseSSHTunnel || a.SSHHost != b.SSHHost || a.SSHPort != b.SSHPort ||
+		a.SSHUser != b.SSHUser || a.SSHPassword != b.SSHPassword || a.SSHPrivateKey != b.SSHPrivateKey ||
+		a.SSHPassphrase != b.SSHPassphrase || a.SSHUseAgent != b.SSHUseAgent {
+		changes = append(changes, "SSH tunnel")
+	}
+	if a.MaxOpenConns != b.MaxOpenConns || a.MaxIdleConns != b.MaxIdleConns || a.ConnMaxLifetime != b.ConnMaxLifetime ||
+		a.ConnectTimeout != b.ConnectTimeout || a.StatementTimeout != b.StatementTimeout || a.TimeZone != b.TimeZone ||
+		a.Charset != b.Charset || a.Collation != b.Collation || a.ApplicationName != b.ApplicationName ||
+		a.SearchPath != b.SearchPath || !slices.Equal(a.InitSQL, b.InitSQL) {
+		changes = append(changes, "session settings")
+	}
+	if !maps.Equal(a.ExtraParams, b.ExtraParams) {
+		changes = append(changes, "parameters")
+	}
+	return changes
+}
+
+// describeConnection names a connection after its address, e.g. app@db1:5432/orders
+func describeConnection(c ConnectionConfig) string {
+	if c.Type == "sqlite" {
+		return filepath.Base(c.FilePath)
+	}
+	name := c.Host
+	if c.Port != 0 {
+		name += ":" + strconv.Itoa(c.Port)
+	}
+	if c.User != "" {
+		name = c.User + "@" + name
+	}
+	if c.Database != "" {
+		name += "/" + c.Database
+	}
+	return name
+}
+
+// PreviewImport reads an import source and reports what importing it would
+// do, without writing anything
+func (s *Storage) PreviewImport(req ImportRequest) (*ImportPreview, error) {
+	candidates, warnings, path, err := readImport(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var preview *ImportPreview
+	err = s.withFileLock(func() error {
+		existing, err := s.loadConnections()
+		if err != nil {
+			return err
+		}
+		items, _ := planImport(existing, candidates, req.Folder)
+		preview = &ImportPreview{Source: req.Source, Path: path, Items: items, Warnings: warnings}
+		return nil
+	})
+	return preview, err
+}
+
+// ApplyImport imports the connections PreviewImport lists, except those in
+// req.Exclude. The source is read and planned again, so the result matches
+// the preview as long as neither changed in between.
+func (s *Storage) ApplyImport(req ImportRequest) (*ImportResult, error) {
+	candidates, warnings, _, err := readImport(req)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[int]bool)
+	for _, i := range req.Exclude {
+		excluded[i] = true
+	}
+	overwrite := make(map[int]bool)
+	for _, i := range req.Overwrite {
+		overwrite[i] = true
+	}
+
+	result := &ImportResult{Warnings: warnings}
+	err = s.withFileLock(func() error {
+		existing, err := s.loadConnections()
+		if err != nil {
+			return err
+		}
+		items, full := planImport(existing, candidates, req.Folder)
+
+		connections, err := s.readConnections()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for i, item := range items {
+			action := item.Action
+			if action == ImportActionConflict && overwrite[item.Index] {
+				action = ImportActionUpdate
+			}
+			if excluded[item.Index] || (action != ImportActionAdd && action != ImportActionUpdate) {
+				result.Skipped++
+				continue
+			}
+
+			conn := full[i]
+			switch action {
+			case ImportActionAdd:
+				if err := normalizeConnection(&conn); err != nil {
+					return fmt.Errorf("%s: %w", conn.Name, err)
+				}
+				// Bundles keep their IDs so later imports merge into the same connections
+				if conn.ID == "" {
+					conn.ID = uuid.NewString()
+				}
+				conn.CreatedAt = now
+				conn.LastUsedAt = nil
+				conn.SortOrder = nextSortOrder(connections, conn.Folder)
+				if err := s.storeSecrets(conn.ID, &conn.Config); err != nil {
+					return fmt.Errorf("%s: %w", conn.Name, err)
+				}
+				connections = append(connections, conn)
+				result.Added++
+
+			case ImportActionUpdate:
+				index := findConnection(connections, item.ExistingID)
+				if index < 0 {
+					return fmt.Errorf("connection not found: %s", item.ExistingID)
+				}
+				var saved SavedConnection
+				for _, e := range existing {
+					if e.ID == item.ExistingID {
+						saved = e
+					}
+				}
+
+				updated := connections[index]
+				if conn.ID != "" {
+					// From a bundle: its settings and descriptions win
+					if err := normalizeConnection(&conn); err != nil {
+						return fmt.Errorf("%s: %w", conn.Name, err)
+					}
+					updated.Name, updated.Tags = conn.Name, conn.Tags
+					updated.Environment, updated.Notes = conn.Environment, conn.Notes
+					if conn.Folder != updated.Folder {
+						updated.Folder = conn.Folder
+						updated.SortOrder = nextSortOrder(connections, conn.Folder)
+					}
+					updated.Config = mergeBundleConfig(saved.Config, conn.Config)
+					updated.MissingSecrets = missingSecrets(updated.Config, conn.MissingSecrets)
+				} else {
+					updated.Config = mergeImportedConfig(saved.Config, conn.Config)
+				}
+
+				if err := s.storeSecrets(updated.ID, &updated.Config); err != nil {
+					return fmt.Errorf("%s: %w", updated.Name, err)
+				}
+				connections[index] = updated
+				result.Updated++
+			}
+		}
+
+		if result.Added == 0 && result.Updated == 0 {
+			return nil
+		}
+		return s.saveConnections(connections)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parsePgpass reads a PostgreSQL password file:
+// hostname:port:database:username:password, with \: and \\ escapes
+func parsePgpass(data []byte, _ string) ([]importCandidate, []string, error) {
+	var candidates []importCandidate
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := splitPgpassLine(text)
+		if len(fields) != 5 {
+			candidates = append(candidates, importCandidate{skip: fmt.Sprintf("line %d: expected 5 fields", line)})
+			continue
+		}
+		host, port, dbName, user, password := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+		config := ConnectionConfig{
+			Type:     "postgres",
+			Host:     host,
+			Port:     defaultPostgresPort,
+			User:     user,
+			Password: password,
+		}
+		if dbName != "*" {
+			config.Database = dbName
+		}
+		conn := SavedConnection{Config: config}
+
+		switch {
+		case host == "*" || user == "*":
+			candidates = append(candidates, importCandidate{conn: conn,
+				skip: fmt.Sprintf("line %d: wildcard host or user", line)})
+			continue
+		case port != "*":
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				candidates = append(candidates, importCandidate{conn: conn,
+					skip: fmt.Sprintf("line %d: invalid port %q", line, port)})
+				continue
+			}
+			conn.Config.Port = p
+		}
+
+		candidates = append(candidates, importCandidate{conn: conn})
+	}
+
+	return candidates, nil, scanner.Err()
+}
+
+// splitPgpassLine splits a .pgpass line on unescaped colons
+func splitPgpassLine(line string) []string {
+	var fields []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':' && len(fields) < 4:
+			fields = append(fields, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(fields, current.String())
+}
+
+// parseMyCnf reads a MySQL option file. [client] and [mysql] make up the
+// default connection; groups with a suffix, as used with
+// --defaults-group-suffix (e.g. [client_prod]), add one connection each on
+// top of those defaults.
+func parseMyCnf(data []byte, _ string) ([]importCandidate, []string, error) {
+	groups, order, warnings := parseOptionFile(data)
+
+	base := map[string]string{}
+	for _, name := range []string{"client", "mysql"} {
+		for k, v := range groups[name] {
+			base[k] = v
+		}
+	}
+
+	var candidates []importCandidate
+	if len(base) > 0 && (base["host"] != "" || base["user"] != "" || base["password"] != "" || base["database"] != "") {
+		candidates = append(candidates, myCnfCandidate("my.cnf", base, &warnings))
+	}
+
+	seen := map[string]bool{}
+	for _, name := range order {
+		var suffix string
+		switch {
+		case strings.HasPrefix(name, "client") && name != "client":
+			suffix = strings.TrimPrefix(name, "client")
+		case strings.HasPrefix(name, "mysql") && name != "mysql" && !strings.HasPrefix(name, "mysqld"):
+			suffix = strings.TrimPrefix(name, "mysql")
+		default:
+			continue
+		}
+		if seen[suffix] {
+			continue
+		}
+		seen[suffix] = true
+
+		options := map[string]string{}
+		for _, group := range []string{"client", "mysql", "client" + suffix, "mysql" + suffix} {
+			for k, v := range groups[group] {
+				options[k] = v
+			}
+		}
+		candidates = append(candidates, myCnfCandidate("my.cnf "+strings.TrimLeft(suffix, "_-"), options, &warnings))
+	}
+
+	return candidates, warnings, nil
+}
+
+// myCnfCandidate maps the options of one option group set to a connection
+func myCnfCandidate(name string, options map[string]string, warnings *[]string) importCandidate {
+	config := ConnectionConfig{
+		Type:     "mysql",
+		Host:     options["host"],
+		Port:     defaultMySQLPort,
+		User:     options["user"],
+		Password: options["password"],
+		Database: options["database"],
+	}
+	if config.Host == "" {
+		config.Host = "localhost"
+	}
+	if port, err := strconv.Atoi(options["port"]); err == nil {
+		config.Port = port
+	}
+	if options["socket"] != "" {
+		*warnings = append(*warnings, fmt.Sprintf("%s: Unix sockets are not supported; using TCP to %s", name, config.Host))
+	}
+
+	config.SSLCACert = options["ssl-ca"]
+	config.SSLClientCert = options["ssl-cert"]
+	config.SSLClientKey = options["ssl-key"]
+	switch strings.ToUpper(options["ssl-mode"]) {
+	case "REQUIRED":
+		config.UseSSL, config.SSLMode = true, "require"
+	case "PREFERRED":
+		config.UseSSL, config.SSLMode = true, "prefer"
+	case "VERIFY_CA":
+		config.UseSSL, config.SSLMode = true, "verify-ca"
+	case "VERIFY_IDENTITY":
+		config.UseSSL, config.SSLMode = true, "verify-full"
+	case "":
+		if config.SSLCACert != "" {
+			config.UseSSL, config.SSLMode = true, "verify-ca"
+		}
+	}
+
+	return importCandidate{conn: SavedConnection{Name: name, Config: config}}
+}
+
+// parseOptionFile reads an INI-style MySQL option file into groups of
+// options, with option names normalized to use dashes
+func parseOptionFile(data []byte) (map[string]map[string]string, []string, []string) {
+	groups := map[string]map[string]string{}
+	var order, warnings []string
+
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '!':
+			warnings = append(warnings, fmt.Sprintf("ignored directive: %s", line))
+			continue
+		case line[0] == '[' && strings.HasSuffix(line, "]"):
+			current = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			if groups[current] == nil {
+				groups[current] = map[string]string{}
+				order = append(order, current)
+			}
+			continue
+		}
+		if current == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+		groups[current][key] = optionValue(strings.TrimSpace(value))
+	}
+
+	return groups, order, warnings
+}
+
+// optionValue unquotes an option file value and drops a trailing comment
+func optionValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// parseURIList reads connection URIs, one per line. Lines may be written as
+// NAME=uri, as in .env files, to name the connection.
+func parseURIList(data []byte, _ string) ([]importCandidate, []string, error) {
+	var candidates []importCandidate
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name := ""
+		line = strings.TrimPrefix(line, "export ")
+		if key, value, ok := strings.Cut(line, "="); ok && !strings.Contains(key, ":") && !strings.Contains(key, "/") {
+			name = strings.TrimSpace(key)
+			line = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+
+		config, err := ParseConnectionURI(line)
+		if err != nil {
+			candidates = append(candidates, importCandidate{
+				conn: SavedConnection{Name: name},
+				skip: err.Error(),
+			})
+			continue
+		}
+		candidates = append(candidates, importCandidate{conn: SavedConnection{Name: name, Config: config}})
+	}
+
+	return candidates, nil, scanner.Err()
+}
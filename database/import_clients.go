@@ -0,0 +1,492 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dbeaverCredentialsKey is the fixed key DBeaver uses to obscure
+// credentials-config.json; it is public and offers no real protection
+const dbeaverCredentialsKey = "babb4a9f774ab853c96c2d653dfe544a"
+
+// dbeaverSources is the part of DBeaver's data-sources.json we read
+type dbeaverSources struct {
+	Folders map[string]struct {
+		Parent string `json:"parent"`
+	} `json:"folders"`
+	Connections map[string]struct {
+		Provider      string `json:"provider"`
+		Driver        string `json:"driver"`
+		Name          string `json:"name"`
+		Folder        string `json:"folder"`
+		ReadOnly      bool   `json:"read-only"`
+		Configuration struct {
+			Host     string      `json:"host"`
+			Port     json.Number `json:"port"`
+			Database string      `json:"database"`
+			URL      string      `json:"url"`
+			User     string      `json:"user"`
+			Type     string      `json:"type"` // Connection type: dev, test or prod
+			Handlers map[string]struct {
+				Enabled    bool                   `json:"enabled"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"handlers"`
+		} `json:"configuration"`
+	} `json:"connections"`
+}
+
+// dbeaverCredentials maps connection IDs to the credentials DBeaver saved
+type dbeaverCredentials map[string]map[string]struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// parseDBeaverSources reads DBeaver's data-sources.json. Saved passwords are
+// taken from credentials-config.json in the same directory when present.
+func parseDBeaverSources(data []byte, path string) ([]importCandidate, []string, error) {
+	var sources dbeaverSources
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	credentials := dbeaverCredentials{}
+	if path != "" {
+		creds, err := readDBeaverCredentials(filepath.Join(filepath.Dir(path), "credentials-config.json"))
+		switch {
+		case err == nil:
+			credentials = creds
+		case !os.IsNotExist(err):
+			warnings = append(warnings, fmt.Sprintf("saved DBeaver credentials could not be read: %v", err))
+		}
+	}
+
+	// Folders are stored by name with a parent reference
+	folderPath := func(name string) string {
+		var parts []string
+		for depth := 0; name != "" && depth < 32; depth++ {
+			parts = append([]string{name}, parts...)
+			name = sources.Folders[name].Parent
+		}
+		return strings.Join(parts, "/")
+	}
+
+	// Go maps are unordered; keep item indexes stable between preview and apply
+	ids := make([]string, 0, len(sources.Connections))
+	for id := range sources.Connections {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var candidates []importCandidate
+	for _, id := range ids {
+		ds := sources.Connections[id]
+		cfg := ds.Configuration
+		conn := SavedConnection{Name: ds.Name, Folder: folderPath(ds.Folder)}
+
+		switch cfg.Type {
+		case "dev":
+			conn.Environment = EnvironmentDevelopment
+		case "test":
+			conn.Environment = EnvironmentStaging
+		case "prod":
+			conn.Environment = EnvironmentProduction
+		}
+
+		config, err := jdbcConfig(ds.Provider, cfg.URL)
+		if err != nil {
+			candidates = append(candidates, importCandidate{conn: conn, skip: err.Error()})
+			continue
+		}
+		if cfg.Host != "" {
+			config.Host = cfg.Host
+		}
+		if port, err := strconv.Atoi(cfg.Port.String()); err == nil {
+			config.Port = port
+		}
+		if cfg.Database != "" {
+			if config.Type == "sqlite" {
+				config.FilePath = cfg.Database
+			} else {
+				config.Database = cfg.Database
+			}
+		}
+		if cfg.User != "" {
+			config.User = cfg.User
+		}
+		config.ReadOnly = ds.ReadOnly
+		config.FileReadOnly = ds.ReadOnly && config.Type == "sqlite"
+
+		if creds, ok := credentials[id]["#connection"]; ok {
+			if creds.User != "" {
+				config.User = creds.User
+			}
+			config.Password = creds.Password
+		}
+
+		if ssh, ok := cfg.Handlers["ssh_tunnel"]; ok && ssh.Enabled {
+			props := ssh.Properties
+			config.UseSSHTunnel = true
+			config.SSHHost = propString(props, "host")
+			config.SSHPort, _ = strconv.Atoi(propString(props, "port"))
+			config.SSHUser = propString(props, "user")
+			switch propString(props, "authType") {
+			case "PUBLIC_KEY":
+				config.SSHPrivateKey = propString(props, "keyPath")
+			case "AGENT":
+				config.SSHUseAgent = true
+			}
+			if creds, ok := credentials[id]["network/ssh_tunnel"]; ok {
+				if creds.User != "" {
+					config.SSHUser = creds.User
+				}
+				if config.SSHPrivateKey != "" {
+					config.SSHPassphrase = creds.Password
+				} else {
+					config.SSHPassword = creds.Password
+				}
+			}
+		}
+
+		conn.Config = config
+		candidates = append(candidates, importCandidate{conn: conn})
+	}
+
+	return candidates, warnings, nil
+}
+
+// propString returns a DBeaver handler property as a string
+func propString(props map[string]interface{}, key string) string {
+	switch v := props[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// readDBeaverCredentials decrypts credentials-config.json: AES-128-CBC under
+// DBeaver's fixed key, with the IV in the first block
+func readDBeaverCredentials(path string) (dbeaverCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, _ := hex.DecodeString(dbeaverCredentialsKey)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("unexpected file size")
+	}
+
+	iv, data := data[:aes.BlockSize], data[aes.BlockSize:]
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	plain = plain[:len(plain)-pad]
+
+	var creds dbeaverCredentials
+	if err := json.Unmarshal(plain, &creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// jdbcConfig maps a JDBC driver or provider name and URL to a config
+func jdbcConfig(driver, url string) (ConnectionConfig, error) {
+	// Names vary by client and version: mysql8, mysql.8, postgres-jdbc, sqlite.xerial...
+	var typ string
+	switch driver = strings.ToLower(driver); {
+	case strings.HasPrefix(driver, "mysql"), strings.HasPrefix(driver, "mariadb"):
+		typ = "mysql"
+	case strings.HasPrefix(driver, "postgres"):
+		typ = "postgres"
+	case strings.HasPrefix(driver, "sqlite"):
+		typ = "sqlite"
+	}
+
+	if url != "" {
+		config, err := ParseConnectionURI(url)
+		if err == nil {
+			return config, nil
+		}
+		if typ == "" {
+			return config, err
+		}
+	}
+	if typ == "" {
+		return ConnectionConfig{}, fmt.Errorf("unsupported driver: %s", driver)
+	}
+
+	config := ConnectionConfig{Type: typ}
+	switch typ {
+	case "mysql":
+		config.Port = defaultMySQLPort
+	case "postgres":
+		config.Port = defaultPostgresPort
+	}
+	return config, nil
+}
+
+// dataGripSources is the part of a JetBrains dataSources.xml or
+// dataSources.local.xml we read
+type dataGripSources struct {
+	Components []struct {
+		DataSources []struct {
+			Name      string `xml:"name,attr"`
+			UUID      string `xml:"uuid,attr"`
+			Group     string `xml:"group,attr"`
+			ReadOnly  bool   `xml:"read-only,attr"`
+			DriverRef string `xml:"driver-ref"`
+			JDBCURL   string `xml:"jdbc-url"`
+			UserName  string `xml:"user-name"`
+			SSH       struct {
+				Enabled bool `xml:"enabled"`
+			} `xml:"ssh-properties"`
+		} `xml:"data-source"`
+	} `xml:"component"`
+}
+
+// parseDataGripSources reads a JetBrains dataSources.xml. User names live in
+// dataSources.local.xml next to it; passwords stay in the IDE's keychain.
+func parseDataGripSources(data []byte, path string) ([]importCandidate, []string, error) {
+	var sources dataGripSources
+	if err := xml.Unmarshal(data, &sources); err != nil {
+		return nil, nil, err
+	}
+
+	users := map[string]string{}
+	sshEnabled := map[string]bool{}
+	if path != "" {
+		localPath := strings.TrimSuffix(path, ".xml") + ".local.xml"
+		if local, err := os.ReadFile(localPath); err == nil {
+			var localSources dataGripSources
+			if err := xml.Unmarshal(local, &localSources); err == nil {
+				for _, component := range localSources.Components {
+					for _, ds := range component.DataSources {
+						users[ds.UUID] = ds.UserName
+						sshEnabled[ds.UUID] = ds.SSH.Enabled
+					}
+				}
+			}
+		}
+	}
+
+	warnings := []string{"DataGrip keeps passwords in the IDE's credential store; enter them after importing"}
+	var candidates []importCandidate
+	for _, component := range sources.Components {
+		for _, ds := range component.DataSources {
+			conn := SavedConnection{Name: ds.Name, Folder: ds.Group}
+
+			config, err := jdbcConfig(ds.DriverRef, ds.JDBCURL)
+			if err != nil {
+				candidates = append(candidates, importCandidate{conn: conn, skip: err.Error()})
+				continue
+			}
+			if ds.UserName != "" {
+				config.User = ds.UserName
+			}
+			if user := users[ds.UUID]; user != "" {
+				config.User = user
+			}
+			config.ReadOnly = ds.ReadOnly
+			config.FileReadOnly = ds.ReadOnly && config.Type == "sqlite"
+			if ds.SSH.Enabled || sshEnabled[ds.UUID] {
+				warnings = append(warnings, fmt.Sprintf("%s: SSH tunnel settings are not imported", ds.Name))
+			}
+
+			conn.Config = config
+			candidates = append(candidates, importCandidate{conn: conn})
+		}
+	}
+
+	return candidates, warnings, nil
+}
+
+// parseTablePlusConnections reads TablePlus's Connections.plist. Passwords
+// are kept in the macOS keychain and not included. Encrypted
+// .tableplusconnection exports can't be read; export the plist instead.
+func parseTablePlusConnections(data []byte, _ string) ([]importCandidate, []string, error) {
+	value, err := decodePlist(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, ok := value.([]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("expected an array of connections")
+	}
+
+	warnings := []string{"TablePlus keeps passwords in the keychain; enter them after importing"}
+	var candidates []importCandidate
+	for _, entry := range entries {
+		tp, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		get := func(key string) string { return plistString(tp[key]) }
+
+		conn := SavedConnection{Name: get("ConnectionName")}
+		switch strings.ToLower(get("Enviroment")) { // Sic; TablePlus's spelling
+		case "local", "development":
+			conn.Environment = EnvironmentDevelopment
+		case "testing", "staging":
+			conn.Environment = EnvironmentStaging
+		case "production":
+			conn.Environment = EnvironmentProduction
+		}
+
+		config, err := jdbcConfig(get("Driver"), "")
+		if err != nil {
+			candidates = append(candidates, importCandidate{conn: conn, skip: err.Error()})
+			continue
+		}
+		if config.Type == "sqlite" {
+			config.FilePath = get("DatabasePath")
+		} else {
+			config.Host = get("DatabaseHost")
+			if port, err := strconv.Atoi(get("DatabasePort")); err == nil {
+				config.Port = port
+			}
+			config.User = get("DatabaseUser")
+			config.Database = get("DatabaseName")
+		}
+
+		if over := get("isOverSSH"); over == "true" || over == "1" {
+			config.UseSSHTunnel = true
+			config.SSHHost = get("ServerAddress")
+			config.SSHPort, _ = strconv.Atoi(get("ServerPort"))
+			config.SSHUser = get("ServerUser")
+			if key := get("ServerPrivateKeyName"); key != "" {
+				config.SSHPrivateKey = filepath.Join("~", ".ssh", key)
+			}
+		}
+
+		conn.Config = config
+		candidates = append(candidates, importCandidate{conn: conn})
+	}
+
+	return candidates, warnings, nil
+}
+
+// plistString renders a decoded plist scalar as a string
+func plistString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// decodePlist decodes an XML property list into dicts, arrays and scalars
+func decodePlist(data []byte) (interface{}, error) {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		return nil, fmt.Errorf("binary property lists are not supported; convert with: plutil -convert xml1 <file>")
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("empty property list")
+			}
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodePlistValue(dec, start)
+		}
+	}
+}
+
+// decodePlistValue decodes the element opened by start
+func decodePlistValue(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]interface{}{}
+		key := ""
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := dec.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := decodePlistValue(dec, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var array []interface{}
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(dec, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := dec.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	default:
+		var text string
+		if err := dec.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+		switch start.Name.Local {
+		case "integer":
+			return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		case "real":
+			return strconv.ParseFloat(strings.TrimSpace(text), 64)
+		}
+		return text, nil
+	}
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeDBeaverCredentials encrypts creds the way DBeaver stores
+// credentials-config.json
+func writeDBeaverCredentials(t *testing.T, path string, creds string) {
+	t.Helper()
+	key, _ := hex.DecodeString(dbeaverCredentialsKey)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pad := aes.BlockSize - len(creds)%aes.BlockSize
+	plain := append([]byte(creds), bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	iv := make([]byte, aes.BlockSize)
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+	if err := os.WriteFile(path, append(iv, out...), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseDBeaverSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data-sources.json")
+	sources := `{
+		"folders": {"Prod": {}, "EU": {"parent": "Prod"}},
+		"connections": {
+			"pg-1": {
+				"provider": "postgresql", "name": "orders", "folder": "EU", "read-only": true,
+				"configuration": {
+					"host": "db1", "port": "5433", "database": "orders", "url": "jdbc:postgresql://db1:5433/orders", "type": "prod",
+					"handlers": {"ssh_tunnel": {"enabled": true, "properties": {"host": "bastion", "port": 22, "authType": "PUBLIC_KEY", "keyPath": "~/.ssh/id_ed25519"}}}
+				}
+			},
+			"mysql-1": {
+				"provider": "mysql", "name": "shop",
+				"configuration": {"host": "db2", "port": "3306", "database": "shop", "type": "dev"}
+			},
+			"oracle-1": {"provider": "oracle", "name": "legacy", "configuration": {}}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(sources), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeDBeaverCredentials(t, filepath.Join(dir, "credentials-config.json"), `{
+		"pg-1": {"#connection": {"user": "app", "password": "pw"}, "network/ssh_tunnel": {"user": "tunnel", "password": "keypass"}},
+		"mysql-1": {"#connection": {"user": "root", "password": "rootpw"}}
+	}`)
+
+	candidates, warnings, err := parseDBeaverSources([]byte(sources), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %q", warnings)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("got %d candidates, want 3", len(candidates))
+	}
+
+	shop, legacy, orders := candidates[0], candidates[1], candidates[2]
+	wantShop := SavedConnection{Name: "shop", Environment: EnvironmentDevelopment, Config: ConnectionConfig{
+		Type: "mysql", Host: "db2", Port: 3306, Database: "shop", User: "root", Password: "rootpw",
+	}}
+	if !reflect.DeepEqual(shop.conn, wantShop) {
+		t.Errorf("shop = %+v, want %+v", shop.conn, wantShop)
+	}
+	if legacy.skip == "" {
+		t.Error("unsupported driver not skipped")
+	}
+
+	c := orders.conn
+	if c.Folder != "Prod/EU" || c.Environment != EnvironmentProduction {
+		t.Errorf("orders folder %q, environment %q", c.Folder, c.Environment)
+	}
+	if c.Config.Type != "postgres" || c.Config.Host != "db1" || c.Config.Port != 5433 || c.Config.User != "app" ||
+		c.Config.Password != "pw" || !c.Config.ReadOnly {
+		t.Errorf("orders config = %+v", c.Config)
+	}
+	if !c.Config.UseSSHTunnel || c.Config.SSHHost != "bastion" || c.Config.SSHPort != 22 || c.Config.SSHUser != "tunnel" ||
+		c.Config.SSHPrivateKey != "~/.ssh/id_ed25519" || c.Config.SSHPassphrase != "keypass" || c.Config.SSHPassword != "" {
+		t.Errorf("orders SSH settings = %+v", c.Config)
+	}
+
+	// Without the credentials file the connections still import
+	candidates, _, err = parseDBeaverSources([]byte(sources), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if candidates[0].conn.Config.Password != "" || candidates[0].conn.Config.User != "" {
+		t.Errorf("credentials found without a credentials file: %+v", candidates[0].conn.Config)
+	}
+}
+
+func TestParseDataGripSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dataSources.xml")
+	sources := `<?xml version="1.0" encoding="UTF-8"?>
+<project version="4">
+  <component name="DataSourceManagerImpl">
+    <data-source source="LOCAL" name="orders" uuid="a1" group="Prod" read-only="true">
+      <driver-ref>postgresql</driver-ref>
+      <jdbc-url>jdbc:postgresql://db1:5432/orders</jdbc-url>
+    </data-source>
+    <data-source source="LOCAL" name="local" uuid="b2">
+      <driver-ref>sqlite.xerial</driver-ref>
+      <jdbc-url>jdbc:sqlite:/data/app.db</jdbc-url>
+    </data-source>
+  </component>
+</project>`
+	local := `<?xml version="1.0" encoding="UTF-8"?>
+<project version="4">
+  <component name="dataSourceStorageLocal">
+    <data-source name="orders" uuid="a1">
+      <user-name>app</user-name>
+      <ssh-properties><enabled>true</enabled></ssh-properties>
+    </data-source>
+  </component>
+</project>`
+	if err := os.WriteFile(filepath.Join(dir, "dataSources.local.xml"), []byte(local), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, warnings, err := parseDataGripSources([]byte(sources), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("got %d candidates, want 2", len(candidates))
+	}
+	// The password notice and the SSH settings left behind
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %q", warnings)
+	}
+
+	orders := candidates[0].conn
+	if orders.Name != "orders" || orders.Folder != "Prod" || orders.Config.Type != "postgres" || orders.Config.Host != "db1" ||
+		orders.Config.Database != "orders" || orders.Config.User != "app" || !orders.Config.ReadOnly {
+		t.Errorf("orders = %+v", orders)
+	}
+	file := candidates[1].conn.Config
+	if file.Type != "sqlite" || file.FilePath != "/data/app.db" {
+		t.Errorf("sqlite config = %+v", file)
+	}
+}
+
+func TestParseTablePlusConnections(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<array>
+	<dict>
+		<key>ConnectionName</key><string>shop</string>
+		<key>Driver</key><string>MySQL</string>
+		<key>Enviroment</key><string>production</string>
+		<key>DatabaseHost</key><string>db2</string>
+		<key>DatabasePort</key><string>3307</string>
+		<key>DatabaseUser</key><string>root</string>
+		<key>DatabaseName</key><string>shop</string>
+		<key>isOverSSH</key><true/>
+		<key>ServerAddress</key><string>bastion</string>
+		<key>ServerPort</key><integer>2222</integer>
+		<key>ServerUser</key><string>tunnel</string>
+		<key>ServerPrivateKeyName</key><string>id_rsa</string>
+	</dict>
+	<dict>
+		<key>ConnectionName</key><string>notes</string>
+		<key>Driver</key><string>SQLite</string>
+		<key>DatabasePath</key><string>/data/notes.db</string>
+	</dict>
+	<dict>
+		<key>ConnectionName</key><string>cache</string>
+		<key>Driver</key><string>Redis</string>
+	</dict>
+</array>
+</plist>`
+
+	candidates, _, err := parseTablePlusConnections([]byte(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("got %d candidates, want 3", len(candidates))
+	}
+
+	wantShop := SavedConnection{Name: "shop", Environment: EnvironmentProduction, Config: ConnectionConfig{
+		Type: "mysql", Host: "db2", Port: 3307, User: "root", Database: "shop",
+		UseSSHTunnel: true, SSHHost: "bastion", SSHPort: 2222, SSHUser: "tunnel", SSHPrivateKey: filepath.Join("~", ".ssh", "id_rsa"),
+	}}
+	if !reflect.DeepEqual(candidates[0].conn, wantShop) {
+		t.Errorf("shop = %+v, want %+v", candidates[0].conn, wantShop)
+	}
+	if notes := candidates[1].conn.Config; notes.Type != "sqlite" || notes.FilePath != "/data/notes.db" {
+		t.Errorf("notes = %+v", notes)
+	}
+	if candidates[2].skip == "" {
+		t.Error("unsupported driver not skipped")
+	}
+
+	if _, _, err := parseTablePlusConnections([]byte("bplist00..."), ""); err == nil {
+		t.Error("binary plist accepted")
+	}
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// summarizeCandidates renders candidates as name, address and password, or
+// the reason they are skipped
+func summarizeCandidates(candidates []importCandidate) []string {
+	var got []string
+	for _, c := range candidates {
+		if c.skip != "" {
+			got = append(got, "skip: "+c.skip)
+			continue
+		}
+		got = append(got, c.conn.Name+" "+c.conn.Config.Type+" "+describeConnection(c.conn.Config)+" "+c.conn.Config.Password)
+	}
+	return got
+}
+
+func TestParsePgpass(t *testing.T) {
+	data := `# comment
+db1:5432:orders:app:secret
+db2:*:*:admin:pa\:ss\\word
+*:5432:*:app:x
+db3:port:orders:app:x
+db4:5432:orders
+`
+	candidates, _, err := parsePgpass([]byte(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		" postgres app@db1:5432/orders secret",
+		` postgres admin@db2:5432 pa:ss\word`,
+		"skip: line 4: wildcard host or user",
+		`skip: line 5: invalid port "port"`,
+		"skip: line 6: expected 5 fields",
+	}
+	if got := summarizeCandidates(candidates); !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestParseMyCnf(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		want         []string
+		wantSSLMode  string // Of the first candidate
+		wantWarnings int
+	}{
+		{
+			name: "client group",
+			data: "[client]\nuser = app\npassword = \"se#cret\"\nport=3307\n",
+			want: []string{"my.cnf mysql app@localhost:3307 se#cret"},
+		},
+		{
+			name: "suffixed groups inherit the defaults",
+			data: "[client]\nuser=app\npassword=pw\n\n[mysqld]\nport=1\n\n[client_prod]\nhost=db.prod # primary\ndatabase=shop\n",
+			want: []string{"my.cnf mysql app@localhost:3306 pw", "my.cnf prod mysql app@db.prod:3306/shop pw"},
+		},
+		{
+			name:        "SSL mode",
+			data:        "[mysql]\nhost=db\nssl_mode=VERIFY_IDENTITY\n",
+			want:        []string{"my.cnf mysql db:3306 "},
+			wantSSLMode: "verify-full",
+		},
+		{
+			name:        "SSL preferred",
+			data:        "[client]\nhost=db\nssl-mode=preferred\n",
+			want:        []string{"my.cnf mysql db:3306 "},
+			wantSSLMode: "prefer",
+		},
+		{
+			name:        "CA without a mode",
+			data:        "[client]\nhost=db\nssl-ca=/etc/ca.pem\n",
+			want:        []string{"my.cnf mysql db:3306 "},
+			wantSSLMode: "verify-ca",
+		},
+		{
+			name:         "socket and includes",
+			data:         "!includedir /etc/mysql\n[client]\nuser=app\nsocket=/tmp/mysql.sock\n",
+			want:         []string{"my.cnf mysql app@localhost:3306 "},
+			wantWarnings: 2,
+		},
+		{
+			name: "server settings only",
+			data: "[mysqld]\nport=3306\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, warnings, err := parseMyCnf([]byte(tt.data), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarizeCandidates(candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidates\n got %q\nwant %q", got, tt.want)
+			}
+			if len(candidates) > 0 && candidates[0].conn.Config.SSLMode != tt.wantSSLMode {
+				t.Errorf("SSL mode = %q, want %q", candidates[0].conn.Config.SSLMode, tt.wantSSLMode)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %q, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseURIList(t *testing.T) {
+	data := `
+# from .env
+DATABASE_URL=postgres://app:pw@db1:5432/orders
+export CACHE_DB="mysql://root@db2/cache"
+postgresql://db3/reports
+REDIS_URL=redis://cache:6379
+`
+	candidates, _, err := parseURIList([]byte(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := summarizeCandidates(candidates)
+	want := []string{
+		"DATABASE_URL postgres app@db1:5432/orders pw",
+		"CACHE_DB mysql root@db2:3306/cache ",
+		" postgres db3:5432/reports ",
+	}
+	if len(got) != 4 || !reflect.DeepEqual(got[:3], want) || !strings.HasPrefix(got[3], "skip: ") {
+		t.Errorf("candidates\n got %q\nwant %q and a skipped redis URI", got, want)
+	}
+}
+
+func TestImportPreviewAndApply(t *testing.T) {
+	s, secrets := newTestStorage(t)
+
+	existing, err := s.SaveConnection(SavedConnection{Name: "orders", Config: ConnectionConfig{
+		Type: "postgres", Host: "db1", Port: 5432, User: "app", Database: "orders", Password: "old", Color: "#f00",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SaveConnection(SavedConnection{Name: "reports", Config: ConnectionConfig{
+		Type: "postgres", Host: "db3", Port: 5432, User: "app", Password: "pw",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := ImportRequest{
+		Source:  ImportPgpass,
+		Folder:  "Imported",
+		Content: "db1:5432:orders:app:new\ndb2:5432:*:app:pw2\ndb2:5432:*:app:pw3\ndb3:5432:*:app:pw\ndb4:5432:*:app:pw4\n",
+	}
+	preview, err := s.PreviewImport(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actions []string
+	for _, item := range preview.Items {
+		actions = append(actions, item.Action)
+		if item.Connection.Config.Password != "" || !item.HasPassword {
+			t.Errorf("item %d: password %q, has password %v", item.Index, item.Connection.Config.Password, item.HasPassword)
+		}
+	}
+	wantActions := []string{ImportActionUpdate, ImportActionAdd, ImportActionSkip, ImportActionSkip, ImportActionAdd}
+	if !reflect.DeepEqual(actions, wantActions) {
+		t.Fatalf("actions = %v, want %v", actions, wantActions)
+	}
+	if preview.Items[0].ExistingID != existing.ID || !strings.Contains(preview.Items[0].Reason, "password") {
+		t.Errorf("update item = %+v", preview.Items[0])
+	}
+
+	// Previewing writes nothing
+	if connections, err := s.LoadConnections(); err != nil || len(connections) != 2 {
+		t.Fatalf("after preview: %d connections, %v", len(connections), err)
+	}
+
+	req.Exclude = []int{4}
+	result, err := s.ApplyImport(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.Updated != 1 || result.Skipped != 3 {
+		t.Errorf("result = %+v, want 1 added, 1 updated, 3 skipped", result)
+	}
+
+	connections, err := s.LoadConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byHost := map[string]SavedConnection{}
+	for _, c := range connections {
+		byHost[c.Config.Host] = c
+	}
+	if len(connections) != 3 || byHost["db4"].ID != "" {
+		t.Fatalf("connections after import: %+v", connections)
+	}
+	if updated := byHost["db1"]; updated.Config.Password != "new" || updated.Config.Color != "#f00" || updated.Folder != "" {
+		t.Errorf("updated connection = %+v, want the new password and its own color and folder", updated)
+	}
+	if added := byHost["db2"]; added.Config.Password != "pw2" || added.Folder != "Imported" {
+		t.Errorf("added connection = %+v", added)
+	}
+	if _, ok := secrets[secretKey(byHost["db2"].ID)]; !ok {
+		t.Error("imported password not kept in the secret store")
+	}
+}
@@ -1,7 +1,10 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 )
 
 // TableAlteration represents a change to a table schema
@@ -12,24 +15,84 @@ type TableAlteration struct {
 	RenameTo      string       `json:"renameTo"`
 }
 
-// AlterTable performs schema modifications on a table
-func (m *Manager) AlterTable(database, table string, alteration TableAlteration) error {
-	db := m.getDB()
-	if db == nil {
-		return fmt.Errorf("not connected to database")
+// AlterTable performs schema modifications on a table. On a production
+// connection it needs the confirmToken from a previous
+// ConfirmationRequiredError.
+func (m *Manager) AlterTable(ctx context.Context, connID, database, schema, table string, alteration TableAlteration, confirmToken string) error {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkWritable("alter table"); err != nil {
+		return err
+	}
+
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return err
 	}
 
-	queries, err := m.driver.BuildAlterTableQuery(database, table, alteration)
+	current, err := m.GetTableInfo(ctx, connID, database, schema, table)
 	if err != nil {
 		return err
 	}
 
+	if r, ok := s.driver.(rebuildReader); ok {
+		if err := r.readRebuildDefinitions(ctx, s.db, namespace, table, current); err != nil {
+			return err
+		}
+	}
+
+	queries, cleanup, err := s.driver.BuildAlterTableQuery(namespace, table, current, alteration)
+	if err != nil {
+		return err
+	}
+	script, statements := alterationScript(queries)
+	if err := s.confirmScript(script, statements, confirmToken); err != nil {
+		return err
+	}
+
+	// Run every statement on one connection; some dialects need per-connection
+	// settings or an explicit transaction to span the whole alteration.
+	ctx, q, release, err := m.trackScript(ctx, s, "", "")
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer func() {
+		// Restore connection settings even when a statement failed or ctx ended
+		for _, query := range cleanup {
+			q.ExecContext(context.WithoutCancel(ctx), query)
+		}
+	}()
+
 	for _, query := range queries {
-		_, err := db.Exec(query)
+		_, err := q.ExecContext(ctx, query)
 		if err != nil {
+			// Don't hand a connection with a half-applied transaction back to the pool
+			q.ExecContext(ctx, "ROLLBACK")
 			return fmt.Errorf("failed to execute alter query [%s]: %w", query, err)
 		}
 	}
 
 	return nil
 }
+
+// rebuildReader is implemented by drivers that emulate ALTER TABLE by
+// rebuilding the table, and need more of its definition than columns and
+// indexes to carry over
+type rebuildReader interface {
+	readRebuildDefinitions(ctx context.Context, db *sql.DB, namespace, table string, details *TableDetails) error
+}
+
+// alterationScript joins the statements of an alteration into the script a
+// confirmation token is bound to
+func alterationScript(queries []string) (string, []ScriptStatement) {
+	statements := make([]ScriptStatement, len(queries))
+	line := 1
+	for i, query := range queries {
+		statements[i] = ScriptStatement{Text: query, Line: line}
+		line += strings.Count(query, "\n") + 1
+	}
+	return strings.Join(queries, ";\n"), statements
+}
@@ -1,30 +1,45 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 type MySQLDriver struct{}
 
-func (d *MySQLDriver) Connect(config ConnectionConfig) (*sql.DB, error) {
+func (d *MySQLDriver) Connect(ctx context.Context, config ConnectionConfig) (*sql.DB, error) {
 	// Build DSN with SSL support
-	dsn := d.buildDSN(config)
+	dsn, err := d.buildDSN(config)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := sql.Open("mysql", dsn)
+	cfg, err := mysql.ParseDSN(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open connection: %w", err)
 	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+
+	initSQL := config.InitSQL
+	if config.ReadOnly {
+		initSQL = append([]string{"SET SESSION TRANSACTION READ ONLY"}, initSQL...)
+	}
 
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(time.Minute * 5)
+	db := sql.OpenDB(withInitSQL(connector, initSQL))
+	applyPoolSettings(db, config)
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
@@ -33,30 +48,84 @@ func (d *MySQLDriver) Connect(config ConnectionConfig) (*sql.DB, error) {
 }
 
 // buildDSN constructs the MySQL DSN with SSL support
-func (d *MySQLDriver) buildDSN(config ConnectionConfig) string {
+func (d *MySQLDriver) buildDSN(config ConnectionConfig) (string, error) {
 	params := []string{"parseTime=true"}
 
 	// SSL/TLS configuration
-	if config.UseSSL {
-		switch config.SSLMode {
-		case "require":
-			params = append(params, "tls=skip-verify")
-		case "verify-ca", "verify-full":
-			params = append(params, "tls=true")
-		default:
-			// disable or empty - no SSL
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return "", err
+	}
+	if tlsConfig != nil {
+		name := mysqlTLSConfigName(config)
+		if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+			return "", fmt.Errorf("failed to register TLS config: %w", err)
+		}
+		params = append(params, "tls="+name)
+		if sslOptional(config) {
+			// The driver has no plaintext-first mode, so allow behaves as prefer
+			params = append(params, "allowFallbackToPlaintext=true")
 		}
 	}
+	// Keys the session settings own can't be overridden by extra parameters
+	reserved := []string{"parseTime", "tls", "allowFallbackToPlaintext", "timeout", "readTimeout", "charset", "collation", "time_zone", "max_execution_time"}
+	for _, key := range extraParamKeys(config, reserved...) {
+		params = append(params, key+"="+url.QueryEscape(config.ExtraParams[key]))
+	}
+	params = append(params, mysqlSessionParams(config)...)
 
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s",
 		config.User, config.Password, config.Host, config.Port, config.Database,
 		strings.Join(params, "&"))
 
-	return dsn
+	return dsn, nil
 }
 
-func (d *MySQLDriver) GetDatabases(db *sql.DB) ([]string, error) {
-	rows, err := db.Query("SHOW DATABASES")
+// mysqlSessionParams returns the DSN parameters for the config's session
+// settings. The driver applies them to every connection it opens, sending
+// unknown names such as time_zone as SET statements.
+func mysqlSessionParams(config ConnectionConfig) []string {
+	var params []string
+	if config.ConnectTimeout > 0 {
+		params = append(params, fmt.Sprintf("timeout=%ds", config.ConnectTimeout))
+	}
+	if config.Charset != "" {
+		params = append(params, "charset="+url.QueryEscape(config.Charset))
+	}
+	if config.Collation != "" {
+		params = append(params, "collation="+url.QueryEscape(config.Collation))
+	}
+	if config.TimeZone != "" {
+		params = append(params, "time_zone="+url.QueryEscape(mysqlQuote(config.TimeZone)))
+	}
+	if config.StatementTimeout > 0 {
+		// Milliseconds; MySQL only enforces it for SELECT
+		params = append(params, fmt.Sprintf("max_execution_time=%d", config.StatementTimeout*1000))
+	}
+	return params
+}
+
+// mysqlQuote quotes a string literal for a SET statement
+func mysqlQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// mysqlTLSConfigName derives the registry key for a config's TLS settings.
+// The driver keeps registered configs for the life of the process, so equal
+// settings share one entry instead of adding a new one per connection.
+func mysqlTLSConfigName(config ConnectionConfig) string {
+	h := sha256.New()
+	for _, v := range []string{config.Host, config.tlsServerName, config.SSLMode, config.SSLCACert, config.SSLClientCert, config.SSLClientKey} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return "mergen-" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (d *MySQLDriver) GetDatabases(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW DATABASES")
 	if err != nil {
 		return nil, err
 	}
@@ -73,9 +142,14 @@ func (d *MySQLDriver) GetDatabases(db *sql.DB) ([]string, error) {
 	return databases, nil
 }
 
-func (d *MySQLDriver) GetTables(db *sql.DB, database string) ([]TableInfo, error) {
+func (d *MySQLDriver) GetSchemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	// In MySQL schemas and databases are the same thing
+	return d.GetDatabases(ctx, db)
+}
+
+func (d *MySQLDriver) GetTables(ctx context.Context, db *sql.DB, database string) ([]TableInfo, error) {
 	query := fmt.Sprintf("SHOW TABLE STATUS FROM `%s`", database)
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -128,9 +202,9 @@ func (d *MySQLDriver) GetTables(db *sql.DB, database string) ([]TableInfo, error
 	return tables, nil
 }
 
-func (d *MySQLDriver) GetColumns(db *sql.DB, database, table string) ([]ColumnInfo, error) {
+func (d *MySQLDriver) GetColumns(ctx context.Context, db *sql.DB, database, table string) ([]ColumnInfo, error) {
 	query := fmt.Sprintf("SHOW FULL COLUMNS FROM `%s`.`%s`", database, table)
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -155,9 +229,9 @@ func (d *MySQLDriver) GetColumns(db *sql.DB, database, table string) ([]ColumnIn
 	return columns, nil
 }
 
-func (d *MySQLDriver) GetIndexes(db *sql.DB, database, table string) ([]IndexInfo, error) {
+func (d *MySQLDriver) GetIndexes(ctx context.Context, db *sql.DB, database, table string) ([]IndexInfo, error) {
 	query := fmt.Sprintf("SHOW INDEX FROM `%s`.`%s`", database, table)
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -177,6 +251,7 @@ func (d *MySQLDriver) GetIndexes(db *sql.DB, database, table string) ([]IndexInf
 				Columns:   []string{},
 				IsUnique:  nonUnique.String == "0",
 				IsPrimary: keyName.String == "PRIMARY",
+				Method:    strings.ToLower(indexType.String),
 			}
 			indexMap[keyName.String] = idx
 		}
@@ -190,25 +265,13 @@ func (d *MySQLDriver) GetIndexes(db *sql.DB, database, table string) ([]IndexInf
 	return indexes, nil
 }
 
-func (d *MySQLDriver) BuildTableDataQuery(req TableDataRequest, primaryKey string) string {
-	where := ""
-	if req.Filters != "" {
-		where = fmt.Sprintf(" WHERE %s", req.Filters)
+func (d *MySQLDriver) BuildTableDataQuery(req TableDataRequest, key RowIdentity, where string) string {
+	if where != "" {
+		where = " WHERE " + where
 	}
 
-	orderBy := req.OrderBy
-	if orderBy == "" && primaryKey != "" {
-		orderBy = primaryKey
-	}
-	orderDir := strings.ToUpper(req.OrderDir)
-	if orderDir != "DESC" {
-		orderDir = "ASC"
-	}
-
-	query := fmt.Sprintf("SELECT * FROM `%s`.`%s` %s", req.Database, req.Table, where)
-	if orderBy != "" {
-		query += fmt.Sprintf(" ORDER BY `%s` %s", orderBy, orderDir)
-	}
+	selectList, orderBy := tableDataClauses(d, req, key)
+	query := fmt.Sprintf("SELECT %s FROM %s.%s%s%s", selectList, d.QuoteIdentifier(req.Database), d.QuoteIdentifier(req.Table), where, orderBy)
 
 	pageSize := req.PageSize
 	if pageSize <= 0 {
@@ -220,15 +283,14 @@ func (d *MySQLDriver) BuildTableDataQuery(req TableDataRequest, primaryKey strin
 	return query
 }
 
-func (d *MySQLDriver) BuildCountQuery(database, table, filters string) string {
-	where := ""
-	if filters != "" {
-		where = fmt.Sprintf(" WHERE %s", filters)
+func (d *MySQLDriver) BuildCountQuery(database, table, where string) string {
+	if where != "" {
+		where = " WHERE " + where
 	}
-	return fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s` %s", database, table, where)
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s.%s%s", d.QuoteIdentifier(database), d.QuoteIdentifier(table), where)
 }
 
-func (d *MySQLDriver) BuildAlterTableQuery(database, table string, alteration TableAlteration) ([]string, error) {
+func (d *MySQLDriver) BuildAlterTableQuery(database, table string, current *TableDetails, alteration TableAlteration) ([]string, []string, error) {
 	var statements []string
 
 	// Rename table if requested
@@ -277,7 +339,7 @@ func (d *MySQLDriver) BuildAlterTableQuery(database, table string, alteration Ta
 		}
 	}
 
-	return statements, nil
+	return statements, nil, nil
 }
 
 func (d *MySQLDriver) BuildTruncateTableQuery(database, table string) string {
@@ -299,34 +361,78 @@ func (d *MySQLDriver) BuildInsertQuery(database, table string, columns []string)
 		database, table, strings.Join(quotedCols, ", "), strings.Join(placeholders, ", "))
 }
 
-func (d *MySQLDriver) BuildUpdateQuery(database, table, primaryKey string, columns []string) string {
+func (d *MySQLDriver) BuildUpdateQuery(database, table string, columns []string, where string) string {
 	setClauses := make([]string, len(columns))
 	for i, col := range columns {
 		setClauses[i] = fmt.Sprintf("%s = ?", d.QuoteIdentifier(col))
 	}
-	return fmt.Sprintf("UPDATE `%s`.`%s` SET %s WHERE %s = ?",
-		database, table, strings.Join(setClauses, ", "), d.QuoteIdentifier(primaryKey))
+	return fmt.Sprintf("UPDATE %s.%s SET %s WHERE %s",
+		d.QuoteIdentifier(database), d.QuoteIdentifier(table), strings.Join(setClauses, ", "), where)
 }
 
-func (d *MySQLDriver) BuildDeleteQuery(database, table, primaryKey string) string {
-	return fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE %s = ?",
-		database, table, d.QuoteIdentifier(primaryKey))
+func (d *MySQLDriver) BuildDeleteQuery(database, table, where string) string {
+	return fmt.Sprintf("DELETE FROM %s.%s WHERE %s", d.QuoteIdentifier(database), d.QuoteIdentifier(table), where)
 }
 
-func (d *MySQLDriver) BuildBatchDeleteQuery(database, table, primaryKey string, count int) string {
-	placeholders := make([]string, count)
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-	return fmt.Sprintf("DELETE FROM `%s`.`%s` WHERE %s IN (%s)",
-		database, table, d.QuoteIdentifier(primaryKey), strings.Join(placeholders, ", "))
+func (d *MySQLDriver) RowLocator() string {
+	return ""
+}
+
+func (d *MySQLDriver) SupportsReturning() bool {
+	return false
 }
 
 func (d *MySQLDriver) QuoteIdentifier(name string) string {
-	return fmt.Sprintf("`%s`", name)
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (d *MySQLDriver) QualifyTable(database, table string) string {
+	return d.QuoteIdentifier(database) + "." + d.QuoteIdentifier(table)
+}
+
+func (d *MySQLDriver) Placeholder(index int) string {
+	return "?"
+}
+
+func (d *MySQLDriver) JSONPathExpr(column string, path []string, placeholder string) (string, interface{}) {
+	return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, %s))", column, placeholder), jsonPathString(path)
 }
 
 func (d *MySQLDriver) BuildDistinctValuesQuery(database, table, column string) string {
 	return fmt.Sprintf("SELECT DISTINCT `%s` FROM `%s`.`%s` ORDER BY `%s` LIMIT 100",
 		column, database, table, column)
 }
+
+func (d *MySQLDriver) ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (d *MySQLDriver) BuildCancelQuery(connectionID int64) string {
+	return fmt.Sprintf("KILL QUERY %d", connectionID)
+}
+
+func (d *MySQLDriver) SplitStatements(script string) []ScriptStatement {
+	return splitStatements(script, splitOptions{
+		mysqlComments:    true,
+		backslashEscapes: true,
+		backticks:        true,
+		delimiterCommand: true,
+	})
+}
+
+func (d *MySQLDriver) BuildUseDatabaseQuery(database string) string {
+	return fmt.Sprintf("USE `%s`", database)
+}
+
+func (d *MySQLDriver) ReconnectsOnUseDatabase() bool {
+	return false
+}
+
+func (d *MySQLDriver) TransactionalDDL() bool {
+	// DDL statements commit the open transaction implicitly
+	return false
+}
@@ -0,0 +1,56 @@
+package database
+
+import "testing"
+
+func TestMySQLBuildDSN(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ConnectionConfig
+		want   string
+	}{
+		{
+			name:   "plain",
+			config: ConnectionConfig{Host: "db", Port: 3306, User: "app", Password: "pw", Database: "shop"},
+			want:   "app:pw@tcp(db:3306)/shop?parseTime=true",
+		},
+		{
+			name: "session settings",
+			config: ConnectionConfig{Host: "db", Port: 3306, User: "app", ConnectTimeout: 5, Charset: "utf8mb4",
+				Collation: "utf8mb4_bin", TimeZone: "Europe/Paris", StatementTimeout: 30},
+			want: "app:@tcp(db:3306)/?parseTime=true&timeout=5s&charset=utf8mb4&collation=utf8mb4_bin" +
+				"&time_zone=%27Europe%2FParis%27&max_execution_time=30000",
+		},
+		{
+			name: "extra parameters",
+			config: ConnectionConfig{Host: "db", Port: 3306, User: "app",
+				ExtraParams: map[string]string{"interpolateParams": "true", "loc": "Local"}},
+			want: "app:@tcp(db:3306)/?parseTime=true&interpolateParams=true&loc=Local",
+		},
+		{
+			name: "extra parameters can't override session settings",
+			config: ConnectionConfig{Host: "db", Port: 3306, User: "app", TimeZone: "UTC", ExtraParams: map[string]string{
+				"parseTime": "false", "timeout": "1h", "readTimeout": "1s", "charset": "latin1", "Collation": "x",
+				"time_zone": "'+01:00'", "max_execution_time": "0", "loc": "UTC",
+			}},
+			want: "app:@tcp(db:3306)/?parseTime=true&loc=UTC&time_zone=%27UTC%27",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&MySQLDriver{}).buildDSN(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("buildDSN\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQLQuote(t *testing.T) {
+	if got, want := mysqlQuote(`it's a \ test`), `'it\'s a \\ test'`; got != want {
+		t.Errorf("mysqlQuote = %s, want %s", got, want)
+	}
+}
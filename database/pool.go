@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Pool defaults used when a connection leaves a setting at zero
+const (
+	defaultMaxOpenConns    = 10
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 5 * time.Minute
+)
+
+// applyPoolSettings sizes db's connection pool from config
+func applyPoolSettings(db *sql.DB, config ConnectionConfig) {
+	maxOpen := config.MaxOpenConns
+	if maxOpen <= 0 {
+		maxOpen = defaultMaxOpenConns
+	}
+	maxIdle := config.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	lifetime := time.Duration(config.ConnMaxLifetime) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultConnMaxLifetime
+	}
+
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(min(maxIdle, maxOpen))
+	db.SetConnMaxLifetime(lifetime)
+}
+
+// withInitSQL wraps connector so statements run on every connection it opens
+func withInitSQL(connector driver.Connector, statements []string) driver.Connector {
+	if len(statements) == 0 {
+		return connector
+	}
+	return &initSQLConnector{Connector: connector, statements: statements}
+}
+
+// initSQLConnector runs statements on each connection it opens, so session
+// state such as SET commands holds on every connection in the pool
+type initSQLConnector struct {
+	driver.Connector
+	statements []string
+}
+
+func (c *initSQLConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range c.statements {
+		if err := execOnConn(ctx, conn, statement); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("init SQL %q failed: %w", statement, err)
+		}
+	}
+	return conn, nil
+}
+
+// execOnConn runs a statement without arguments on a raw driver connection
+func execOnConn(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// dsnConnector adapts a driver and DSN to driver.Connector for drivers that
+// don't provide one
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.drv
+}
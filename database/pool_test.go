@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLitePoolSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      ConnectionConfig
+		wantMaxOpen int
+	}{
+		{name: "defaults", wantMaxOpen: defaultMaxOpenConns},
+		{name: "configured", config: ConnectionConfig{MaxOpenConns: 3, MaxIdleConns: 8, ConnMaxLifetime: 60}, wantMaxOpen: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager()
+			defer m.DisconnectAll()
+			tt.config.Type = "sqlite"
+			tt.config.FilePath = filepath.Join(t.TempDir(), "test.db")
+			if err := m.Connect(context.Background(), "test", tt.config); err != nil {
+				t.Fatal(err)
+			}
+			s, err := m.getSession("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.db.Stats().MaxOpenConnections; got != tt.wantMaxOpen {
+				t.Errorf("max open connections = %d, want %d", got, tt.wantMaxOpen)
+			}
+		})
+	}
+}
+
+func TestInitSQL(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	defer m.DisconnectAll()
+	config := ConnectionConfig{
+		Type:         "sqlite",
+		FilePath:     filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns: 2,
+		InitSQL:      []string{"CREATE TEMP TABLE session_marker (x)", "INSERT INTO session_marker VALUES (1)"},
+	}
+	if err := m.Connect(ctx, "test", config); err != nil {
+		t.Fatal(err)
+	}
+	s, err := m.getSession("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every connection in the pool ran the statements, not just the first
+	conns := make([]interface{ Close() error }, 0, 2)
+	for i := 0; i < 2; i++ {
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+		var n int
+		if err := conn.QueryRowContext(ctx, "SELECT count(*) FROM temp.session_marker").Scan(&n); err != nil || n != 1 {
+			t.Errorf("connection %d: %d marker rows, %v", i, n, err)
+		}
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	config.InitSQL = []string{"SELECT * FROM missing_table"}
+	err = m.Connect(ctx, "broken", config)
+	if err == nil || !strings.Contains(err.Error(), "init SQL") {
+		t.Errorf("error = %v, want the failed init statement named", err)
+	}
+}
@@ -1,26 +1,62 @@
 package database
 
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
 	"fmt"
+	"io"
+	"net"
 	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresDriver struct{}
 
-func (d *PostgresDriver) Connect(config ConnectionConfig) (*sql.DB, error) {
-	sslmode := "disable"
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.Database, sslmode)
+func (d *PostgresDriver) Connect(ctx context.Context, config ConnectionConfig) (*sql.DB, error) {
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// allow tries a plain connection first and only then the TLS one
+	if config.SSLMode == "allow" && tlsConfig != nil {
+		db, err := d.open(ctx, config, nil)
+		if err == nil {
+			return db, nil
+		}
+	}
+	return d.open(ctx, config, tlsConfig)
+}
 
-	db, err := sql.Open("postgres", connStr)
+// open connects with TLS negotiated by pgTLSDialer, or unencrypted when
+// tlsConfig is nil
+func (d *PostgresDriver) open(ctx context.Context, config ConnectionConfig, tlsConfig *tls.Config) (*sql.DB, error) {
+	// TLS is negotiated by pgTLSDialer, so lib/pq itself always sees a plain connection
+	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		pgQuote(config.Host), config.Port, pgQuote(config.User), pgQuote(config.Password), pgQuote(config.Database))
+	for _, key := range extraParamKeys(config, "host", "hostaddr", "port", "user", "password", "dbname",
+		"sslmode", "sslrootcert", "sslcert", "sslkey") {
+		connStr += " " + key + "=" + pgQuote(config.ExtraParams[key])
+	}
+	for _, param := range postgresSessionParams(config) {
+		connStr += " " + param
+	}
+
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open postgres connection: %w", err)
 	}
+	if tlsConfig != nil {
+		connector.Dialer(&pgTLSDialer{tlsConfig: tlsConfig, optional: sslOptional(config)})
+	}
 
-	if err := db.Ping(); err != nil {
+	db := sql.OpenDB(withInitSQL(connector, config.InitSQL))
+	applyPoolSettings(db, config)
+
+	if err := db.PingContext(ctx); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to ping postgres: %w", err)
 	}
@@ -28,8 +64,101 @@ func (d *PostgresDriver) Connect(config ConnectionConfig) (*sql.DB, error) {
 	return db, nil
 }
 
-func (d *PostgresDriver) GetDatabases(db *sql.DB) ([]string, error) {
-	rows, err := db.Query("SELECT datname FROM pg_database WHERE datistemplate = false")
+// postgresSessionParams returns the connection string parameters for the
+// config's session settings. Apart from connect_timeout they are sent as
+// run-time parameters in the startup message of every connection.
+func postgresSessionParams(config ConnectionConfig) []string {
+	var params []string
+	if config.ConnectTimeout > 0 {
+		params = append(params, fmt.Sprintf("connect_timeout=%d", config.ConnectTimeout))
+	}
+	if config.ApplicationName != "" {
+		params = append(params, "application_name="+pgQuote(config.ApplicationName))
+	}
+	if config.SearchPath != "" {
+		params = append(params, "search_path="+pgQuote(config.SearchPath))
+	}
+	if config.TimeZone != "" {
+		params = append(params, "timezone="+pgQuote(config.TimeZone))
+	}
+	if config.StatementTimeout > 0 {
+		params = append(params, fmt.Sprintf("statement_timeout=%d", config.StatementTimeout*1000))
+	}
+	if config.ReadOnly {
+		params = append(params, "default_transaction_read_only=on")
+	}
+	return params
+}
+
+// pgQuote quotes a value for a libpq key/value connection string
+func pgQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// pgSSLRequest is the startup message asking a Postgres server to switch to TLS
+var pgSSLRequest = []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}
+
+// pgTLSDialer performs the Postgres SSLRequest exchange and TLS handshake
+// itself, which lets certificates be supplied inline and keeps host name
+// verification pointed at the real server when dialing through an SSH tunnel
+type pgTLSDialer struct {
+	tlsConfig *tls.Config
+	optional  bool // Carry on unencrypted when the server declines TLS
+}
+
+func (d *pgTLSDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *pgTLSDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+func (d *pgTLSDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(pgSSLRequest); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply[0] == 'N' && d.optional {
+		conn.SetDeadline(time.Time{})
+		return conn, nil
+	}
+	if reply[0] != 'S' {
+		conn.Close()
+		return nil, fmt.Errorf("SSL is not enabled on the server")
+	}
+
+	tlsConn := tls.Client(conn, d.tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+func (d *PostgresDriver) GetDatabases(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT datname FROM pg_database WHERE datistemplate = false")
 	if err != nil {
 		return nil, err
 	}
@@ -46,8 +175,44 @@ func (d *PostgresDriver) GetDatabases(db *sql.DB) ([]string, error) {
 	return databases, nil
 }
 
-func (d *PostgresDriver) GetTables(db *sql.DB, database string) ([]TableInfo, error) {
-	// Simple table list for Postgres
+// schemaName returns the schema to use, defaulting to public. A Postgres
+// connection is bound to one database, so table-level calls address schemas.
+func (d *PostgresDriver) schemaName(schema string) string {
+	if schema == "" {
+		return "public"
+	}
+	return schema
+}
+
+// QualifyTable returns a schema-qualified, quoted table name
+func (d *PostgresDriver) QualifyTable(schema, table string) string {
+	return d.QuoteIdentifier(d.schemaName(schema)) + "." + d.QuoteIdentifier(table)
+}
+
+func (d *PostgresDriver) GetSchemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT nspname
+		FROM pg_namespace
+		WHERE nspname !~ '^pg_' AND nspname <> 'information_schema'
+		ORDER BY nspname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, name)
+	}
+	return schemas, nil
+}
+
+func (d *PostgresDriver) GetTables(ctx context.Context, db *sql.DB, schema string) ([]TableInfo, error) {
 	query := `
 		SELECT 
 			table_name, 
@@ -56,9 +221,10 @@ func (d *PostgresDriver) GetTables(db *sql.DB, database string) ([]TableInfo, er
 			0 as data_size,
 			'' as create_time
 		FROM information_schema.tables 
-		WHERE table_schema = 'public'
+		WHERE table_schema = $1
+		ORDER BY table_name
 	`
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query, d.schemaName(schema))
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +241,7 @@ func (d *PostgresDriver) GetTables(db *sql.DB, database string) ([]TableInfo, er
 	return tables, nil
 }
 
-func (d *PostgresDriver) GetColumns(db *sql.DB, database, table string) ([]ColumnInfo, error) {
+func (d *PostgresDriver) GetColumns(ctx context.Context, db *sql.DB, schema, table string) ([]ColumnInfo, error) {
 	query := `
 		SELECT 
 			column_name, 
@@ -85,10 +251,10 @@ func (d *PostgresDriver) GetColumns(db *sql.DB, database, table string) ([]Colum
 			column_default, 
 			'' 
 		FROM information_schema.columns 
-		WHERE table_name = $1 AND table_schema = 'public'
+		WHERE table_name = $1 AND table_schema = $2
 		ORDER BY ordinal_position
 	`
-	rows, err := db.Query(query, table)
+	rows, err := db.QueryContext(ctx, query, table, d.schemaName(schema))
 	if err != nil {
 		return nil, err
 	}
@@ -106,29 +272,77 @@ func (d *PostgresDriver) GetColumns(db *sql.DB, database, table string) ([]Colum
 		c.Default = defaultVal.String
 		columns = append(columns, c)
 	}
-	return columns, nil
-}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-func (d *PostgresDriver) GetIndexes(db *sql.DB, database, table string) ([]IndexInfo, error) {
-	// Simplified indexes for PG PoC
-	return []IndexInfo{}, nil
+	indexes, err := d.GetIndexes(ctx, db, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	applyIndexKeys(columns, indexes)
+
+	return columns, nil
 }
 
-func (d *PostgresDriver) BuildTableDataQuery(req TableDataRequest, primaryKey string) string {
-	orderBy := req.OrderBy
-	if orderBy == "" && primaryKey != "" {
-		orderBy = primaryKey
+func (d *PostgresDriver) GetIndexes(ctx context.Context, db *sql.DB, schema, table string) ([]IndexInfo, error) {
+	// One row per key column; expression columns come back as their definition
+	query := `
+		SELECT
+			i.relname,
+			ix.indisunique,
+			ix.indisprimary,
+			am.amname,
+			COALESCE(pg_get_expr(ix.indpred, ix.indrelid), ''),
+			COALESCE(a.attname, pg_get_indexdef(ix.indexrelid, k.n::int, true))
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace ns ON ns.oid = t.relnamespace
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_am am ON am.oid = i.relam
+		CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, n)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum AND k.attnum > 0
+		WHERE ns.nspname = $1 AND t.relname = $2 AND k.n <= ix.indnkeyatts
+		ORDER BY ix.indisprimary DESC, i.relname, k.n
+	`
+	rows, err := db.QueryContext(ctx, query, d.schemaName(schema), table)
+	if err != nil {
+		return nil, err
 	}
-	orderDir := strings.ToUpper(req.OrderDir)
-	if orderDir != "DESC" {
-		orderDir = "ASC"
+	defer rows.Close()
+
+	indexes := []IndexInfo{}
+	for rows.Next() {
+		var name, method, predicate, column string
+		var unique, primary bool
+		if err := rows.Scan(&name, &unique, &primary, &method, &predicate, &column); err != nil {
+			return nil, err
+		}
+
+		if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		indexes = append(indexes, IndexInfo{
+			Name:      name,
+			Columns:   []string{column},
+			IsUnique:  unique,
+			IsPrimary: primary,
+			Method:    method,
+			Predicate: predicate,
+		})
 	}
+	return indexes, rows.Err()
+}
 
-	query := fmt.Sprintf("SELECT * FROM %s", d.QuoteIdentifier(req.Table))
-	if orderBy != "" {
-		query += fmt.Sprintf(" ORDER BY %s %s", d.QuoteIdentifier(orderBy), orderDir)
+func (d *PostgresDriver) BuildTableDataQuery(req TableDataRequest, key RowIdentity, where string) string {
+	if where != "" {
+		where = " WHERE " + where
 	}
 
+	selectList, orderBy := tableDataClauses(d, req, key)
+	query := fmt.Sprintf("SELECT %s FROM %s%s%s", selectList, d.QualifyTable(req.Schema, req.Table), where, orderBy)
+
 	pageSize := req.PageSize
 	if pageSize <= 0 {
 		pageSize = 50
@@ -139,22 +353,21 @@ func (d *PostgresDriver) BuildTableDataQuery(req TableDataRequest, primaryKey st
 	return query
 }
 
-func (d *PostgresDriver) BuildCountQuery(database, table, filters string) string {
-	where := ""
-	if filters != "" {
-		where = fmt.Sprintf(" WHERE %s", filters)
+func (d *PostgresDriver) BuildCountQuery(schema, table, where string) string {
+	if where != "" {
+		where = " WHERE " + where
 	}
-	return fmt.Sprintf("SELECT COUNT(*) FROM %s%s", d.QuoteIdentifier(table), where)
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s%s", d.QualifyTable(schema, table), where)
 }
 
-func (d *PostgresDriver) BuildAlterTableQuery(database, table string, alteration TableAlteration) ([]string, error) {
+func (d *PostgresDriver) BuildAlterTableQuery(schema, table string, current *TableDetails, alteration TableAlteration) ([]string, []string, error) {
 	var statements []string
-	quotedTable := d.QuoteIdentifier(table)
+	quotedTable := d.QualifyTable(schema, table)
 
 	// Rename table if requested
 	if alteration.RenameTo != "" && alteration.RenameTo != table {
 		statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quotedTable, d.QuoteIdentifier(alteration.RenameTo)))
-		quotedTable = d.QuoteIdentifier(alteration.RenameTo)
+		quotedTable = d.QualifyTable(schema, alteration.RenameTo)
 	}
 
 	// Drop columns
@@ -205,18 +418,18 @@ func (d *PostgresDriver) BuildAlterTableQuery(database, table string, alteration
 		}
 	}
 
-	return statements, nil
+	return statements, nil, nil
 }
 
-func (d *PostgresDriver) BuildTruncateTableQuery(database, table string) string {
-	return fmt.Sprintf("TRUNCATE TABLE %s", d.QuoteIdentifier(table))
+func (d *PostgresDriver) BuildTruncateTableQuery(schema, table string) string {
+	return fmt.Sprintf("TRUNCATE TABLE %s", d.QualifyTable(schema, table))
 }
 
-func (d *PostgresDriver) BuildDropTableQuery(database, table string) string {
-	return fmt.Sprintf("DROP TABLE %s", d.QuoteIdentifier(table))
+func (d *PostgresDriver) BuildDropTableQuery(schema, table string) string {
+	return fmt.Sprintf("DROP TABLE %s", d.QualifyTable(schema, table))
 }
 
-func (d *PostgresDriver) BuildInsertQuery(database, table string, columns []string) string {
+func (d *PostgresDriver) BuildInsertQuery(schema, table string, columns []string) string {
 	quotedCols := make([]string, len(columns))
 	placeholders := make([]string, len(columns))
 	for i, col := range columns {
@@ -224,37 +437,75 @@ func (d *PostgresDriver) BuildInsertQuery(database, table string, columns []stri
 		placeholders[i] = fmt.Sprintf("$%d", i+1)
 	}
 	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		d.QuoteIdentifier(table), strings.Join(quotedCols, ", "), strings.Join(placeholders, ", "))
+		d.QualifyTable(schema, table), strings.Join(quotedCols, ", "), strings.Join(placeholders, ", "))
 }
 
-func (d *PostgresDriver) BuildUpdateQuery(database, table, primaryKey string, columns []string) string {
+func (d *PostgresDriver) BuildUpdateQuery(schema, table string, columns []string, where string) string {
 	setClauses := make([]string, len(columns))
 	for i, col := range columns {
 		setClauses[i] = fmt.Sprintf("%s = $%d", d.QuoteIdentifier(col), i+1)
 	}
-	return fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d",
-		d.QuoteIdentifier(table), strings.Join(setClauses, ", "), d.QuoteIdentifier(primaryKey), len(columns)+1)
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		d.QualifyTable(schema, table), strings.Join(setClauses, ", "), where)
 }
 
-func (d *PostgresDriver) BuildDeleteQuery(database, table, primaryKey string) string {
-	return fmt.Sprintf("DELETE FROM %s WHERE %s = $1",
-		d.QuoteIdentifier(table), d.QuoteIdentifier(primaryKey))
+func (d *PostgresDriver) BuildDeleteQuery(schema, table, where string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", d.QualifyTable(schema, table), where)
 }
 
-func (d *PostgresDriver) BuildBatchDeleteQuery(database, table, primaryKey string, count int) string {
-	placeholders := make([]string, count)
-	for i := range placeholders {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-	}
-	return fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)",
-		d.QuoteIdentifier(table), d.QuoteIdentifier(primaryKey), strings.Join(placeholders, ", "))
+func (d *PostgresDriver) RowLocator() string {
+	// ctid changes when the row is updated, so keys go stale after an edit
+	return "ctid"
+}
+
+func (d *PostgresDriver) SupportsReturning() bool {
+	return true
 }
 
 func (d *PostgresDriver) QuoteIdentifier(name string) string {
-	return fmt.Sprintf("\"%s\"", name)
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (d *PostgresDriver) Placeholder(index int) string {
+	return fmt.Sprintf("$%d", index)
+}
+
+func (d *PostgresDriver) JSONPathExpr(column string, path []string, placeholder string) (string, interface{}) {
+	return fmt.Sprintf("(%s #>> %s)", column, placeholder), pq.Array(path)
 }
 
-func (d *PostgresDriver) BuildDistinctValuesQuery(database, table, column string) string {
+func (d *PostgresDriver) BuildDistinctValuesQuery(schema, table, column string) string {
 	return fmt.Sprintf("SELECT DISTINCT %s FROM %s ORDER BY %s LIMIT 100",
-		d.QuoteIdentifier(column), d.QuoteIdentifier(table), d.QuoteIdentifier(column))
+		d.QuoteIdentifier(column), d.QualifyTable(schema, table), d.QuoteIdentifier(column))
+}
+
+func (d *PostgresDriver) ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (d *PostgresDriver) BuildCancelQuery(connectionID int64) string {
+	return fmt.Sprintf("SELECT pg_cancel_backend(%d)", connectionID)
+}
+
+func (d *PostgresDriver) SplitStatements(script string) []ScriptStatement {
+	return splitStatements(script, splitOptions{
+		dollarQuotes: true,
+	})
+}
+
+func (d *PostgresDriver) BuildUseDatabaseQuery(database string) string {
+	return ""
+}
+
+func (d *PostgresDriver) ReconnectsOnUseDatabase() bool {
+	// A Postgres connection can't change databases; the pool must be reopened
+	return true
+}
+
+func (d *PostgresDriver) TransactionalDDL() bool {
+	return true
 }
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    Driver
+		connected string // Database the session is connected to
+		database  string
+		schema    string
+		want      string
+		wantErr   bool
+	}{
+		{name: "MySQL addresses the database", driver: &MySQLDriver{}, connected: "shop", database: "crm", schema: "ignored", want: "crm"},
+		{name: "SQLite addresses the attached database", driver: &SQLiteDriver{}, database: "aux", want: "aux"},
+		{name: "Postgres addresses the schema", driver: &PostgresDriver{}, connected: "shop", database: "shop", schema: "sales", want: "sales"},
+		{name: "Postgres without a database", driver: &PostgresDriver{}, connected: "shop", schema: "sales", want: "sales"},
+		{name: "Postgres default schema", driver: &PostgresDriver{}, connected: "shop", database: "shop", want: ""},
+		{name: "Postgres other database", driver: &PostgresDriver{}, connected: "shop", database: "crm", schema: "sales", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &session{driver: tt.driver, config: &ConnectionConfig{Database: tt.connected}}
+			got, err := s.namespace(tt.database, tt.schema)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("namespace = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("namespace = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresQualifiedQueries(t *testing.T) {
+	d := &PostgresDriver{}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "default schema", got: d.QualifyTable("", "orders"), want: `"public"."orders"`},
+		{name: "quoted names", got: d.QualifyTable(`Sales "EU"`, "Order"), want: `"Sales ""EU"""."Order"`},
+		{
+			name: "table data",
+			got:  d.BuildTableDataQuery(TableDataRequest{Schema: "sales", Table: "orders", Page: 2, PageSize: 10}, RowIdentity{}, ""),
+			want: `SELECT * FROM "sales"."orders" LIMIT 10 OFFSET 10`,
+		},
+		{name: "count", got: d.BuildCountQuery("sales", "orders", `"id" > $1`), want: `SELECT COUNT(*) FROM "sales"."orders" WHERE "id" > $1`},
+		{name: "truncate", got: d.BuildTruncateTableQuery("sales", "orders"), want: `TRUNCATE TABLE "sales"."orders"`},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	statements, _, err := d.BuildAlterTableQuery("sales", "orders", &TableDetails{}, TableAlteration{RenameTo: "orders_old", DropColumns: []string{"note"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `ALTER TABLE "sales"."orders" RENAME TO "orders_old";ALTER TABLE "sales"."orders_old" DROP COLUMN "note"`
+	if got := strings.Join(statements, ";"); got != want {
+		t.Errorf("alter statements = %s, want %s", got, want)
+	}
+}
+
+func TestPostgresSessionParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ConnectionConfig
+		want   string
+	}{
+		{name: "none"},
+		{
+			name: "all",
+			config: ConnectionConfig{ConnectTimeout: 5, ApplicationName: "mergen", SearchPath: "sales, public",
+				TimeZone: "UTC", StatementTimeout: 30, ReadOnly: true},
+			want: "connect_timeout=5 application_name='mergen' search_path='sales, public' timezone='UTC' " +
+				"statement_timeout=30000 default_transaction_read_only=on",
+		},
+		{name: "quoting", config: ConnectionConfig{ApplicationName: `it's \ mine`}, want: `application_name='it\'s \\ mine'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(postgresSessionParams(tt.config), " "); got != tt.want {
+				t.Errorf("params\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,109 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 )
 
-// ExecuteQuery runs a SELECT query and returns results
-func (m *Manager) ExecuteQuery(query string) (*QueryResult, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+// ExecuteQuery runs a SELECT query and returns results.
+// A non-empty queryID makes the query cancellable through CancelQuery, and an
+// editorID with an open transaction runs the query inside it.
+// Destructive statements on a production connection need confirmToken, see
+// CheckStatement.
+func (m *Manager) ExecuteQuery(ctx context.Context, connID, editorID, queryID, query, confirmToken string) (*QueryResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkStatement(query); err != nil {
+		return nil, err
+	}
+	if err := s.checkTxControl(editorID, query); err != nil {
+		return nil, err
+	}
+	if err := s.confirmDestructive(query, confirmToken); err != nil {
+		return nil, err
+	}
+
+	ctx, q, release, err := m.trackQuery(ctx, s, editorID, queryID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return runQuery(ctx, q, query)
+}
+
+// ExecuteStatement runs an INSERT/UPDATE/DELETE statement.
+// A non-empty queryID makes the statement cancellable through CancelQuery.
+// When an editorID is given, BEGIN/COMMIT/ROLLBACK manage that editor's pinned
+// transaction and other statements run inside it while it is open.
+// Destructive statements on a production connection need confirmToken, see
+// CheckStatement.
+func (m *Manager) ExecuteStatement(ctx context.Context, connID, editorID, queryID, query, confirmToken string) (*ExecuteResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+
+	if editorID != "" {
+		switch transactionControl(query) {
+		case "BEGIN":
+			return &ExecuteResult{}, m.BeginTransaction(ctx, connID, editorID)
+		case "COMMIT":
+			return &ExecuteResult{}, m.Commit(connID, editorID)
+		case "ROLLBACK":
+			return &ExecuteResult{}, m.Rollback(connID, editorID)
+		}
+	}
+
+	if err := s.checkStatement(query); err != nil {
+		return nil, err
+	}
+	if err := s.confirmDestructive(query, confirmToken); err != nil {
+		return nil, err
+	}
+
+	ctx, q, release, err := m.trackQuery(ctx, s, editorID, queryID)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
+
+	return runStatement(ctx, q, query)
+}
 
-	rows, err := db.Query(query)
+// runQuery runs a row-returning statement and collects its result set
+func runQuery(ctx context.Context, q queryer, query string) (*QueryResult, error) {
+	rows, err := q.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
+	return collectRows(rows)
+}
+
+// runStatement runs a statement that does not return rows
+func runStatement(ctx context.Context, q queryer, query string) (*ExecuteResult, error) {
+	res, err := q.ExecContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("statement failed: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	lastInsertId, _ := res.LastInsertId()
+
+	return &ExecuteResult{
+		RowsAffected: rowsAffected,
+		LastInsertId: lastInsertId,
+	}, nil
+}
+
+// collectRows reads every row of a result set into a QueryResult
+func collectRows(rows *sql.Rows) (*QueryResult, error) {
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
@@ -40,43 +127,28 @@ func (m *Manager) ExecuteQuery(query string) (*QueryResult, error) {
 		if err := rows.Scan(valuePtrs...); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-
-		// Convert values to JSON-serializable types
-		row := make([]interface{}, len(columns))
-		for i, v := range values {
-			switch val := v.(type) {
-			case []byte:
-				row[i] = string(val)
-			case nil:
-				row[i] = nil
-			default:
-				row[i] = val
-			}
-		}
-		result.Rows = append(result.Rows, row)
+		result.Rows = append(result.Rows, convertRow(values))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
 	}
 
 	result.RowCount = len(result.Rows)
 	return result, nil
 }
 
-// ExecuteStatement runs an INSERT/UPDATE/DELETE statement
-func (m *Manager) ExecuteStatement(query string) (*ExecuteResult, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
-	}
-
-	res, err := db.Exec(query)
-	if err != nil {
-		return nil, fmt.Errorf("statement failed: %w", err)
+// convertRow copies scanned values into JSON-serializable types
+func convertRow(values []interface{}) []interface{} {
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		switch val := v.(type) {
+		case []byte:
+			row[i] = string(val)
+		case nil:
+			row[i] = nil
+		default:
+			row[i] = val
+		}
 	}
-
-	rowsAffected, _ := res.RowsAffected()
-	lastInsertId, _ := res.LastInsertId()
-
-	return &ExecuteResult{
-		RowsAffected: rowsAffected,
-		LastInsertId: lastInsertId,
-	}, nil
+	return row
 }
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReadOnlyError is returned when a write is attempted on a read-only
+// connection
+type ReadOnlyError struct {
+	Operation string // e.g. "insert", "drop table" or the statement's keyword
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("connection is read-only: %s is not allowed", e.Operation)
+}
+
+// readOnlyKeywords are leading keywords of statements allowed on a read-only
+// connection. The server session is read-only as well, so this is a first
+// line of defence rather than a complete parser.
+var readOnlyKeywords = map[string]bool{
+	"SELECT":   true,
+	"SHOW":     true,
+	"EXPLAIN":  true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"USE":      true,
+}
+
+// isReadOnlyStatement reports whether stmt only reads data. A WITH clause
+// is judged by the statement it belongs to. mysqlComments treats # as
+// starting a comment, as in the session's dialect.
+func isReadOnlyStatement(stmt string, mysqlComments bool) bool {
+	keyword := statementKeyword(stmt, mysqlComments)
+	if keyword == "PRAGMA" {
+		// PRAGMA name reads a setting; PRAGMA name = value changes it
+		return !strings.Contains(stmt, "=")
+	}
+	return readOnlyKeywords[keyword]
+}
+
+// isReadOnly reports whether the session refuses writes
+func (s *session) isReadOnly() bool {
+	config := s.getConfig()
+	return config != nil && config.ReadOnly
+}
+
+// checkWritable refuses operation when the session is read-only
+func (s *session) checkWritable(operation string) error {
+	if s.isReadOnly() {
+		return &ReadOnlyError{Operation: operation}
+	}
+	return nil
+}
+
+// checkStatement refuses statements that may write when the session is
+// read-only
+func (s *session) checkStatement(stmt string) error {
+	if !s.isReadOnly() || isReadOnlyStatement(stmt, hashComments(s.driver)) {
+		return nil
+	}
+	operation := statementKeyword(stmt, hashComments(s.driver))
+	if operation == "" {
+		operation = "this statement"
+	}
+	return &ReadOnlyError{Operation: operation}
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIsReadOnlyStatement(t *testing.T) {
+	tests := []struct {
+		stmt          string
+		mysqlComments bool
+		want          bool
+	}{
+		{stmt: "SELECT * FROM t", want: true},
+		{stmt: "  -- note\nshow tables", want: true},
+		{stmt: "EXPLAIN SELECT 1", want: true},
+		{stmt: "(SELECT 1) UNION (SELECT 2)", want: true},
+		{stmt: "WITH x AS (SELECT 1) SELECT * FROM x", want: true},
+		{stmt: "WITH x AS (SELECT id FROM t) DELETE FROM t WHERE id IN (SELECT id FROM x)"},
+		{stmt: "WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x"},
+		{stmt: "PRAGMA table_info(t)", want: true},
+		{stmt: "PRAGMA journal_mode = WAL"},
+		{stmt: "# note\nSELECT 1", mysqlComments: true, want: true},
+		{stmt: "INSERT INTO t VALUES (1)"},
+		{stmt: "SET GLOBAL read_only = 0"},
+		{stmt: "CALL refresh()"},
+		{stmt: ""},
+	}
+
+	for _, tt := range tests {
+		if got := isReadOnlyStatement(tt.stmt, tt.mysqlComments); got != tt.want {
+			t.Errorf("isReadOnlyStatement(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestReadOnlySession(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql", ReadOnly: true})
+
+	if _, err := m.ExecuteQuery(ctx, "a", "editor", "", "SELECT 1", ""); err != nil {
+		t.Fatalf("read on a read-only connection: %v", err)
+	}
+
+	writes := []struct {
+		name          string
+		run           func() error
+		wantOperation string
+	}{
+		{
+			name: "query",
+			run: func() error {
+				_, err := m.ExecuteQuery(ctx, "a", "editor", "", "WITH x AS (SELECT 1) DELETE FROM t", "")
+				return err
+			},
+			wantOperation: "DELETE",
+		},
+		{
+			name: "statement",
+			run: func() error {
+				_, err := m.ExecuteStatement(ctx, "a", "editor", "", "UPDATE t SET a = 1", "")
+				return err
+			},
+			wantOperation: "UPDATE",
+		},
+		{
+			name: "script",
+			run: func() error {
+				_, err := m.ExecuteScript(ctx, "a", "editor", "", "SELECT 1;\nDROP TABLE t;", ScriptOptions{})
+				return err
+			},
+			wantOperation: "DROP",
+		},
+		{
+			name: "row insert",
+			run: func() error {
+				_, err := m.InsertRow(ctx, "a", "", "", "t", RowData{"a": 1})
+				return err
+			},
+			wantOperation: "insert",
+		},
+	}
+
+	for _, w := range writes {
+		err := w.run()
+		var readOnly *ReadOnlyError
+		if !errors.As(err, &readOnly) || readOnly.Operation != w.wantOperation {
+			t.Errorf("%s: error = %v, want a read-only error for %s", w.name, err, w.wantOperation)
+		}
+	}
+
+	// No write reached the server, and the script stopped before its SELECT
+	if got := server.statements(); len(got) != 0 {
+		t.Errorf("statements = %q, want none", got)
+	}
+}
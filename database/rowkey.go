@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Row identity kinds, from most to least reliable
+const (
+	RowKeyPrimary = "primary" // Primary key
+	RowKeyUnique  = "unique"  // Unique index over NOT NULL columns
+	RowKeyLocator = "locator" // Physical row locator: Postgres ctid or SQLite rowid; changes when Postgres rewrites the row
+	RowKeyFullRow = "row"     // Every comparable column; duplicate rows can't be told apart
+	RowKeyNone    = "none"    // No column can be compared, so rows can't be edited
+)
+
+// ErrAmbiguousRowKey is returned when a row key matches more than one row;
+// nothing is changed
+var ErrAmbiguousRowKey = errors.New("row key matches more than one row")
+
+// nonComparableTypes are the column types whose values can't be matched
+// with = after a round trip through the grid: Postgres has no = operator for
+// json, xml and the geometric types, and floating point and date-time values
+// may not come back exactly as stored. They are matched by type name prefix.
+var nonComparableTypes = []string{
+	"json", "xml", "point", "line", "lseg", "box", "path", "polygon", "circle",
+	"geometry", "multi", "array", "user-defined",
+	"real", "float", "double", "time", "datetime",
+}
+
+// comparableType reports whether columns of colType can be part of a
+// full-row key
+func comparableType(colType string) bool {
+	colType = strings.ToLower(strings.TrimSpace(colType))
+	for _, prefix := range nonComparableTypes {
+		if strings.HasPrefix(colType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// RowKey identifies one row by column values
+type RowKey map[string]interface{}
+
+// RowIdentity is how the rows of a table are told apart
+type RowIdentity struct {
+	Kind    string   `json:"kind"`
+	Columns []string `json:"columns"`
+}
+
+// tableRowIdentity picks the most reliable way to identify rows of a table
+func tableRowIdentity(driver Driver, details *TableDetails) RowIdentity {
+	notNull := make(map[string]bool, len(details.Columns))
+	var pkColumns, comparable []string
+	for _, col := range details.Columns {
+		notNull[col.Name] = !col.Nullable
+		if comparableType(col.Type) {
+			comparable = append(comparable, col.Name)
+		}
+		if col.Key == "PRI" {
+			pkColumns = append(pkColumns, col.Name)
+		}
+	}
+
+	var unique *IndexInfo
+	for i, idx := range details.Indexes {
+		if idx.IsPrimary && len(idx.Columns) > 0 {
+			// Index order is the key order, unlike column order
+			return RowIdentity{Kind: RowKeyPrimary, Columns: idx.Columns}
+		}
+		if !idx.IsUnique || idx.Predicate != "" || len(idx.Columns) == 0 {
+			continue
+		}
+		usable := true
+		for _, name := range idx.Columns {
+			// NULLs don't collide in unique indexes; expression parts aren't columns
+			if !notNull[name] {
+				usable = false
+				break
+			}
+		}
+		if usable && (unique == nil || len(idx.Columns) < len(unique.Columns)) {
+			unique = &details.Indexes[i]
+		}
+	}
+
+	if len(pkColumns) > 0 {
+		return RowIdentity{Kind: RowKeyPrimary, Columns: pkColumns}
+	}
+	if unique != nil {
+		return RowIdentity{Kind: RowKeyUnique, Columns: unique.Columns}
+	}
+	if locator := driver.RowLocator(); locator != "" {
+		if _, shadowed := notNull[locator]; !shadowed {
+			return RowIdentity{Kind: RowKeyLocator, Columns: []string{locator}}
+		}
+	}
+	if len(comparable) == 0 {
+		return RowIdentity{Kind: RowKeyNone}
+	}
+	return RowIdentity{Kind: RowKeyFullRow, Columns: comparable}
+}
+
+// checkRowKey refuses a key that isn't made of exactly the identity's
+// columns, before any of its names reach a query
+func checkRowKey(identity RowIdentity, key RowKey) error {
+	if identity.Kind == RowKeyNone {
+		return fmt.Errorf("rows of this table can't be identified: it has no key and no column that can be compared")
+	}
+	columns := make(map[string]bool, len(identity.Columns))
+	for _, name := range identity.Columns {
+		if _, ok := key[name]; !ok {
+			return fmt.Errorf("row key is missing column %q", name)
+		}
+		columns[name] = true
+	}
+	for name := range key {
+		if !columns[name] {
+			return fmt.Errorf("%q is not a row key column of this table", name)
+		}
+	}
+	return nil
+}
+
+// checkRowKeys checks keys from the client against the row identity of table
+func (m *Manager) checkRowKeys(ctx context.Context, s *session, connID, database, schema, table string, keys ...RowKey) error {
+	details, err := m.GetTableInfo(ctx, connID, database, schema, table)
+	if err != nil {
+		return err
+	}
+	identity := tableRowIdentity(s.driver, details)
+	for _, key := range keys {
+		if err := checkRowKey(identity, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowKeys reads the key of every row in result, whose columns include the
+// identity's columns
+func rowKeys(identity RowIdentity, result *QueryResult) []RowKey {
+	index := make(map[string]int, len(result.Columns))
+	for i, name := range result.Columns {
+		index[name] = i
+	}
+
+	keys := make([]RowKey, len(result.Rows))
+	for r, row := range result.Rows {
+		key := make(RowKey, len(identity.Columns))
+		for _, name := range identity.Columns {
+			if i, ok := index[name]; ok {
+				key[name] = row[i]
+			}
+		}
+		keys[r] = key
+	}
+	return keys
+}
+
+// buildKeyCondition builds a WHERE condition matching key, numbering its
+// placeholders after offset. NULL key values match with IS NULL.
+func buildKeyCondition(driver Driver, key RowKey, offset int) (string, []interface{}, error) {
+	if len(key) == 0 {
+		return "", nil, fmt.Errorf("row key is required")
+	}
+
+	columns := make([]string, 0, len(key))
+	for name := range key {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	parts := make([]string, len(columns))
+	var args []interface{}
+	for i, name := range columns {
+		if key[name] == nil {
+			parts[i] = driver.QuoteIdentifier(name) + " IS NULL"
+			continue
+		}
+		args = append(args, key[name])
+		parts[i] = fmt.Sprintf("%s = %s", driver.QuoteIdentifier(name), driver.Placeholder(offset+len(args)))
+	}
+	return strings.Join(parts, " AND "), args, nil
+}
+
+// execSingleRow runs a write in tx that must change at most one row. The
+// caller rolls tx back when it fails.
+func execSingleRow(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected > 1 {
+		return 0, fmt.Errorf("%w: %d rows would change", ErrAmbiguousRowKey, rowsAffected)
+	}
+	return rowsAffected, nil
+}
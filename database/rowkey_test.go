@@ -0,0 +1,211 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTableRowIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  Driver
+		details TableDetails
+		want    RowIdentity
+	}{
+		{
+			name:   "primary key index order",
+			driver: &MySQLDriver{},
+			details: TableDetails{
+				Columns: []ColumnInfo{{Name: "a", Key: "PRI"}, {Name: "b", Key: "PRI"}, {Name: "c"}},
+				Indexes: []IndexInfo{{Name: "PRIMARY", Columns: []string{"b", "a"}, IsPrimary: true, IsUnique: true}},
+			},
+			want: RowIdentity{Kind: RowKeyPrimary, Columns: []string{"b", "a"}},
+		},
+		{
+			name:    "primary key from columns",
+			driver:  &MySQLDriver{},
+			details: TableDetails{Columns: []ColumnInfo{{Name: "id", Key: "PRI"}, {Name: "v"}}},
+			want:    RowIdentity{Kind: RowKeyPrimary, Columns: []string{"id"}},
+		},
+		{
+			name:   "smallest usable unique index",
+			driver: &PostgresDriver{},
+			details: TableDetails{
+				Columns: []ColumnInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d", Nullable: true}},
+				Indexes: []IndexInfo{
+					{Name: "ab", Columns: []string{"a", "b"}, IsUnique: true},
+					{Name: "d", Columns: []string{"d"}, IsUnique: true},
+					{Name: "c_partial", Columns: []string{"c"}, IsUnique: true, Predicate: "c > 0"},
+					{Name: "c_plain", Columns: []string{"c"}},
+					{Name: "a", Columns: []string{"a"}, IsUnique: true},
+				},
+			},
+			want: RowIdentity{Kind: RowKeyUnique, Columns: []string{"a"}},
+		},
+		{
+			name:    "Postgres locator",
+			driver:  &PostgresDriver{},
+			details: TableDetails{Columns: []ColumnInfo{{Name: "v", Nullable: true}}},
+			want:    RowIdentity{Kind: RowKeyLocator, Columns: []string{"ctid"}},
+		},
+		{
+			name:    "SQLite rowid shadowed by a column",
+			driver:  &SQLiteDriver{},
+			details: TableDetails{Columns: []ColumnInfo{{Name: "rowid", Type: "TEXT"}, {Name: "v", Type: "TEXT"}}},
+			want:    RowIdentity{Kind: RowKeyFullRow, Columns: []string{"rowid", "v"}},
+		},
+		{
+			name:   "full row leaves out non-comparable columns",
+			driver: &MySQLDriver{},
+			details: TableDetails{Columns: []ColumnInfo{
+				{Name: "name", Type: "varchar(20)"},
+				{Name: "price", Type: "double"},
+				{Name: "doc", Type: "json"},
+				{Name: "at", Type: "timestamp(3)"},
+				{Name: "amount", Type: "decimal(10,2)"},
+			}},
+			want: RowIdentity{Kind: RowKeyFullRow, Columns: []string{"name", "amount"}},
+		},
+		{
+			name:   "no comparable column",
+			driver: &MySQLDriver{},
+			details: TableDetails{Columns: []ColumnInfo{
+				{Name: "doc", Type: "json"},
+				{Name: "score", Type: "float"},
+			}},
+			want: RowIdentity{Kind: RowKeyNone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tableRowIdentity(tt.driver, &tt.details)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparableType(t *testing.T) {
+	tests := []struct {
+		colType string
+		want    bool
+	}{
+		{"integer", true},
+		{"character varying", true},
+		{"numeric", true},
+		{"bytea", true},
+		{"date", true},
+		{"uuid", true},
+		{"jsonb", false},
+		{"xml", false},
+		{"point", false},
+		{"double precision", false},
+		{"REAL", false},
+		{"timestamp without time zone", false},
+		{"datetime(6)", false},
+		{"ARRAY", false},
+		{"USER-DEFINED", false},
+		{"MULTIPOLYGON", false},
+	}
+
+	for _, tt := range tests {
+		if got := comparableType(tt.colType); got != tt.want {
+			t.Errorf("comparableType(%q) = %v, want %v", tt.colType, got, tt.want)
+		}
+	}
+}
+
+func TestCheckRowKey(t *testing.T) {
+	primary := RowIdentity{Kind: RowKeyPrimary, Columns: []string{"a", "b"}}
+
+	tests := []struct {
+		name     string
+		identity RowIdentity
+		key      RowKey
+		wantErr  string
+	}{
+		{name: "exact", identity: primary, key: RowKey{"a": 1, "b": nil}},
+		{name: "missing column", identity: primary, key: RowKey{"a": 1}, wantErr: `missing column "b"`},
+		{name: "extra column", identity: primary, key: RowKey{"a": 1, "b": 2, "c": 3}, wantErr: `"c" is not a row key column`},
+		{name: "injected name", identity: primary, key: RowKey{"a": 1, "b": 2, `a" = 1 OR "1`: 1}, wantErr: "is not a row key column"},
+		{name: "no identity", identity: RowIdentity{Kind: RowKeyNone}, key: RowKey{}, wantErr: "can't be identified"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRowKey(tt.identity, tt.key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildKeyCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    Driver
+		key       RowKey
+		offset    int
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "sorted columns",
+			driver:    &MySQLDriver{},
+			key:       RowKey{"b": 2, "a": "x"},
+			wantWhere: "`a` = ? AND `b` = ?",
+			wantArgs:  []interface{}{"x", 2},
+		},
+		{
+			name:      "NULL and offset",
+			driver:    &PostgresDriver{},
+			key:       RowKey{"a": nil, "b": 2, "c": 3},
+			offset:    2,
+			wantWhere: `"a" IS NULL AND "b" = $3 AND "c" = $4`,
+			wantArgs:  []interface{}{2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, err := buildKeyCondition(tt.driver, tt.key, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+
+	if _, _, err := buildKeyCondition(&MySQLDriver{}, RowKey{}, 0); err == nil {
+		t.Error("expected an empty key to be refused")
+	}
+}
+
+func TestRowKeys(t *testing.T) {
+	identity := RowIdentity{Kind: RowKeyPrimary, Columns: []string{"id"}}
+	result := &QueryResult{
+		Columns: []string{"name", "id"},
+		Rows:    [][]interface{}{{"a", int64(1)}, {"b", nil}},
+	}
+
+	got := rowKeys(identity, result)
+	want := []RowKey{{"id": int64(1)}, {"id": nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rowKeys = %v, want %v", got, want)
+	}
+}
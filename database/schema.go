@@ -1,17 +1,18 @@
 package database
 
 import (
+	"context"
 	"fmt"
 )
 
 // GetDatabases returns list of all databases
-func (m *Manager) GetDatabases() ([]DatabaseInfo, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+func (m *Manager) GetDatabases(ctx context.Context, connID string) ([]DatabaseInfo, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
 	}
 
-	names, err := m.driver.GetDatabases(db)
+	names, err := s.driver.GetDatabases(ctx, s.db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get databases: %w", err)
 	}
@@ -24,14 +25,48 @@ func (m *Manager) GetDatabases() ([]DatabaseInfo, error) {
 	return databases, nil
 }
 
-// GetTables returns list of tables in a database
-func (m *Manager) GetTables(database string) ([]TableInfo, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+// GetSchemas returns the schemas of the connected database, to pass as the
+// schema argument of table-level calls on Postgres
+func (m *Manager) GetSchemas(ctx context.Context, connID string) ([]string, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := s.driver.GetSchemas(ctx, s.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schemas: %w", err)
+	}
+
+	return schemas, nil
+}
+
+// namespace returns what table-level driver calls address for database and
+// schema. Dialects whose connections are bound to one database address a
+// schema of the connected database; the others address the database.
+func (s *session) namespace(database, schema string) (string, error) {
+	if !s.driver.ReconnectsOnUseDatabase() {
+		return database, nil
 	}
+	if current := s.getConfig().Database; database != "" && current != "" && database != current {
+		return "", fmt.Errorf("not connected to database %s; switch to it first", database)
+	}
+	return schema, nil
+}
 
-	tables, err := m.driver.GetTables(db, database)
+// GetTables returns list of tables in a database. schema picks the schema on
+// Postgres, where "" means public, and is ignored elsewhere.
+func (m *Manager) GetTables(ctx context.Context, connID, database, schema string) ([]TableInfo, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := s.driver.GetTables(ctx, s.db, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tables: %w", err)
 	}
@@ -40,13 +75,17 @@ func (m *Manager) GetTables(database string) ([]TableInfo, error) {
 }
 
 // GetColumns returns list of columns in a table
-func (m *Manager) GetColumns(database, table string) ([]ColumnInfo, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+func (m *Manager) GetColumns(ctx context.Context, connID, database, schema, table string) ([]ColumnInfo, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return nil, err
 	}
 
-	columns, err := m.driver.GetColumns(db, database, table)
+	columns, err := s.driver.GetColumns(ctx, s.db, namespace, table)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
@@ -55,13 +94,13 @@ func (m *Manager) GetColumns(database, table string) ([]ColumnInfo, error) {
 }
 
 // GetTableInfo returns detailed information about a table
-func (m *Manager) GetTableInfo(database, table string) (*TableDetails, error) {
-	columns, err := m.GetColumns(database, table)
+func (m *Manager) GetTableInfo(ctx context.Context, connID, database, schema, table string) (*TableDetails, error) {
+	columns, err := m.GetColumns(ctx, connID, database, schema, table)
 	if err != nil {
 		return nil, err
 	}
 
-	indexes, err := m.GetIndexes(database, table)
+	indexes, err := m.GetIndexes(ctx, connID, database, schema, table)
 	if err != nil {
 		return nil, err
 	}
@@ -74,13 +113,17 @@ func (m *Manager) GetTableInfo(database, table string) (*TableDetails, error) {
 }
 
 // GetIndexes returns list of indexes on a table
-func (m *Manager) GetIndexes(database, table string) ([]IndexInfo, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+func (m *Manager) GetIndexes(ctx context.Context, connID, database, schema, table string) ([]IndexInfo, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return nil, err
 	}
 
-	indexes, err := m.driver.GetIndexes(db, database, table)
+	indexes, err := s.driver.GetIndexes(ctx, s.db, namespace, table)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get indexes: %w", err)
 	}
@@ -89,36 +132,49 @@ func (m *Manager) GetIndexes(database, table string) ([]IndexInfo, error) {
 }
 
 // UseDatabase switches to a specific database
-func (m *Manager) UseDatabase(database string) error {
-	db := m.getDB()
-	if db == nil {
-		return fmt.Errorf("not connected to database")
+func (m *Manager) UseDatabase(ctx context.Context, connID, database string) error {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
 	}
 
-	// Dialect specific switch might be needed, but USE is fairly common.
-	// For now, let's just use a raw statement, but PostgreSQL uses a different connection.
-	// We might need Driver.SwitchDatabase in the future.
-	_, err := db.Exec("USE " + m.driver.QuoteIdentifier(database))
-	if err != nil {
-		return fmt.Errorf("failed to switch database: %w", err)
+	// PostgreSQL uses a different connection per database
+	if s.driver.ReconnectsOnUseDatabase() {
+		return m.reopenSession(ctx, s, database)
 	}
 
-	if m.config != nil {
-		m.config.Database = database
+	// SQLite has nothing to run
+	if query := s.driver.BuildUseDatabaseQuery(database); query != "" {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to switch database: %w", err)
+		}
 	}
 
+	s.setDatabase(database)
+
 	return nil
 }
 
-// TruncateTable removes all rows from a table
-func (m *Manager) TruncateTable(database, table string) error {
-	db := m.getDB()
-	if db == nil {
-		return fmt.Errorf("not connected to database")
+// TruncateTable removes all rows from a table. On a production connection it
+// needs the confirmToken from a previous ConfirmationRequiredError.
+func (m *Manager) TruncateTable(ctx context.Context, connID, database, schema, table, confirmToken string) error {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkWritable("truncate table"); err != nil {
+		return err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return err
 	}
 
-	query := m.driver.BuildTruncateTableQuery(database, table)
-	_, err := db.Exec(query)
+	query := s.driver.BuildTruncateTableQuery(namespace, table)
+	if err := s.confirmDestructive(query, confirmToken); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to truncate table: %w", err)
 	}
@@ -126,15 +182,26 @@ func (m *Manager) TruncateTable(database, table string) error {
 	return nil
 }
 
-// DropTable deletes a table
-func (m *Manager) DropTable(database, table string) error {
-	db := m.getDB()
-	if db == nil {
-		return fmt.Errorf("not connected to database")
+// DropTable deletes a table. On a production connection it needs the
+// confirmToken from a previous ConfirmationRequiredError.
+func (m *Manager) DropTable(ctx context.Context, connID, database, schema, table, confirmToken string) error {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkWritable("drop table"); err != nil {
+		return err
+	}
+	namespace, err := s.namespace(database, schema)
+	if err != nil {
+		return err
 	}
 
-	query := m.driver.BuildDropTableQuery(database, table)
-	_, err := db.Exec(query)
+	query := s.driver.BuildDropTableQuery(namespace, table)
+	if err := s.confirmDestructive(query, confirmToken); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to drop table: %w", err)
 	}
@@ -1,13 +1,11 @@
 package database
 
-import (
-	"fmt"
-)
+import "context"
 
-func (m *Manager) GetDatabaseSchema(database string) (map[string][]string, error) {
-	db := m.getDB()
-	if db == nil {
-		return nil, fmt.Errorf("not connected to database")
+func (m *Manager) GetDatabaseSchema(ctx context.Context, connID, database, schema string) (map[string][]string, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Helper to check what driver we are using to execute correct query
@@ -31,34 +29,34 @@ func (m *Manager) GetDatabaseSchema(database string) (map[string][]string, error
 	// Given the user wants DataGrip like storage, let's try the efficient way but valid for MySQL.
 
 	// Check if MySQL
-	if _, ok := m.driver.(*MySQLDriver); ok {
-		rows, err := db.Query(query, database)
+	if _, ok := s.driver.(*MySQLDriver); ok {
+		rows, err := s.db.QueryContext(ctx, query, database)
 		if err != nil {
 			return nil, err
 		}
 		defer rows.Close()
 
-		schema := make(map[string][]string)
+		result := make(map[string][]string)
 		for rows.Next() {
 			var table, col string
 			if err := rows.Scan(&table, &col); err != nil {
 				continue
 			}
-			schema[table] = append(schema[table], col)
+			result[table] = append(result[table], col)
 		}
-		return schema, nil
+		return result, nil
 	}
 
 	// Fallback for others (Postgres) or if we want to be safe
-	tables, err := m.GetTables(database)
+	tables, err := m.GetTables(ctx, connID, database, schema)
 	if err != nil {
 		return nil, err
 	}
 
-	schema := make(map[string][]string)
+	result := make(map[string][]string)
 	// This might be slow for many tables, but reliable
 	for _, table := range tables {
-		cols, err := m.GetColumns(database, table.Name)
+		cols, err := m.GetColumns(ctx, connID, database, schema, table.Name)
 		if err != nil {
 			continue
 		}
@@ -66,8 +64,8 @@ func (m *Manager) GetDatabaseSchema(database string) (map[string][]string, error
 		for _, c := range cols {
 			colNames = append(colNames, c.Name)
 		}
-		schema[table.Name] = colNames
+		result[table.Name] = colNames
 	}
 
-	return schema, nil
+	return result, nil
 }
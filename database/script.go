@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ScriptOptions controls how ExecuteScript reacts to failing statements
+type ScriptOptions struct {
+	ContinueOnError bool   `json:"continueOnError"`        // Keep going after a failed statement instead of stopping
+	ConfirmToken    string `json:"confirmToken,omitempty"` // Confirms destructive statements on a production connection
+}
+
+// StatementResult is the outcome of one statement in a script
+type StatementResult struct {
+	Index        int          `json:"index"`
+	Statement    string       `json:"statement"`
+	Line         int          `json:"line"`
+	Result       *QueryResult `json:"result,omitempty"` // Set for statements that return rows
+	RowsAffected int64        `json:"rowsAffected"`
+	Error        string       `json:"error,omitempty"`
+	Skipped      bool         `json:"skipped"` // Not run because an earlier statement failed
+	ElapsedMs    int64        `json:"elapsedMs"`
+}
+
+// ScriptResult holds the ordered outcomes of a script run
+type ScriptResult struct {
+	Statements []StatementResult `json:"statements"`
+	Failed     int               `json:"failed"`
+	ElapsedMs  int64             `json:"elapsedMs"`
+}
+
+// rowReturningKeywords are leading keywords of statements that produce a result set
+var rowReturningKeywords = map[string]bool{
+	"SELECT":   true,
+	"SHOW":     true,
+	"WITH":     true,
+	"EXPLAIN":  true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"CALL":     true,
+	"PRAGMA":   true,
+}
+
+// returningClause matches INSERT/UPDATE/DELETE ... RETURNING
+var returningClause = regexp.MustCompile(`(?i)\bRETURNING\b`)
+
+// returnsRows reports whether a statement should be run as a query
+func returnsRows(stmt string) bool {
+	if rowReturningKeywords[leadingKeyword(stmt)] {
+		return true
+	}
+	return returningClause.MatchString(stmt)
+}
+
+// ExecuteScript splits a script with the session's dialect rules and runs the
+// statements in order on a single connection, so session state such as USE,
+// SET and temporary tables carries over between statements.
+// A non-empty queryID makes the whole script cancellable through CancelQuery,
+// and an editorID with an open transaction runs the script inside it.
+func (m *Manager) ExecuteScript(ctx context.Context, connID, editorID, queryID, script string, opts ScriptOptions) (*ScriptResult, error) {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := s.driver.SplitStatements(script)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no statements to execute")
+	}
+	for _, stmt := range statements {
+		if err := s.checkStatement(stmt.Text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", stmt.Line, err)
+		}
+		if err := s.checkTxControl(editorID, stmt.Text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", stmt.Line, err)
+		}
+	}
+	if err := s.confirmScript(script, statements, opts.ConfirmToken); err != nil {
+		return nil, err
+	}
+
+	ctx, q, release, err := m.trackScript(ctx, s, editorID, queryID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	result := &ScriptResult{
+		Statements: make([]StatementResult, len(statements)),
+	}
+
+	stopped := false
+	for i, stmt := range statements {
+		res := &result.Statements[i]
+		res.Index = i
+		res.Statement = stmt.Text
+		res.Line = stmt.Line
+
+		if stopped || ctx.Err() != nil {
+			res.Skipped = true
+			continue
+		}
+
+		stmtStart := time.Now()
+		if returnsRows(stmt.Text) {
+			res.Result, err = runQuery(ctx, q, stmt.Text)
+		} else {
+			var exec *ExecuteResult
+			exec, err = runStatement(ctx, q, stmt.Text)
+			if exec != nil {
+				res.RowsAffected = exec.RowsAffected
+			}
+		}
+		res.ElapsedMs = time.Since(stmtStart).Milliseconds()
+
+		if err != nil {
+			res.Error = err.Error()
+			result.Failed++
+			if !opts.ContinueOnError {
+				stopped = true
+			}
+		}
+	}
+
+	result.ElapsedMs = time.Since(start).Milliseconds()
+	return result, nil
+}
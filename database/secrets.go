@@ -0,0 +1,244 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name secrets are filed under in the OS keyring
+const keyringService = "runedb"
+
+// Secret backends reported by Storage.SecretBackend
+const (
+	SecretBackendKeyring = "keyring"
+	SecretBackendVault   = "vault"
+)
+
+// ErrSecretNotFound is returned when a key has no stored secret
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore keeps connection secrets outside of connections.json
+type SecretStore interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// keyringStore stores secrets in the OS secret service: the Secret Service
+// D-Bus API on Linux, Keychain on macOS and Credential Manager on Windows
+type keyringStore struct{}
+
+func (keyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrSecretNotFound
+	}
+	return value, err
+}
+
+func (keyringStore) Set(key, value string) error {
+	err := keyring.Set(keyringService, key, value)
+	if errors.Is(err, keyring.ErrSetDataTooBig) {
+		return fmt.Errorf("%w; store large private keys as a file path instead", err)
+	}
+	return err
+}
+
+func (keyringStore) Delete(key string) error {
+	err := keyring.Delete(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// keyringAvailable reports whether the OS secret service can be reached
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "__probe__")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// connectionSecrets holds the secret fields of a connection config
+type connectionSecrets struct {
+	Password      string            `json:"password,omitempty"`
+	SSHPassword   string            `json:"sshPassword,omitempty"`
+	SSHPrivateKey string            `json:"sshPrivateKey,omitempty"`
+	SSHPassphrase string            `json:"sshPassphrase,omitempty"`
+	SSLClientKey  string            `json:"sslClientKey,omitempty"`
+	JumpHosts     []jumpHostSecrets `json:"jumpHosts,omitempty"`
+}
+
+type jumpHostSecrets struct {
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// extractSecrets moves the secret fields out of config
+func extractSecrets(config *ConnectionConfig) connectionSecrets {
+	secrets := connectionSecrets{
+		Password:      config.Password,
+		SSHPassword:   config.SSHPassword,
+		SSHPrivateKey: config.SSHPrivateKey,
+		SSHPassphrase: config.SSHPassphrase,
+		SSLClientKey:  config.SSLClientKey,
+	}
+	config.Password = ""
+	config.SSHPassword = ""
+	config.SSHPrivateKey = ""
+	config.SSHPassphrase = ""
+	config.SSLClientKey = ""
+
+	// Copy so callers holding the original slice keep their secrets
+	config.SSHJumpHosts = append([]SSHJumpHost(nil), config.SSHJumpHosts...)
+
+	hasJumpSecrets := false
+	jumps := make([]jumpHostSecrets, len(config.SSHJumpHosts))
+	for i := range config.SSHJumpHosts {
+		jump := &config.SSHJumpHosts[i]
+		jumps[i] = jumpHostSecrets{
+			Password:   jump.Password,
+			PrivateKey: jump.PrivateKey,
+			Passphrase: jump.Passphrase,
+		}
+		if jumps[i] != (jumpHostSecrets{}) {
+			hasJumpSecrets = true
+		}
+		jump.Password = ""
+		jump.PrivateKey = ""
+		jump.Passphrase = ""
+	}
+	if hasJumpSecrets {
+		secrets.JumpHosts = jumps
+	}
+
+	return secrets
+}
+
+// apply puts the secrets back into config
+func (s connectionSecrets) apply(config *ConnectionConfig) {
+	config.Password = s.Password
+	config.SSHPassword = s.SSHPassword
+	config.SSHPrivateKey = s.SSHPrivateKey
+	config.SSHPassphrase = s.SSHPassphrase
+	config.SSLClientKey = s.SSLClientKey
+
+	for i := range config.SSHJumpHosts {
+		if i >= len(s.JumpHosts) {
+			break
+		}
+		config.SSHJumpHosts[i].Password = s.JumpHosts[i].Password
+		config.SSHJumpHosts[i].PrivateKey = s.JumpHosts[i].PrivateKey
+		config.SSHJumpHosts[i].Passphrase = s.JumpHosts[i].Passphrase
+	}
+}
+
+// empty reports whether there is nothing to store
+func (s connectionSecrets) empty() bool {
+	return s.Password == "" && s.SSHPassword == "" && s.SSHPrivateKey == "" &&
+		s.SSHPassphrase == "" && s.SSLClientKey == "" && len(s.JumpHosts) == 0
+}
+
+// fieldNames lists the secret fields that are set, e.g. "password" or
+// "jumpHosts.0.privateKey"
+func (s connectionSecrets) fieldNames() []string {
+	var names []string
+	s.each(func(name string, value *string) {
+		if *value != "" {
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+// filter returns the secrets for which keep is true; the others are cleared
+func (s connectionSecrets) filter(keep func(name, value string) bool) connectionSecrets {
+	s.JumpHosts = append([]jumpHostSecrets(nil), s.JumpHosts...)
+	s.each(func(name string, value *string) {
+		if *value != "" && !keep(name, *value) {
+			*value = ""
+		}
+	})
+	return s
+}
+
+// each calls fn with the name and address of every secret field
+func (s *connectionSecrets) each(fn func(name string, value *string)) {
+	fn("password", &s.Password)
+	fn("sshPassword", &s.SSHPassword)
+	fn("sshPrivateKey", &s.SSHPrivateKey)
+	fn("sshPassphrase", &s.SSHPassphrase)
+	fn("sslClientKey", &s.SSLClientKey)
+	for i := range s.JumpHosts {
+		prefix := "jumpHosts." + strconv.Itoa(i) + "."
+		fn(prefix+"password", &s.JumpHosts[i].Password)
+		fn(prefix+"privateKey", &s.JumpHosts[i].PrivateKey)
+		fn(prefix+"passphrase", &s.JumpHosts[i].Passphrase)
+	}
+}
+
+// MissingSecretsError is returned when connecting to a saved connection
+// whose secrets were left out of an imported bundle and not entered since.
+// Fields are named as in SavedConnection.MissingSecrets.
+type MissingSecretsError struct {
+	Fields []string
+}
+
+func (e *MissingSecretsError) Error() string {
+	return fmt.Sprintf("missing secrets: %s", strings.Join(e.Fields, ", "))
+}
+
+// missingSecrets returns the fields of wanted that config has no value for
+func missingSecrets(config ConnectionConfig, wanted []string) []string {
+	set := make(map[string]bool)
+	for _, name := range extractSecrets(&config).fieldNames() {
+		set[name] = true
+	}
+
+	var missing []string
+	for _, name := range wanted {
+		if !set[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// saveSecrets stores the secrets for key, or removes them when there are none
+func saveSecrets(store SecretStore, key string, secrets connectionSecrets) error {
+	if secrets.empty() {
+		return store.Delete(key)
+	}
+
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	if err := store.Set(key, string(data)); err != nil {
+		return fmt.Errorf("failed to store secrets: %w", err)
+	}
+	return nil
+}
+
+// loadSecrets returns the secrets stored for key; a missing entry is not an error
+func loadSecrets(store SecretStore, key string) (connectionSecrets, error) {
+	var secrets connectionSecrets
+
+	data, err := store.Get(key)
+	if errors.Is(err, ErrSecretNotFound) {
+		return secrets, nil
+	}
+	if err != nil {
+		return secrets, fmt.Errorf("failed to read secrets: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(data), &secrets); err != nil {
+		return secrets, fmt.Errorf("failed to parse secrets: %w", err)
+	}
+	return secrets, nil
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// session is a single open connection: its own pool, driver and SSH tunnel
+type session struct {
+	id     string
+	db     *sql.DB
+	config *ConnectionConfig
+	driver Driver
+	tunnel *SSHTunnel
+	txs    map[string]*editorTx // Open manual-commit transactions by editor ID
+	mu     sync.RWMutex
+	closed bool // Set by close; the pool and tunnel are released once
+
+	confirmations map[string]pendingConfirmation // Tokens for destructive statements by token
+	lastChangeSet *appliedChangeSet              // Inverse of the last committed change set, for undo
+}
+
+// SessionInfo describes an open session for the frontend
+type SessionInfo struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Database string `json:"database"`
+	Color    string `json:"color"`
+	ReadOnly bool   `json:"readOnly"`
+	Active   bool   `json:"active"`
+}
+
+// getConfig returns the session's connection config
+func (s *session) getConfig() *ConnectionConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// setDatabase records the database the session is currently using
+func (s *session) setDatabase(database string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config != nil {
+		s.config.Database = database
+	}
+}
+
+// close rolls back any open transactions and releases the session's pool and
+// SSH tunnel. The pool and tunnel are taken under s.mu and closed after it is
+// released; s.db itself is never reset, since statements already running on
+// it read it without the lock and fail cleanly once the pool is closed.
+func (s *session) close() error {
+	var errs []error
+
+	s.mu.Lock()
+	txs := s.txs
+	s.txs = make(map[string]*editorTx)
+	db, tunnel := s.db, s.tunnel
+	if s.closed {
+		db, tunnel = nil, nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	for _, et := range txs {
+		if err := et.finish(false); err != nil {
+			errs = append(errs, fmt.Errorf("failed to roll back open transaction: %w", err))
+		}
+	}
+
+	if db != nil {
+		if err := db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if tunnel != nil {
+		if err := tunnel.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ListSessions returns all open sessions ordered by ID
+func (m *Manager) ListSessions() []SessionInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]SessionInfo, 0, len(m.sessions))
+	for id, s := range m.sessions {
+		info := SessionInfo{
+			ID:     id,
+			Active: id == m.activeID,
+		}
+		if config := s.getConfig(); config != nil {
+			info.Type = config.Type
+			info.Host = config.Host
+			info.Port = config.Port
+			info.User = config.User
+			info.Database = config.Database
+			info.Color = config.Color
+			info.ReadOnly = config.ReadOnly
+		}
+		sessions = append(sessions, info)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions
+}
+
+// ActivateSession makes connID the active session
+func (m *Manager) ActivateSession(connID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[connID]; !ok {
+		return fmt.Errorf("session not found: %s", connID)
+	}
+	m.activeID = connID
+	return nil
+}
+
+// ActiveSession returns the ID of the active session, or "" if none
+func (m *Manager) ActiveSession() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.activeID
+}
@@ -0,0 +1,361 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer stands in for a MySQL server behind database/sql, so sessions
+// can be tested without a database to connect to. It understands:
+//
+//	SELECT CONNECTION_ID()  the ID of the connection
+//	SELECT <n>              n rows of one column "n" counting from 1
+//	SLEEP                   blocks until KILL QUERY or cancellation
+//	KILL QUERY <id>         stops the SLEEP running on connection id
+//
+// Any other statement affects one row and is logged, as are BEGIN, COMMIT
+// and ROLLBACK. Like the MySQL driver, cancelling a statement's context
+// while it runs or while its rows are open breaks the connection.
+type fakeServer struct {
+	mu       sync.Mutex
+	nextID   int64
+	open     int                     // Connections not yet closed
+	sleeping map[int64]chan struct{} // Running SLEEP statements by connection ID
+	log      []string
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{sleeping: make(map[int64]chan struct{})}
+}
+
+// db opens a pool on the server
+func (fs *fakeServer) db() *sql.DB {
+	return sql.OpenDB(fakeConnector{fs})
+}
+
+// record logs a statement the server ran
+func (fs *fakeServer) record(stmt string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.log = append(fs.log, stmt)
+}
+
+// statements returns the logged statements
+func (fs *fakeServer) statements() []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]string(nil), fs.log...)
+}
+
+// openConns returns the number of connections not yet closed
+func (fs *fakeServer) openConns() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.open
+}
+
+// waitSleeping waits until a SLEEP statement is running
+func (fs *fakeServer) waitSleeping(t *testing.T) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		fs.mu.Lock()
+		n := len(fs.sleeping)
+		fs.mu.Unlock()
+		if n > 0 {
+			return
+		}
+	}
+	t.Fatal("statement never started")
+}
+
+type fakeConnector struct {
+	server *fakeServer
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	c.server.nextID++
+	c.server.open++
+	return &fakeConn{server: c.server, id: c.server.nextID}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	server *fakeServer
+	id     int64
+	broken bool // Guarded by server.mu
+}
+
+func (c *fakeConn) isBroken() bool {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	return c.broken
+}
+
+func (c *fakeConn) breakConn() {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	c.broken = true
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	c.server.open--
+	return nil
+}
+
+func (c *fakeConn) IsValid() bool {
+	return !c.isBroken()
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.isBroken() {
+		return nil, driver.ErrBadConn
+	}
+	c.server.record("BEGIN")
+	return fakeTx{c}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.isBroken() {
+		return nil, driver.ErrBadConn
+	}
+	if query == "SLEEP" {
+		return nil, c.sleep(ctx)
+	}
+	if rest, ok := strings.CutPrefix(query, "KILL QUERY "); ok {
+		id, _ := strconv.ParseInt(rest, 10, 64)
+		c.server.mu.Lock()
+		if done, ok := c.server.sleeping[id]; ok {
+			close(done)
+			delete(c.server.sleeping, id)
+		}
+		c.server.mu.Unlock()
+	}
+	c.server.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.isBroken() {
+		return nil, driver.ErrBadConn
+	}
+	switch {
+	case query == "SLEEP":
+		return nil, c.sleep(ctx)
+	case query == "SELECT CONNECTION_ID()":
+		return &fakeRows{conn: c, ctx: ctx, column: "CONNECTION_ID()", values: []int64{c.id}}, nil
+	case strings.HasPrefix(query, "SELECT "):
+		n, err := strconv.Atoi(strings.TrimPrefix(query, "SELECT "))
+		if err != nil {
+			return nil, fmt.Errorf("unknown query: %s", query)
+		}
+		rows := &fakeRows{conn: c, ctx: ctx, column: "n"}
+		for i := 1; i <= n; i++ {
+			rows.values = append(rows.values, int64(i))
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unknown query: %s", query)
+}
+
+// sleep blocks until the statement is killed or ctx is cancelled
+func (c *fakeConn) sleep(ctx context.Context) error {
+	done := make(chan struct{})
+	c.server.mu.Lock()
+	c.server.sleeping[c.id] = done
+	c.server.mu.Unlock()
+
+	select {
+	case <-done:
+		return errors.New("query execution was interrupted")
+	case <-ctx.Done():
+		c.server.mu.Lock()
+		delete(c.server.sleeping, c.id)
+		c.server.mu.Unlock()
+		c.breakConn()
+		return ctx.Err()
+	}
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx fakeTx) Commit() error {
+	if tx.conn.isBroken() {
+		return errors.New("invalid connection")
+	}
+	tx.conn.server.record("COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	if tx.conn.isBroken() {
+		return errors.New("invalid connection")
+	}
+	tx.conn.server.record("ROLLBACK")
+	return nil
+}
+
+type fakeRows struct {
+	conn   *fakeConn
+	ctx    context.Context
+	column string
+	values []int64
+	next   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{r.column}
+}
+
+func (r *fakeRows) Close() error {
+	// Rows closed after their statement was cancelled cost the connection
+	if r.ctx.Err() != nil {
+		r.conn.breakConn()
+	}
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if err := r.ctx.Err(); err != nil {
+		r.conn.breakConn()
+		return err
+	}
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	dest[0] = r.values[r.next]
+	r.next++
+	return nil
+}
+
+// addFakeSession registers a session on a new fake server under id
+func addFakeSession(t *testing.T, m *Manager, id string, config ConnectionConfig) (*session, *fakeServer) {
+	t.Helper()
+	server := newFakeServer()
+	s := &session{
+		id:            id,
+		db:            server.db(),
+		config:        &config,
+		driver:        &MySQLDriver{},
+		txs:           make(map[string]*editorTx),
+		confirmations: make(map[string]pendingConfirmation),
+	}
+	m.mu.Lock()
+	m.sessions[id] = s
+	m.activeID = id
+	m.mu.Unlock()
+	t.Cleanup(func() { s.close() })
+	return s, server
+}
+
+func TestSessions(t *testing.T) {
+	m := NewManager()
+	_, serverA := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql", Host: "db-a", Database: "shop"})
+	addFakeSession(t, m, "b", ConnectionConfig{Type: "mysql", Host: "db-b", Color: "#22c55e"})
+
+	want := []SessionInfo{
+		{ID: "a", Type: "mysql", Host: "db-a", Database: "shop"},
+		{ID: "b", Type: "mysql", Host: "db-b", Color: "#22c55e", Active: true},
+	}
+	if got := m.ListSessions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSessions = %+v, want %+v", got, want)
+	}
+
+	if err := m.ActivateSession("a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.ActiveSession(); got != "a" {
+		t.Errorf("active session = %q, want a", got)
+	}
+	if err := m.ActivateSession("c"); err == nil {
+		t.Error("activating an unknown session succeeded")
+	}
+
+	tests := []struct {
+		connID   string
+		wantHost string
+	}{
+		{connID: "", wantHost: "db-a"}, // The active session
+		{connID: "a", wantHost: "db-a"},
+		{connID: "b", wantHost: "db-b"},
+	}
+	for _, tt := range tests {
+		if config := m.GetCurrentConfig(tt.connID); config == nil || config.Host != tt.wantHost {
+			t.Errorf("GetCurrentConfig(%q) = %+v, want host %s", tt.connID, config, tt.wantHost)
+		}
+	}
+
+	s, err := m.getSession("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Disconnect("a"); err != nil {
+		t.Fatal(err)
+	}
+	if m.IsConnected("a") || !m.IsConnected("b") {
+		t.Errorf("connected after disconnecting a: a=%v b=%v", m.IsConnected("a"), m.IsConnected("b"))
+	}
+	if m.ActiveSession() != "" {
+		t.Errorf("active session = %q after disconnecting it", m.ActiveSession())
+	}
+	if n := serverA.openConns(); n != 0 {
+		t.Errorf("%d connections of a still open", n)
+	}
+	if _, err := m.getSession(""); err == nil {
+		t.Error("no active session, but getSession found one")
+	}
+}
+
+func TestSessionCloseWhileQuerying(t *testing.T) {
+	m := NewManager()
+	s, _ := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				// Fails once the pool is closed, but must not race with close
+				s.db.Ping()
+			}
+		}()
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.close(); err != nil {
+		t.Fatalf("closing twice: %v", err)
+	}
+	wg.Wait()
+}
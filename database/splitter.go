@@ -0,0 +1,387 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ScriptStatement is a single statement cut out of a script
+type ScriptStatement struct {
+	Text string `json:"text"`
+	Line int    `json:"line"` // 1-based line where the statement starts
+}
+
+// splitOptions describes the lexical rules of a dialect that matter when
+// cutting a script into statements
+type splitOptions struct {
+	mysqlComments    bool // '#' line comments, and '--' only when followed by whitespace
+	backslashEscapes bool // Backslash escapes inside quoted strings
+	backticks        bool // `quoted identifiers`
+	delimiterCommand bool // Client-side DELIMITER command changes the terminator
+	dollarQuotes     bool // $tag$ ... $tag$ string bodies
+	triggerBodies    bool // CREATE TRIGGER ... BEGIN ... END bodies contain delimiters
+}
+
+// triggerStart detects SQLite trigger bodies, whose inner statements end
+// with the delimiter too
+var triggerStart = regexp.MustCompile(`(?is)^\s*CREATE\s+(TEMP\s+|TEMPORARY\s+)?TRIGGER\b`)
+
+// splitStatements cuts a script into statements on the current delimiter,
+// ignoring delimiters inside strings, quoted identifiers, comments and
+// dollar-quoted bodies. Statements that contain only comments are dropped.
+func splitStatements(script string, opts splitOptions) []ScriptStatement {
+	var statements []ScriptStatement
+	var buf strings.Builder
+
+	delimiter := ";"
+	hasContent := false
+	line := 1
+	startLine := 1
+	i := 0
+	n := len(script)
+
+	// copyTo appends script[i:j] to the current statement and advances i
+	copyTo := func(j int) {
+		if j > n {
+			j = n
+		}
+		chunk := script[i:j]
+		line += strings.Count(chunk, "\n")
+		buf.WriteString(chunk)
+		i = j
+	}
+
+	emit := func() {
+		text := strings.TrimSpace(buf.String())
+		if hasContent && text != "" {
+			statements = append(statements, ScriptStatement{Text: text, Line: startLine})
+		}
+		buf.Reset()
+		hasContent = false
+	}
+
+	markContent := func() {
+		if !hasContent {
+			hasContent = true
+			startLine = line
+		}
+	}
+
+	for i < n {
+		c := script[i]
+
+		// DELIMITER is only recognised at the start of a statement
+		if opts.delimiterCommand && !hasContent && isDelimiterCommand(script[i:]) {
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			fields := strings.Fields(script[i : i+end])
+			if len(fields) > 1 {
+				delimiter = fields[1]
+			}
+			line += strings.Count(script[i:i+end], "\n")
+			i += end
+			buf.Reset()
+			continue
+		}
+
+		if strings.HasPrefix(script[i:], delimiter) {
+			if opts.triggerBodies && inTriggerBody(buf.String(), opts) {
+				copyTo(i + len(delimiter))
+				continue
+			}
+			line += strings.Count(delimiter, "\n")
+			i += len(delimiter)
+			emit()
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || (c == '`' && opts.backticks):
+			markContent()
+			copyTo(quotedEnd(script, i, opts.backslashEscapes && c != '`'))
+
+		case c == '-' && i+1 < n && script[i+1] == '-' &&
+			(!opts.mysqlComments || i+2 >= n || isSpace(script[i+2])):
+			copyTo(lineEnd(script, i))
+
+		case c == '#' && opts.mysqlComments:
+			copyTo(lineEnd(script, i))
+
+		case c == '/' && i+1 < n && script[i+1] == '*':
+			// MySQL executable comments (/*! ... */) carry real SQL
+			if i+2 < n && script[i+2] == '!' {
+				markContent()
+			}
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				copyTo(n)
+			} else {
+				copyTo(i + 2 + end + 2)
+			}
+
+		case c == '$' && opts.dollarQuotes && (i == 0 || !isIdentChar(script[i-1])):
+			tag, ok := dollarTag(script[i:])
+			if !ok {
+				markContent()
+				copyTo(i + 1)
+				break
+			}
+			markContent()
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				copyTo(n)
+			} else {
+				copyTo(i + len(tag) + end + len(tag))
+			}
+
+		default:
+			if !isSpace(c) {
+				markContent()
+			}
+			copyTo(i + 1)
+		}
+	}
+
+	emit()
+	return statements
+}
+
+// inTriggerBody reports whether a delimiter after stmt falls inside a
+// CREATE TRIGGER body rather than ending the statement. The body ends at the
+// END matching its BEGIN; CASE expressions inside it have an END of their own.
+func inTriggerBody(stmt string, opts splitOptions) bool {
+	stmt = stripLeadingComments(stmt, opts.mysqlComments)
+	if !triggerStart.MatchString(stmt) {
+		return false
+	}
+
+	depth := 0
+	i := 0
+	n := len(stmt)
+	for i < n {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || (c == '`' && opts.backticks):
+			i = quotedEnd(stmt, i, opts.backslashEscapes && c != '`')
+
+		case strings.HasPrefix(stmt[i:], "--") || (c == '#' && opts.mysqlComments):
+			i = lineEnd(stmt, i)
+
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return depth > 0
+			}
+			i += 2 + end + 2
+
+		case isIdentChar(c):
+			j := i
+			for j < n && isIdentChar(stmt[j]) {
+				j++
+			}
+			switch strings.ToUpper(stmt[i:j]) {
+			case "BEGIN", "CASE":
+				depth++
+			case "END":
+				if depth > 0 {
+					depth--
+				}
+			}
+			i = j
+
+		default:
+			i++
+		}
+	}
+	return depth > 0
+}
+
+// stripLeadingComments removes whitespace and comments before the first
+// token. mysqlComments also treats # as starting a line comment.
+func stripLeadingComments(stmt string, mysqlComments bool) string {
+	i := 0
+	n := len(stmt)
+	for i < n {
+		switch {
+		case isSpace(stmt[i]):
+			i++
+		case strings.HasPrefix(stmt[i:], "--") || (stmt[i] == '#' && mysqlComments):
+			i = lineEnd(stmt, i)
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return ""
+			}
+			i += 2 + end + 2
+		default:
+			return stmt[i:]
+		}
+	}
+	return ""
+}
+
+// isDelimiterCommand reports whether s starts with a DELIMITER command
+func isDelimiterCommand(s string) bool {
+	const kw = "DELIMITER"
+	if len(s) <= len(kw) || !strings.EqualFold(s[:len(kw)], kw) {
+		return false
+	}
+	return s[len(kw)] == ' ' || s[len(kw)] == '\t'
+}
+
+// quotedEnd returns the index just past the quoted literal starting at i.
+// A doubled quote character is treated as an escaped quote.
+func quotedEnd(s string, i int, backslashEscapes bool) int {
+	q := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslashEscapes && s[j] == '\\':
+			j++
+		case s[j] == q:
+			if j+1 < len(s) && s[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// lineEnd returns the index of the newline ending the line at i, or len(s)
+func lineEnd(s string, i int) int {
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return len(s)
+	}
+	return i + end
+}
+
+// dollarTag returns the opening $tag$ at the start of s, if any
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		if c == '$' {
+			return s[:j+1], true
+		}
+		if !isIdentChar(c) || (j == 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// leadingKeyword returns the first keyword of a statement in upper case,
+// skipping whitespace, comments and opening parentheses. It does not know
+// the dialect, so # comments are skipped too; no other dialect can start a
+// statement with #.
+func leadingKeyword(stmt string) string {
+	stmt = stripLeadingComments(stmt, true)
+	for strings.HasPrefix(stmt, "(") {
+		stmt = stripLeadingComments(stmt[1:], true)
+	}
+
+	j := 0
+	for j < len(stmt) && isIdentChar(stmt[j]) && stmt[j] != '$' {
+		j++
+	}
+	return strings.ToUpper(stmt[:j])
+}
+
+// hasTopLevelKeyword reports whether keyword appears in stmt outside string
+// literals, quoted identifiers, comments and parentheses. mysqlComments also
+// treats # as starting a line comment; elsewhere it is an operator, as in
+// Postgres' #- and #>.
+func hasTopLevelKeyword(stmt, keyword string, mysqlComments bool) bool {
+	found := false
+	eachTopLevelWord(stmt, mysqlComments, func(word string) bool {
+		found = strings.EqualFold(word, keyword)
+		return !found
+	})
+	return found
+}
+
+// eachTopLevelWord calls fn with the words of stmt outside string literals,
+// quoted identifiers, comments and parentheses until fn returns false
+func eachTopLevelWord(stmt string, mysqlComments bool, fn func(word string) bool) {
+	depth := 0
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = quotedEnd(stmt, i, c == '\'')
+		case strings.HasPrefix(stmt[i:], "--") || (c == '#' && mysqlComments):
+			i = lineEnd(stmt, i)
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += 2 + end + 2
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case isIdentChar(c):
+			j := i
+			for j < len(stmt) && isIdentChar(stmt[j]) {
+				j++
+			}
+			if depth == 0 && !fn(stmt[i:j]) {
+				return
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+}
+
+// cteStatements are the statements a WITH clause can belong to
+var cteStatements = map[string]bool{
+	"SELECT":  true,
+	"INSERT":  true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"REPLACE": true,
+	"MERGE":   true,
+	"VALUES":  true,
+	"TABLE":   true,
+}
+
+// statementKeyword is leadingKeyword looking past a WITH clause, so
+// WITH x AS (...) DELETE FROM t is a DELETE. The common table expressions
+// are in parentheses, which leaves the statement they belong to as the first
+// such keyword at the top level. A WITH clause without one stays WITH.
+func statementKeyword(stmt string, mysqlComments bool) string {
+	keyword := leadingKeyword(stmt)
+	if keyword != "WITH" {
+		return keyword
+	}
+	eachTopLevelWord(stmt, mysqlComments, func(word string) bool {
+		if upper := strings.ToUpper(word); cteStatements[upper] {
+			keyword = upper
+			return false
+		}
+		return true
+	})
+	return keyword
+}
+
+// hashComments reports whether the dialect of d starts line comments with #
+func hashComments(d Driver) bool {
+	_, ok := d.(*MySQLDriver)
+	return ok
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver Driver
+		script string
+		want   []ScriptStatement
+	}{
+		{
+			name:   "simple",
+			driver: &PostgresDriver{},
+			script: "SELECT 1;\nSELECT 2;",
+			want:   []ScriptStatement{{Text: "SELECT 1", Line: 1}, {Text: "SELECT 2", Line: 2}},
+		},
+		{
+			name:   "no trailing delimiter",
+			driver: &PostgresDriver{},
+			script: "SELECT 1;\n\n  SELECT 2",
+			want:   []ScriptStatement{{Text: "SELECT 1", Line: 1}, {Text: "SELECT 2", Line: 3}},
+		},
+		{
+			name:   "delimiter in strings and identifiers",
+			driver: &PostgresDriver{},
+			script: `SELECT 'a;b', "c;d"; SELECT 'it''s;'`,
+			want:   []ScriptStatement{{Text: `SELECT 'a;b', "c;d"`, Line: 1}, {Text: `SELECT 'it''s;'`, Line: 1}},
+		},
+		{
+			name:   "comment only statements are dropped",
+			driver: &PostgresDriver{},
+			script: "-- header;\n/* block; */\nSELECT 1;\n-- trailer",
+			want:   []ScriptStatement{{Text: "-- header;\n/* block; */\nSELECT 1", Line: 3}},
+		},
+		{
+			name:   "dollar quoted body",
+			driver: &PostgresDriver{},
+			script: "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END $body$ LANGUAGE plpgsql;\nSELECT f();",
+			want: []ScriptStatement{
+				{Text: "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END $body$ LANGUAGE plpgsql", Line: 1},
+				{Text: "SELECT f()", Line: 2},
+			},
+		},
+		{
+			name:   "positional parameter is not a dollar quote",
+			driver: &PostgresDriver{},
+			script: "SELECT $1; SELECT 2",
+			want:   []ScriptStatement{{Text: "SELECT $1", Line: 1}, {Text: "SELECT 2", Line: 1}},
+		},
+		{
+			name:   "hash is not a comment on Postgres",
+			driver: &PostgresDriver{},
+			script: "SELECT 5 # 3; SELECT 2",
+			want:   []ScriptStatement{{Text: "SELECT 5 # 3", Line: 1}, {Text: "SELECT 2", Line: 1}},
+		},
+		{
+			name:   "MySQL hash comment",
+			driver: &MySQLDriver{},
+			script: "# note; here\nSELECT 1;",
+			want:   []ScriptStatement{{Text: "# note; here\nSELECT 1", Line: 2}},
+		},
+		{
+			name:   "MySQL double dash needs whitespace",
+			driver: &MySQLDriver{},
+			script: "SELECT 1--1; SELECT 2",
+			want:   []ScriptStatement{{Text: "SELECT 1--1", Line: 1}, {Text: "SELECT 2", Line: 1}},
+		},
+		{
+			name:   "MySQL backslash escapes",
+			driver: &MySQLDriver{},
+			script: `SELECT 'a\';b'; SELECT 2`,
+			want:   []ScriptStatement{{Text: `SELECT 'a\';b'`, Line: 1}, {Text: "SELECT 2", Line: 1}},
+		},
+		{
+			name:   "MySQL executable comment",
+			driver: &MySQLDriver{},
+			script: "/*!40101 SET NAMES utf8 */;\nSELECT 1;",
+			want:   []ScriptStatement{{Text: "/*!40101 SET NAMES utf8 */", Line: 1}, {Text: "SELECT 1", Line: 2}},
+		},
+		{
+			name:   "MySQL DELIMITER command",
+			driver: &MySQLDriver{},
+			script: "DELIMITER $$\nCREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END$$\nDELIMITER ;\nCALL p();",
+			want: []ScriptStatement{
+				{Text: "CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END", Line: 2},
+				{Text: "CALL p()", Line: 4},
+			},
+		},
+		{
+			name:   "SQLite trigger body",
+			driver: &SQLiteDriver{},
+			script: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\n  INSERT INTO b VALUES (1);\n  DELETE FROM c;\nEND;\nSELECT 1;",
+			want: []ScriptStatement{
+				{Text: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\n  INSERT INTO b VALUES (1);\n  DELETE FROM c;\nEND", Line: 1},
+				{Text: "SELECT 1", Line: 5},
+			},
+		},
+		{
+			name:   "SQLite trigger with CASE",
+			driver: &SQLiteDriver{},
+			script: "CREATE TRIGGER t AFTER UPDATE ON a BEGIN UPDATE b SET x = CASE WHEN new.y THEN 1 ELSE 0 END; DELETE FROM c; END; SELECT 1",
+			want: []ScriptStatement{
+				{Text: "CREATE TRIGGER t AFTER UPDATE ON a BEGIN UPDATE b SET x = CASE WHEN new.y THEN 1 ELSE 0 END; DELETE FROM c; END", Line: 1},
+				{Text: "SELECT 1", Line: 1},
+			},
+		},
+		{
+			name:   "SQLite trigger with END in a string",
+			driver: &SQLiteDriver{},
+			script: "CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN INSERT INTO log VALUES ('end'); END; SELECT 1",
+			want: []ScriptStatement{
+				{Text: "CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN INSERT INTO log VALUES ('end'); END", Line: 1},
+				{Text: "SELECT 1", Line: 1},
+			},
+		},
+		{
+			name:   "SQLite trigger after a comment",
+			driver: &SQLiteDriver{},
+			script: "-- audit\nCREATE TRIGGER t AFTER DELETE ON a BEGIN DELETE FROM b; END;",
+			want: []ScriptStatement{
+				{Text: "-- audit\nCREATE TRIGGER t AFTER DELETE ON a BEGIN DELETE FROM b; END", Line: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.driver.SplitStatements(tt.script)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements(%q)\n got %#v\nwant %#v", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeadingKeyword(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"select 1", "SELECT"},
+		{"  -- note\n/* x */ DELETE FROM t", "DELETE"},
+		{"# note\nDROP TABLE t", "DROP"},
+		{"((SELECT 1) UNION (SELECT 2))", "SELECT"},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "WITH"},
+		{"/* unterminated", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := leadingKeyword(tt.stmt); got != tt.want {
+			t.Errorf("leadingKeyword(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestHasTopLevelKeyword(t *testing.T) {
+	tests := []struct {
+		stmt          string
+		mysqlComments bool
+		want          bool
+	}{
+		{stmt: "DELETE FROM t WHERE id = 1", want: true},
+		{stmt: "delete from t where id = 1", want: true},
+		{stmt: "DELETE FROM t"},
+		{stmt: "DELETE FROM t -- WHERE id = 1"},
+		{stmt: "DELETE FROM t /* WHERE */"},
+		{stmt: "DELETE FROM t WHERE_x = 1"},
+		{stmt: "DELETE FROM \"where\""},
+		{stmt: "DELETE FROM t USING (SELECT id FROM u WHERE x) s"},
+		{stmt: "UPDATE t SET note = 'where'"},
+		{stmt: "UPDATE t SET a = 1 # WHERE id = 1", mysqlComments: true},
+		// In Postgres # is an operator, so what follows is still code
+		{stmt: "UPDATE t SET doc = doc #- '{a}' WHERE id = 1", want: true},
+		{stmt: "UPDATE t SET doc = doc #> '{a}' WHERE id = 1", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := hasTopLevelKeyword(tt.stmt, "WHERE", tt.mysqlComments); got != tt.want {
+			t.Errorf("hasTopLevelKeyword(%q, WHERE, %v) = %v, want %v", tt.stmt, tt.mysqlComments, got, tt.want)
+		}
+	}
+}
+
+func TestStatementKeyword(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"SELECT 1", "SELECT"},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "SELECT"},
+		{"WITH x AS (SELECT id FROM t) DELETE FROM t WHERE id IN (SELECT id FROM x)", "DELETE"},
+		{"WITH RECURSIVE x(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM x) UPDATE t SET n = 1", "UPDATE"},
+		{"WITH x AS (SELECT 1)", "WITH"},
+		{"  -- note\nINSERT INTO t VALUES (1)", "INSERT"},
+	}
+
+	for _, tt := range tests {
+		if got := statementKeyword(tt.stmt, false); got != tt.want {
+			t.Errorf("statementKeyword(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,774 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+type SQLiteDriver struct{}
+
+func (d *SQLiteDriver) Connect(ctx context.Context, config ConnectionConfig) (*sql.DB, error) {
+	path, err := expandHome(config.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("no database file provided")
+	}
+
+	// sql.Open would silently create a missing file; only do that when writable
+	if config.FileReadOnly {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("failed to open database file: %w", err)
+		}
+	}
+
+	dsn := d.buildDSN(path, config)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	if len(config.InitSQL) > 0 {
+		// The driver has no connector of its own to wrap
+		drv := db.Driver()
+		db.Close()
+		db = sql.OpenDB(withInitSQL(&dsnConnector{dsn: dsn, drv: drv}, config.InitSQL))
+	}
+	applyPoolSettings(db, config)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	return db, nil
+}
+
+// buildDSN constructs a SQLite URI filename with connection pragmas
+func (d *SQLiteDriver) buildDSN(path string, config ConnectionConfig) string {
+	escaped := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(filepath.ToSlash(path))
+
+	params := []string{
+		"_pragma=foreign_keys(1)",
+		"_pragma=busy_timeout(5000)",
+	}
+	if config.FileReadOnly {
+		params = append(params, "mode=ro")
+	}
+	if config.ReadOnly {
+		params = append(params, "_pragma=query_only(1)")
+	}
+	for _, key := range extraParamKeys(config, "mode") {
+		params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(config.ExtraParams[key]))
+	}
+
+	return fmt.Sprintf("file:%s?%s", escaped, strings.Join(params, "&"))
+}
+
+// expandHome resolves a leading ~ to the user's home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, path[1:]), nil
+}
+
+// schemaName returns the attached schema to use, defaulting to main
+func (d *SQLiteDriver) schemaName(database string) string {
+	if database == "" {
+		return "main"
+	}
+	return database
+}
+
+// QualifyTable returns a schema-qualified, quoted table name
+func (d *SQLiteDriver) QualifyTable(database, table string) string {
+	return d.QuoteIdentifier(d.schemaName(database)) + "." + d.QuoteIdentifier(table)
+}
+
+func (d *SQLiteDriver) GetDatabases(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_database_list WHERE name <> 'temp' ORDER BY seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var databases []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		databases = append(databases, name)
+	}
+	return databases, nil
+}
+
+func (d *SQLiteDriver) GetSchemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	// Each attached database is a schema
+	return d.GetDatabases(ctx, db)
+}
+
+func (d *SQLiteDriver) GetTables(ctx context.Context, db *sql.DB, database string) ([]TableInfo, error) {
+	query := fmt.Sprintf(`
+		SELECT name, type
+		FROM %s.sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%%'
+		ORDER BY name
+	`, d.QuoteIdentifier(d.schemaName(database)))
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []TableInfo
+	for rows.Next() {
+		var t TableInfo
+		if err := rows.Scan(&t.Name, &t.Engine); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+func (d *SQLiteDriver) GetColumns(ctx context.Context, db *sql.DB, database, table string) ([]ColumnInfo, error) {
+	schema := d.schemaName(database)
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?, ?) ORDER BY cid`,
+		table, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	pkCount := 0
+	for rows.Next() {
+		var c ColumnInfo
+		var notNull, pk int
+		var defaultVal sql.NullString
+		if err := rows.Scan(&c.Name, &c.Type, &notNull, &defaultVal, &pk); err != nil {
+			return nil, err
+		}
+		c.Nullable = notNull == 0 && pk == 0
+		c.Default = sqliteUnquoteDefault(defaultVal.String)
+		if pk > 0 {
+			c.Key = "PRI"
+			pkCount++
+		}
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A single INTEGER PRIMARY KEY aliases the rowid and is auto-assigned
+	if pkCount == 1 {
+		for i := range columns {
+			if columns[i].Key == "PRI" && strings.EqualFold(columns[i].Type, "INTEGER") {
+				columns[i].Extra = "auto_increment"
+			}
+		}
+	}
+
+	indexes, err := d.GetIndexes(ctx, db, database, table)
+	if err != nil {
+		return nil, err
+	}
+	applyIndexKeys(columns, indexes)
+
+	return columns, nil
+}
+
+func (d *SQLiteDriver) GetIndexes(ctx context.Context, db *sql.DB, database, table string) ([]IndexInfo, error) {
+	schema := d.schemaName(database)
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT name, "unique", origin FROM pragma_index_list(?, ?) ORDER BY seq`,
+		table, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []IndexInfo
+	hasPrimary := false
+	for rows.Next() {
+		var idx IndexInfo
+		var unique int
+		var origin string
+		if err := rows.Scan(&idx.Name, &unique, &origin); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		idx.IsUnique = unique == 1
+		idx.IsPrimary = origin == "pk"
+		if idx.IsPrimary {
+			hasPrimary = true
+		}
+		indexes = append(indexes, idx)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		cols, err := d.indexColumns(ctx, db, schema, indexes[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i].Columns = cols
+	}
+
+	// Rowid tables have no index backing an INTEGER PRIMARY KEY
+	if !hasPrimary {
+		pkRows, err := db.QueryContext(ctx,
+			`SELECT name FROM pragma_table_info(?, ?) WHERE pk > 0 ORDER BY pk`,
+			table, schema)
+		if err != nil {
+			return nil, err
+		}
+		defer pkRows.Close()
+
+		var pkCols []string
+		for pkRows.Next() {
+			var name string
+			if err := pkRows.Scan(&name); err != nil {
+				return nil, err
+			}
+			pkCols = append(pkCols, name)
+		}
+		if len(pkCols) > 0 {
+			indexes = append([]IndexInfo{{
+				Name:      "PRIMARY",
+				Columns:   pkCols,
+				IsUnique:  true,
+				IsPrimary: true,
+			}}, indexes...)
+		}
+	}
+
+	return indexes, nil
+}
+
+// indexColumns returns the columns of an index in key order
+func (d *SQLiteDriver) indexColumns(ctx context.Context, db *sql.DB, schema, index string) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT name FROM pragma_index_info(?, ?) ORDER BY seqno`,
+		index, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := []string{}
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		// Expression indexes have no column name
+		if name.Valid {
+			cols = append(cols, name.String)
+		}
+	}
+	return cols, rows.Err()
+}
+
+func (d *SQLiteDriver) BuildTableDataQuery(req TableDataRequest, key RowIdentity, where string) string {
+	if where != "" {
+		where = " WHERE " + where
+	}
+
+	selectList, orderBy := tableDataClauses(d, req, key)
+	query := fmt.Sprintf("SELECT %s FROM %s%s%s", selectList, d.QualifyTable(req.Database, req.Table), where, orderBy)
+
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	offset := (req.Page - 1) * pageSize
+	query += fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, offset)
+
+	return query
+}
+
+func (d *SQLiteDriver) BuildCountQuery(database, table, where string) string {
+	if where != "" {
+		where = " WHERE " + where
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s%s", d.QualifyTable(database, table), where)
+}
+
+func (d *SQLiteDriver) BuildAlterTableQuery(database, table string, current *TableDetails, alteration TableAlteration) ([]string, []string, error) {
+	rebuild, err := d.needsRebuild(current, alteration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rebuild {
+		statements, err := d.buildRebuildTableQuery(database, table, current, alteration)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Must be toggled outside the transaction; the DSN always enables it
+		return statements, []string{"PRAGMA foreign_keys = ON"}, nil
+	}
+
+	var statements []string
+	qualified := d.QualifyTable(database, table)
+
+	// Rename table if requested
+	if alteration.RenameTo != "" && alteration.RenameTo != table {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", qualified, d.QuoteIdentifier(alteration.RenameTo)))
+		qualified = d.QualifyTable(database, alteration.RenameTo)
+	}
+
+	// Renames are the only column modifications SQLite can apply in place
+	for _, col := range alteration.ModifyColumns {
+		if col.OldName != "" && col.OldName != col.Name {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+				qualified, d.QuoteIdentifier(col.OldName), d.QuoteIdentifier(col.Name)))
+		}
+	}
+
+	// Drop columns
+	for _, col := range alteration.DropColumns {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", qualified, d.QuoteIdentifier(col)))
+	}
+
+	// Add columns
+	for _, col := range alteration.AddColumns {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", qualified, d.columnDefinition(col)))
+	}
+
+	return statements, nil, nil
+}
+
+// needsRebuild reports whether an alteration changes a column's type,
+// nullability or default, which SQLite's ALTER TABLE cannot do
+func (d *SQLiteDriver) needsRebuild(current *TableDetails, alteration TableAlteration) (bool, error) {
+	for _, col := range alteration.ModifyColumns {
+		existing := findColumn(current.Columns, columnSourceName(col))
+		if existing == nil {
+			return false, fmt.Errorf("column not found: %s", columnSourceName(col))
+		}
+		if !strings.EqualFold(existing.Type, col.Type) || existing.Nullable != col.Nullable || existing.Default != col.Default {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// buildRebuildTableQuery emulates ALTER TABLE by creating a new table with the
+// altered definition, copying the rows across, dropping the original and
+// recreating its indexes, foreign keys and triggers. Tables with CHECK
+// constraints are refused, since they can't be carried over. The statements
+// turn foreign key enforcement off; the caller turns it back on.
+func (d *SQLiteDriver) buildRebuildTableQuery(database, table string, current *TableDetails, alteration TableAlteration) ([]string, error) {
+	if current.hasChecks {
+		return nil, fmt.Errorf("cannot change column types, nullability or defaults of %s: it has CHECK constraints, which rebuilding the table would lose", table)
+	}
+
+	dropped := make(map[string]bool)
+	for _, name := range alteration.DropColumns {
+		dropped[name] = true
+	}
+	modified := make(map[string]ColumnInfo)
+	for _, col := range alteration.ModifyColumns {
+		modified[columnSourceName(col)] = col
+	}
+
+	// Work out the new column list and where each column's data comes from
+	var definitions, targetCols, sourceCols, pkCols []string
+	renamed := make(map[string]string)
+	for _, col := range current.Columns {
+		if dropped[col.Name] {
+			continue
+		}
+		next := col
+		if m, ok := modified[col.Name]; ok {
+			next = m
+		}
+		renamed[col.Name] = next.Name
+
+		definitions = append(definitions, d.columnDefinition(next))
+		targetCols = append(targetCols, d.QuoteIdentifier(next.Name))
+		sourceCols = append(sourceCols, d.QuoteIdentifier(col.Name))
+		if col.Key == "PRI" {
+			pkCols = append(pkCols, d.QuoteIdentifier(next.Name))
+		}
+	}
+	for _, col := range alteration.AddColumns {
+		definitions = append(definitions, d.columnDefinition(col))
+	}
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("cannot drop every column of a table")
+	}
+	if len(pkCols) > 0 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pkCols, ", ")))
+	}
+
+	finalName := table
+	if alteration.RenameTo != "" {
+		finalName = alteration.RenameTo
+	}
+	tempName := "_mergen_rebuild_" + finalName
+
+	definitions = append(definitions, d.foreignKeyDefinitions(current.foreignKeys, renamed, table, finalName)...)
+
+	// Triggers are recreated as written, so they must not name anything the
+	// alteration changes
+	if len(current.triggers) > 0 {
+		changesNames := finalName != table || len(alteration.DropColumns) > 0
+		for old, next := range renamed {
+			changesNames = changesNames || old != next
+		}
+		if changesNames || d.schemaName(database) != "main" {
+			return nil, fmt.Errorf("cannot rebuild %s while it has triggers: drop them first, or change types, nullability and defaults without renaming or dropping anything", table)
+		}
+	}
+
+	statements := []string{
+		"PRAGMA foreign_keys = OFF",
+		"BEGIN",
+		fmt.Sprintf("CREATE TABLE %s (%s)", d.QualifyTable(database, tempName), strings.Join(definitions, ", ")),
+	}
+	if len(targetCols) > 0 {
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			d.QualifyTable(database, tempName), strings.Join(targetCols, ", "),
+			strings.Join(sourceCols, ", "), d.QualifyTable(database, table)))
+	}
+	statements = append(statements,
+		fmt.Sprintf("DROP TABLE %s", d.QualifyTable(database, table)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", d.QualifyTable(database, tempName), d.QuoteIdentifier(finalName)),
+	)
+
+	// Recreate secondary indexes that still have all their columns
+	for _, idx := range current.Indexes {
+		if idx.IsPrimary || len(idx.Columns) == 0 {
+			continue
+		}
+		cols := make([]string, 0, len(idx.Columns))
+		plain := make([]string, 0, len(idx.Columns))
+		for _, c := range idx.Columns {
+			name, ok := renamed[c]
+			if !ok {
+				break
+			}
+			cols = append(cols, d.QuoteIdentifier(name))
+			plain = append(plain, name)
+		}
+		if len(cols) != len(idx.Columns) {
+			continue
+		}
+
+		name := idx.Name
+		// Indexes behind UNIQUE constraints have reserved names
+		if strings.HasPrefix(name, "sqlite_autoindex_") {
+			name = fmt.Sprintf("%s_%s_key", finalName, strings.Join(plain, "_"))
+		}
+		unique := ""
+		if idx.IsUnique {
+			unique = "UNIQUE "
+		}
+		statements = append(statements, fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)",
+			unique, d.QualifyTable(database, name), d.QuoteIdentifier(finalName), strings.Join(cols, ", ")))
+	}
+
+	statements = append(statements, current.triggers...)
+
+	return append(statements, "COMMIT"), nil
+}
+
+// foreignKeyDefinitions renders the foreign keys of a rebuilt table. Foreign
+// keys on a dropped column are dropped with it, as on other databases.
+func (d *SQLiteDriver) foreignKeyDefinitions(keys []foreignKeyInfo, renamed map[string]string, table, finalName string) []string {
+	var definitions []string
+next:
+	for _, fk := range keys {
+		cols := make([]string, len(fk.Columns))
+		for i, col := range fk.Columns {
+			name, ok := renamed[col]
+			if !ok {
+				continue next
+			}
+			cols[i] = d.QuoteIdentifier(name)
+		}
+
+		refTable := fk.RefTable
+		refColumns := fk.RefColumns
+		if refTable == table {
+			// A self-reference follows the table's new names
+			refTable = finalName
+			refColumns = make([]string, len(fk.RefColumns))
+			for i, col := range fk.RefColumns {
+				refColumns[i] = renamed[col]
+				if refColumns[i] == "" {
+					continue next
+				}
+			}
+		}
+
+		def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", strings.Join(cols, ", "), d.QuoteIdentifier(refTable))
+		if len(refColumns) > 0 {
+			quoted := make([]string, len(refColumns))
+			for i, col := range refColumns {
+				quoted[i] = d.QuoteIdentifier(col)
+			}
+			def += " (" + strings.Join(quoted, ", ") + ")"
+		}
+		if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+			def += " ON UPDATE " + fk.OnUpdate
+		}
+		if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+			def += " ON DELETE " + fk.OnDelete
+		}
+		definitions = append(definitions, def)
+	}
+	return definitions
+}
+
+// readRebuildDefinitions reads the foreign keys, triggers and CHECK
+// constraints of a table that a rebuild must carry over
+func (d *SQLiteDriver) readRebuildDefinitions(ctx context.Context, db *sql.DB, database, table string, details *TableDetails) error {
+	schema := d.schemaName(database)
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, "table", "from", "to", on_update, on_delete FROM pragma_foreign_key_list(?, ?) ORDER BY id, seq`,
+		table, schema)
+	if err != nil {
+		return fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	lastID := -1
+	for rows.Next() {
+		var id int
+		var refTable, from, onUpdate, onDelete string
+		var to sql.NullString
+		if err := rows.Scan(&id, &refTable, &from, &to, &onUpdate, &onDelete); err != nil {
+			return err
+		}
+		if id != lastID {
+			details.foreignKeys = append(details.foreignKeys, foreignKeyInfo{RefTable: refTable, OnUpdate: onUpdate, OnDelete: onDelete})
+			lastID = id
+		}
+		fk := &details.foreignKeys[len(details.foreignKeys)-1]
+		fk.Columns = append(fk.Columns, from)
+		if to.Valid {
+			fk.RefColumns = append(fk.RefColumns, to.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	master := d.QuoteIdentifier(schema) + ".sqlite_master"
+	var createSQL sql.NullString
+	err = db.QueryRowContext(ctx, "SELECT sql FROM "+master+" WHERE type = 'table' AND name = ?", table).Scan(&createSQL)
+	if err != nil {
+		return fmt.Errorf("failed to read table definition: %w", err)
+	}
+	details.hasChecks = hasTopLevelKeyword(createTableBody(createSQL.String), "CHECK", false)
+
+	triggers, err := db.QueryContext(ctx, "SELECT sql FROM "+master+" WHERE type = 'trigger' AND tbl_name = ? ORDER BY name", table)
+	if err != nil {
+		return fmt.Errorf("failed to read triggers: %w", err)
+	}
+	defer triggers.Close()
+	for triggers.Next() {
+		var trigger string
+		if err := triggers.Scan(&trigger); err != nil {
+			return err
+		}
+		details.triggers = append(details.triggers, trigger)
+	}
+	return triggers.Err()
+}
+
+// createTableBody returns the column and constraint list of a CREATE TABLE
+// statement, without the enclosing parentheses
+func createTableBody(createSQL string) string {
+	for i := 0; i < len(createSQL); {
+		switch c := createSQL[i]; c {
+		case '\'', '"', '`':
+			i = quotedEnd(createSQL, i, false)
+		case '[':
+			end := strings.IndexByte(createSQL[i:], ']')
+			if end < 0 {
+				return ""
+			}
+			i += end + 1
+		case '(':
+			end := strings.LastIndexByte(createSQL, ')')
+			if end < i {
+				return ""
+			}
+			return createSQL[i+1 : end]
+		default:
+			i++
+		}
+	}
+	return ""
+}
+
+// columnDefinition renders a column for CREATE TABLE / ADD COLUMN
+func (d *SQLiteDriver) columnDefinition(col ColumnInfo) string {
+	def := d.QuoteIdentifier(col.Name)
+	if col.Type != "" {
+		def += " " + col.Type
+	}
+	if !col.Nullable {
+		def += " NOT NULL"
+	}
+	if col.Default != "" {
+		def += " DEFAULT " + sqliteDefault(col.Default)
+	}
+	return def
+}
+
+// columnSourceName returns the current name of a column being modified
+func columnSourceName(col ColumnInfo) string {
+	if col.OldName != "" {
+		return col.OldName
+	}
+	return col.Name
+}
+
+// findColumn returns the column with the given name, or nil
+func findColumn(columns []ColumnInfo, name string) *ColumnInfo {
+	for i := range columns {
+		if columns[i].Name == name {
+			return &columns[i]
+		}
+	}
+	return nil
+}
+
+// sqliteUnquoteDefault turns a quoted string default back into its plain value
+func sqliteUnquoteDefault(v string) string {
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+	}
+	return v
+}
+
+// sqliteDefault renders a default value, keeping numbers, keywords and
+// parenthesised expressions as-is and quoting everything else
+func sqliteDefault(v string) string {
+	switch strings.ToUpper(v) {
+	case "NULL", "CURRENT_TIMESTAMP", "CURRENT_DATE", "CURRENT_TIME", "TRUE", "FALSE":
+		return v
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v
+	}
+	if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
+
+func (d *SQLiteDriver) BuildTruncateTableQuery(database, table string) string {
+	// SQLite has no TRUNCATE; an unqualified DELETE uses the truncate optimisation
+	return fmt.Sprintf("DELETE FROM %s", d.QualifyTable(database, table))
+}
+
+func (d *SQLiteDriver) BuildDropTableQuery(database, table string) string {
+	return fmt.Sprintf("DROP TABLE %s", d.QualifyTable(database, table))
+}
+
+func (d *SQLiteDriver) BuildUseDatabaseQuery(database string) string {
+	// Attached databases are addressed by schema prefix; there is no USE
+	return ""
+}
+
+func (d *SQLiteDriver) ReconnectsOnUseDatabase() bool {
+	return false
+}
+
+func (d *SQLiteDriver) TransactionalDDL() bool {
+	return true
+}
+
+func (d *SQLiteDriver) BuildInsertQuery(database, table string, columns []string) string {
+	quotedCols := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, col := range columns {
+		quotedCols[i] = d.QuoteIdentifier(col)
+		placeholders[i] = "?"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.QualifyTable(database, table), strings.Join(quotedCols, ", "), strings.Join(placeholders, ", "))
+}
+
+func (d *SQLiteDriver) BuildUpdateQuery(database, table string, columns []string, where string) string {
+	setClauses := make([]string, len(columns))
+	for i, col := range columns {
+		setClauses[i] = fmt.Sprintf("%s = ?", d.QuoteIdentifier(col))
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		d.QualifyTable(database, table), strings.Join(setClauses, ", "), where)
+}
+
+func (d *SQLiteDriver) BuildDeleteQuery(database, table, where string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", d.QualifyTable(database, table), where)
+}
+
+func (d *SQLiteDriver) RowLocator() string {
+	// Tables without a primary key always have a rowid
+	return "rowid"
+}
+
+func (d *SQLiteDriver) SupportsReturning() bool {
+	// Since SQLite 3.35; the bundled one is newer
+	return true
+}
+
+func (d *SQLiteDriver) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (d *SQLiteDriver) Placeholder(index int) string {
+	return "?"
+}
+
+func (d *SQLiteDriver) JSONPathExpr(column string, path []string, placeholder string) (string, interface{}) {
+	return fmt.Sprintf("json_extract(%s, %s)", column, placeholder), jsonPathString(path)
+}
+
+func (d *SQLiteDriver) BuildDistinctValuesQuery(database, table, column string) string {
+	return fmt.Sprintf("SELECT DISTINCT %s FROM %s ORDER BY %s LIMIT 100",
+		d.QuoteIdentifier(column), d.QualifyTable(database, table), d.QuoteIdentifier(column))
+}
+
+func (d *SQLiteDriver) SplitStatements(script string) []ScriptStatement {
+	return splitStatements(script, splitOptions{
+		backticks:     true,
+		triggerBodies: true,
+	})
+}
+
+func (d *SQLiteDriver) ConnectionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	// SQLite is in-process; cancelling the context interrupts the statement
+	return 0, nil
+}
+
+func (d *SQLiteDriver) BuildCancelQuery(connectionID int64) string {
+	return ""
+}
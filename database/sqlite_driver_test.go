@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openTestSQLite connects a Manager to a new SQLite file prepared with setup
+func openTestSQLite(t *testing.T, setup ...string) (*Manager, *session) {
+	t.Helper()
+	m := NewManager()
+	config := ConnectionConfig{Type: "sqlite", FilePath: filepath.Join(t.TempDir(), "test.db")}
+	if err := m.Connect(context.Background(), "test", config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.DisconnectAll() })
+
+	s, err := m.getSession("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range setup {
+		if _, err := s.db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return m, s
+}
+
+// tableContents returns the rows of query, for comparing table states
+func tableContents(t *testing.T, s *session, query string) [][]interface{} {
+	t.Helper()
+	rows, err := s.db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	result, err := collectRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Rows
+}
+
+func TestSQLiteBuildDSN(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		config ConnectionConfig
+		want   string
+	}{
+		{
+			name: "plain",
+			path: "/data/app.db",
+			want: "file:/data/app.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+		},
+		{
+			name: "special characters in the path",
+			path: "/data/50% off?#1.db",
+			want: "file:/data/50%25 off%3f%231.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+		},
+		{
+			name:   "read-only file",
+			path:   "/data/app.db",
+			config: ConnectionConfig{FileReadOnly: true, ExtraParams: map[string]string{"mode": "rwc"}},
+			want:   "file:/data/app.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&mode=ro",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&SQLiteDriver{}).buildDSN(tt.path, tt.config); got != tt.want {
+				t.Errorf("buildDSN = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteConnectReadOnlyFile(t *testing.T) {
+	config := ConnectionConfig{Type: "sqlite", FilePath: filepath.Join(t.TempDir(), "missing.db"), FileReadOnly: true}
+	if err := NewManager().Connect(context.Background(), "test", config); err == nil {
+		t.Error("a missing read-only file was opened")
+	}
+}
+
+func TestSQLiteIntrospection(t *testing.T) {
+	ctx := context.Background()
+	d := &SQLiteDriver{}
+	_, s := openTestSQLite(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, name TEXT DEFAULT 'anon')",
+		"CREATE TABLE tags (a TEXT, b TEXT, PRIMARY KEY (b, a))",
+		"CREATE INDEX users_name ON users (name, email)",
+		"CREATE VIEW names AS SELECT name FROM users",
+	)
+
+	tables, err := d.GetTables(ctx, s.db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTables := []TableInfo{{Name: "names", Engine: "view"}, {Name: "tags", Engine: "table"}, {Name: "users", Engine: "table"}}
+	if !reflect.DeepEqual(tables, wantTables) {
+		t.Errorf("GetTables = %+v, want %+v", tables, wantTables)
+	}
+
+	columns, err := d.GetColumns(ctx, s.db, "main", "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantColumns := []ColumnInfo{
+		{Name: "id", Type: "INTEGER", Key: "PRI", Extra: "auto_increment"},
+		{Name: "email", Type: "TEXT", Key: "UNI"},
+		{Name: "name", Type: "TEXT", Nullable: true, Default: "anon", Key: "MUL"},
+	}
+	if !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("GetColumns = %+v, want %+v", columns, wantColumns)
+	}
+
+	tests := []struct {
+		table string
+		want  []IndexInfo
+	}{
+		{
+			table: "users",
+			want: []IndexInfo{
+				{Name: "PRIMARY", Columns: []string{"id"}, IsUnique: true, IsPrimary: true},
+				{Name: "users_name", Columns: []string{"name", "email"}},
+				{Name: "sqlite_autoindex_users_1", Columns: []string{"email"}, IsUnique: true},
+			},
+		},
+		{
+			table: "tags",
+			want: []IndexInfo{
+				{Name: "sqlite_autoindex_tags_1", Columns: []string{"b", "a"}, IsUnique: true, IsPrimary: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		indexes, err := d.GetIndexes(ctx, s.db, "", tt.table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(indexes, tt.want) {
+			t.Errorf("GetIndexes(%s) = %+v, want %+v", tt.table, indexes, tt.want)
+		}
+	}
+}
+
+func TestSQLiteAlterTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       []string
+		alteration  TableAlteration
+		wantErr     string
+		wantSQL     string // Definition of t afterwards
+		wantRows    [][]interface{}
+		wantIndexes int // Secondary indexes afterwards
+	}{
+		{
+			name:  "rename and add in place",
+			setup: []string{"CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT)", "INSERT INTO t VALUES (1, 'x')"},
+			alteration: TableAlteration{
+				ModifyColumns: []ColumnInfo{{OldName: "a", Name: "b", Type: "TEXT", Nullable: true}},
+				AddColumns:    []ColumnInfo{{Name: "c", Type: "INTEGER", Nullable: true}},
+			},
+			wantSQL:  `CREATE TABLE t (id INTEGER PRIMARY KEY, "b" TEXT, "c" INTEGER)`,
+			wantRows: [][]interface{}{{int64(1), "x", nil}},
+		},
+		{
+			name: "type change rebuilds the table",
+			setup: []string{
+				"CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, n TEXT)",
+				"CREATE INDEX t_n ON t (n)",
+				"INSERT INTO t VALUES (1, 'x', '5')",
+			},
+			alteration: TableAlteration{
+				ModifyColumns: []ColumnInfo{{Name: "n", Type: "INTEGER", Nullable: false, Default: "0"}},
+			},
+			wantSQL:     `CREATE TABLE "t" ("id" INTEGER NOT NULL, "a" TEXT, "n" INTEGER NOT NULL DEFAULT 0, PRIMARY KEY ("id"))`,
+			wantRows:    [][]interface{}{{int64(1), "x", int64(5)}},
+			wantIndexes: 2,
+		},
+		{
+			name:  "CHECK constraints are not rebuilt",
+			setup: []string{"CREATE TABLE t (id INTEGER PRIMARY KEY, n TEXT, CHECK (length(n) < 10))"},
+			alteration: TableAlteration{
+				ModifyColumns: []ColumnInfo{{Name: "n", Type: "INTEGER", Nullable: true}},
+			},
+			wantErr: "CHECK constraints",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m, s := openTestSQLite(t, tt.setup...)
+
+			err := m.AlterTable(ctx, "test", "main", "", "t", tt.alteration, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var createSQL string
+			if err := s.db.QueryRow("SELECT sql FROM sqlite_master WHERE name = 't'").Scan(&createSQL); err != nil {
+				t.Fatal(err)
+			}
+			if createSQL != tt.wantSQL {
+				t.Errorf("table definition\n got %s\nwant %s", createSQL, tt.wantSQL)
+			}
+			if got := tableContents(t, s, "SELECT * FROM t"); !reflect.DeepEqual(got, tt.wantRows) {
+				t.Errorf("rows = %v, want %v", got, tt.wantRows)
+			}
+
+			// Secondary indexes survive a rebuild
+			indexes, err := (&SQLiteDriver{}).GetIndexes(ctx, s.db, "", "t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			secondary := 0
+			for _, idx := range indexes {
+				if !idx.IsPrimary {
+					secondary++
+				}
+			}
+			if secondary != tt.wantIndexes {
+				t.Errorf("%d secondary indexes, want %d: %+v", secondary, tt.wantIndexes, indexes)
+			}
+		})
+	}
+}
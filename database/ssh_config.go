@@ -0,0 +1,474 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSSHConfigDepth bounds Include nesting and ProxyJump resolution
+const maxSSHConfigDepth = 16
+
+// SSHConfigHost holds the effective ~/.ssh/config settings for a Host alias
+type SSHConfigHost struct {
+	Alias         string   `json:"alias"`
+	HostName      string   `json:"hostName"`
+	Port          int      `json:"port"`
+	User          string   `json:"user"`
+	IdentityFiles []string `json:"identityFiles"`
+	ProxyJump     string   `json:"proxyJump"`
+}
+
+// sshConfigLine is one keyword/arguments pair from an ssh_config file
+type sshConfigLine struct {
+	keyword string
+	args    []string
+}
+
+// sshConfigPath returns the user's ~/.ssh/config path
+func sshConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".ssh", "config"), nil
+}
+
+// ListSSHConfigHosts returns the concrete Host aliases defined in ~/.ssh/config
+// and the files it includes. Wildcard and negated patterns are left out.
+func ListSSHConfigHosts() ([]string, error) {
+	path, err := sshConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := readSSHConfig(path, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, line := range lines {
+		if line.keyword != "host" {
+			continue
+		}
+		for _, pattern := range line.args {
+			if strings.ContainsAny(pattern, "*?!") || seen[pattern] {
+				continue
+			}
+			seen[pattern] = true
+			hosts = append(hosts, pattern)
+		}
+	}
+
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+// ResolveSSHConfigHost returns the effective settings for alias, applying
+// every matching Host block in file order with the first value winning, as
+// OpenSSH does
+func ResolveSSHConfigHost(alias string) (*SSHConfigHost, error) {
+	path, err := sshConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := readSSHConfig(path, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	host := &SSHConfigHost{Alias: alias}
+	matching := true // Lines before the first Host block apply to every host
+	for _, line := range lines {
+		switch line.keyword {
+		case "host":
+			matching = matchHostPatterns(alias, line.args)
+			continue
+		case "match":
+			// Only the unconditional form is supported
+			matching = len(line.args) == 1 && strings.EqualFold(line.args[0], "all")
+			continue
+		}
+		if !matching || len(line.args) == 0 {
+			continue
+		}
+
+		switch line.keyword {
+		case "hostname":
+			if host.HostName == "" {
+				host.HostName = line.args[0]
+			}
+		case "port":
+			if host.Port == 0 {
+				port, err := strconv.Atoi(line.args[0])
+				if err != nil {
+					return nil, fmt.Errorf("invalid Port %q for host %s", line.args[0], alias)
+				}
+				host.Port = port
+			}
+		case "user":
+			if host.User == "" {
+				host.User = line.args[0]
+			}
+		case "identityfile":
+			host.IdentityFiles = append(host.IdentityFiles, line.args[0])
+		case "proxyjump":
+			if host.ProxyJump == "" {
+				host.ProxyJump = line.args[0]
+			}
+		}
+	}
+
+	if host.Port == 0 {
+		host.Port = 22
+	}
+	if host.User == "" {
+		if u, err := user.Current(); err == nil {
+			host.User = u.Username
+		}
+	}
+	if host.HostName == "" {
+		host.HostName = alias
+	}
+	host.HostName = expandSSHTokens(host.HostName, alias, alias, host.Port, host.User)
+	if strings.EqualFold(host.ProxyJump, "none") {
+		host.ProxyJump = ""
+	}
+	for i, f := range host.IdentityFiles {
+		host.IdentityFiles[i] = expandSSHTokens(f, alias, host.HostName, host.Port, host.User)
+	}
+
+	return host, nil
+}
+
+// readSSHConfig reads an ssh_config file, splicing in Include directives
+func readSSHConfig(path string, depth int) ([]sshConfigLine, error) {
+	if depth > maxSSHConfigDepth {
+		return nil, fmt.Errorf("ssh config Include nested too deeply at %s", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if depth > 0 && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	defer f.Close()
+
+	var lines []sshConfigLine
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, ok := parseSSHConfigLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if line.keyword != "include" {
+			lines = append(lines, line)
+			continue
+		}
+
+		for _, pattern := range line.args {
+			included, err := expandSSHInclude(pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, inc := range included {
+				incLines, err := readSSHConfig(inc, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				lines = append(lines, incLines...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ssh config: %w", err)
+	}
+
+	return lines, nil
+}
+
+// parseSSHConfigLine splits a line into a lower-cased keyword and its
+// arguments. Both "Key value" and "Key=value" forms are accepted.
+func parseSSHConfigLine(text string) (sshConfigLine, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.HasPrefix(text, "#") {
+		return sshConfigLine{}, false
+	}
+
+	end := strings.IndexAny(text, " \t=")
+	if end < 0 {
+		return sshConfigLine{keyword: strings.ToLower(text)}, true
+	}
+	keyword := strings.ToLower(text[:end])
+	rest := strings.TrimLeft(text[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+
+	return sshConfigLine{keyword: keyword, args: splitSSHConfigArgs(rest)}, true
+}
+
+// splitSSHConfigArgs splits arguments on whitespace, honouring double quotes
+// and stopping at a trailing comment
+func splitSSHConfigArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	inQuotes, hasArg := false, false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case !inQuotes && (c == ' ' || c == '\t'):
+			if hasArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				hasArg = false
+			}
+		case !inQuotes && c == '#' && !hasArg:
+			return args
+		default:
+			cur.WriteByte(c)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// expandSSHInclude resolves an Include argument to the files it names.
+// Relative paths are taken from ~/.ssh, and glob patterns are expanded.
+func expandSSHInclude(pattern string) ([]string, error) {
+	path, err := expandHome(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, ".ssh", path)
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh config Include %q: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchHostPatterns reports whether host matches a Host line: at least one
+// pattern must match and no negated pattern may match
+func matchHostPatterns(host string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		ok, err := filepath.Match(strings.ToLower(pattern), strings.ToLower(host))
+		if err != nil || !ok {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// expandSSHTokens replaces the %h, %n, %p, %r and %% tokens and a leading ~
+func expandSSHTokens(value, alias, hostName string, port int, user string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' || i+1 >= len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'h':
+			b.WriteString(hostName)
+		case 'n':
+			b.WriteString(alias)
+		case 'p':
+			b.WriteString(strconv.Itoa(port))
+		case 'r':
+			b.WriteString(user)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(value[i])
+		}
+	}
+
+	expanded, err := expandHome(b.String())
+	if err != nil {
+		return b.String()
+	}
+	return expanded
+}
+
+// parseJumpSpec splits a ProxyJump entry of the form [user@]host[:port]
+func parseJumpSpec(spec string) (user, host string, port int) {
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		user, spec = spec[:at], spec[at+1:]
+	}
+	host = spec
+	if strings.HasPrefix(spec, "[") {
+		// [ipv6]:port
+		if end := strings.Index(spec, "]"); end > 0 {
+			host = spec[1:end]
+			if p, err := strconv.Atoi(strings.TrimPrefix(spec[end+1:], ":")); err == nil {
+				port = p
+			}
+		}
+		return user, host, port
+	}
+	if colon := strings.LastIndex(spec, ":"); colon >= 0 && strings.Count(spec, ":") == 1 {
+		if p, err := strconv.Atoi(spec[colon+1:]); err == nil {
+			host, port = spec[:colon], p
+		}
+	}
+	return user, host, port
+}
+
+// applySSHConfigHost fills the SSH fields of config from its SSHConfigHost
+// alias. Fields set explicitly on the connection take precedence, the way
+// command-line options override ssh_config. It also returns a description of
+// the resolved route for diagnostics.
+func applySSHConfigHost(config ConnectionConfig) (ConnectionConfig, []string, error) {
+	if config.SSHConfigHost == "" {
+		return config, nil, nil
+	}
+
+	host, err := ResolveSSHConfigHost(config.SSHConfigHost)
+	if err != nil {
+		return config, nil, err
+	}
+
+	if config.SSHHost == "" {
+		config.SSHHost = host.HostName
+	}
+	if config.SSHPort == 0 {
+		config.SSHPort = host.Port
+	}
+	if config.SSHUser == "" {
+		config.SSHUser = host.User
+	}
+	if config.SSHPrivateKey == "" && config.SSHPassword == "" {
+		config.SSHPrivateKey = identityFile(host)
+		// ssh falls back to the agent when it is running
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			config.SSHUseAgent = true
+		}
+	}
+
+	var jumps []SSHJumpHost
+	if len(config.SSHJumpHosts) == 0 && host.ProxyJump != "" {
+		jumps, err = resolveProxyJump(host.ProxyJump, 0)
+		if err != nil {
+			return config, nil, err
+		}
+		config.SSHJumpHosts = jumps
+	}
+
+	diagnostics := []string{
+		fmt.Sprintf("SSH config host %q resolved to %s@%s:%d", host.Alias, config.SSHUser, config.SSHHost, config.SSHPort),
+	}
+	if len(host.IdentityFiles) > 0 {
+		diagnostics = append(diagnostics, fmt.Sprintf("Identity files: %s", strings.Join(host.IdentityFiles, ", ")))
+	}
+	if config.SSHUseAgent {
+		diagnostics = append(diagnostics, "Using ssh-agent")
+	}
+	for _, jump := range config.SSHJumpHosts {
+		diagnostics = append(diagnostics, fmt.Sprintf("Jump host: %s@%s:%d", jump.User, jump.Host, jump.Port))
+	}
+
+	return config, diagnostics, nil
+}
+
+// resolveProxyJump turns a comma-separated ProxyJump value into jump hosts,
+// resolving each entry through ssh_config the way ssh does
+func resolveProxyJump(proxyJump string, depth int) ([]SSHJumpHost, error) {
+	if depth > maxSSHConfigDepth {
+		return nil, fmt.Errorf("ProxyJump chain is too long or loops")
+	}
+
+	var jumps []SSHJumpHost
+	for _, spec := range strings.Split(proxyJump, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		user, alias, port := parseJumpSpec(spec)
+
+		resolved, err := ResolveSSHConfigHost(alias)
+		if err != nil {
+			return nil, err
+		}
+
+		// A jump host's own ProxyJump comes before it in the chain
+		if resolved.ProxyJump != "" {
+			inner, err := resolveProxyJump(resolved.ProxyJump, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			jumps = append(jumps, inner...)
+		}
+
+		jump := SSHJumpHost{
+			Host:       resolved.HostName,
+			Port:       resolved.Port,
+			User:       resolved.User,
+			PrivateKey: identityFile(resolved),
+			UseAgent:   os.Getenv("SSH_AUTH_SOCK") != "",
+		}
+		if user != "" {
+			jump.User = user
+		}
+		if port != 0 {
+			jump.Port = port
+		}
+		jumps = append(jumps, jump)
+	}
+	return jumps, nil
+}
+
+// identityFile returns the first identity file of host that exists, falling
+// back to ssh's default key names when none are configured
+func identityFile(host *SSHConfigHost) string {
+	paths := host.IdentityFiles
+	if len(paths) == 0 {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			paths = append(paths, filepath.Join("~", ".ssh", name))
+		}
+	}
+
+	for _, p := range paths {
+		path, err := expandHome(p)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSSHConfig points HOME at a temporary directory holding files under
+// ~/.ssh, and returns that directory
+func writeSSHConfig(t *testing.T, files map[string]string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	for name, content := range files {
+		path := filepath.Join(home, ".ssh", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+const testSSHConfig = `# Global settings apply until the first Host
+User fallback
+
+Host bastion
+    HostName bastion.example.com
+    Port 2222
+    User ops
+
+Host db-* !db-legacy
+    HostName %n.internal
+    ProxyJump bastion
+    IdentityFile ~/.ssh/db_key
+
+Host db-primary
+    Port 2200
+    User = "app user"
+
+Host inner
+    HostName 10.0.0.9
+    ProxyJump ops2@edge:2022,db-primary
+
+Host edge
+    HostName edge.example.com
+    ProxyJump none
+
+Include conf.d/*.conf
+`
+
+func TestResolveSSHConfigHost(t *testing.T) {
+	home := writeSSHConfig(t, map[string]string{
+		"config":            testSSHConfig,
+		"conf.d/extra.conf": "Host extra\n  HostName extra.example.com # trailing comment\n  Port=2022\n",
+	})
+
+	tests := []struct {
+		alias string
+		want  SSHConfigHost
+	}{
+		{
+			alias: "bastion",
+			want:  SSHConfigHost{Alias: "bastion", HostName: "bastion.example.com", Port: 2222, User: "fallback"},
+		},
+		{
+			// The first value wins, including over later, more specific blocks
+			alias: "db-primary",
+			want: SSHConfigHost{
+				Alias: "db-primary", HostName: "db-primary.internal", Port: 2200, User: "fallback",
+				IdentityFiles: []string{filepath.Join(home, ".ssh", "db_key")}, ProxyJump: "bastion",
+			},
+		},
+		{
+			alias: "db-legacy",
+			want:  SSHConfigHost{Alias: "db-legacy", HostName: "db-legacy", Port: 22, User: "fallback"},
+		},
+		{
+			alias: "edge",
+			want:  SSHConfigHost{Alias: "edge", HostName: "edge.example.com", Port: 22, User: "fallback"},
+		},
+		{
+			alias: "extra",
+			want:  SSHConfigHost{Alias: "extra", HostName: "extra.example.com", Port: 2022, User: "fallback"},
+		},
+		{
+			alias: "unknown.example.com",
+			want:  SSHConfigHost{Alias: "unknown.example.com", HostName: "unknown.example.com", Port: 22, User: "fallback"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			got, err := ResolveSSHConfigHost(tt.alias)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("\n got %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSSHConfigHosts(t *testing.T) {
+	writeSSHConfig(t, map[string]string{
+		"config":            testSSHConfig,
+		"conf.d/extra.conf": "Host extra bastion\n",
+	})
+
+	got, err := ListSSHConfigHosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"bastion", "db-primary", "edge", "extra", "inner"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSSHConfigHosts() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveProxyJump(t *testing.T) {
+	writeSSHConfig(t, map[string]string{"config": testSSHConfig})
+
+	inner, err := ResolveSSHConfigHost("inner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := resolveProxyJump(inner.ProxyJump, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// db-primary's own ProxyJump comes before it; an explicit user and port
+	// override the resolved ones
+	want := []SSHJumpHost{
+		{Host: "edge.example.com", Port: 2022, User: "ops2"},
+		{Host: "bastion.example.com", Port: 2222, User: "fallback"},
+		{Host: "db-primary.internal", Port: 2200, User: "fallback"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestResolveProxyJumpLoop(t *testing.T) {
+	writeSSHConfig(t, map[string]string{"config": "Host a\n  ProxyJump b\nHost b\n  ProxyJump a\n"})
+
+	if _, err := resolveProxyJump("a", 0); err == nil {
+		t.Fatal("expected a ProxyJump loop to be refused")
+	}
+}
+
+func TestParseJumpSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		user string
+		host string
+		port int
+	}{
+		{"bastion", "", "bastion", 0},
+		{"ops@bastion:2222", "ops", "bastion", 2222},
+		{"a@b@bastion", "a@b", "bastion", 0},
+		{"[fe80::1]:2200", "", "fe80::1", 2200},
+		{"fe80::1", "", "fe80::1", 0},
+	}
+
+	for _, tt := range tests {
+		user, host, port := parseJumpSpec(tt.spec)
+		if user != tt.user || host != tt.host || port != tt.port {
+			t.Errorf("parseJumpSpec(%q) = %q, %q, %d; want %q, %q, %d", tt.spec, user, host, port, tt.user, tt.host, tt.port)
+		}
+	}
+}
+
+func TestMatchHostPatterns(t *testing.T) {
+	tests := []struct {
+		host     string
+		patterns []string
+		want     bool
+	}{
+		{"db-1", []string{"db-*"}, true},
+		{"DB-1", []string{"db-?"}, true},
+		{"db-legacy", []string{"db-*", "!db-legacy"}, false},
+		{"web", []string{"db-*", "web"}, true},
+		{"web", []string{"!db"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchHostPatterns(tt.host, tt.patterns); got != tt.want {
+			t.Errorf("matchHostPatterns(%q, %q) = %v, want %v", tt.host, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestParseSSHConfigLine(t *testing.T) {
+	tests := []struct {
+		text string
+		want sshConfigLine
+		ok   bool
+	}{
+		{"  # comment", sshConfigLine{}, false},
+		{"", sshConfigLine{}, false},
+		{"HostName db.example.com", sshConfigLine{keyword: "hostname", args: []string{"db.example.com"}}, true},
+		{"Port=2222", sshConfigLine{keyword: "port", args: []string{"2222"}}, true},
+		{`IdentityFile "~/My Keys/id" # note`, sshConfigLine{keyword: "identityfile", args: []string{"~/My Keys/id"}}, true},
+		{"Host a b\t c", sshConfigLine{keyword: "host", args: []string{"a", "b", "c"}}, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseSSHConfigLine(tt.text)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSSHConfigLine(%q) = %+v, %v; want %+v, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyPrompt describes an SSH host key seen for the first time
+type HostKeyPrompt struct {
+	Host        string `json:"host"`
+	KeyType     string `json:"keyType"`
+	Fingerprint string `json:"fingerprint"` // SHA256 fingerprint as printed by ssh-keygen -l
+}
+
+// HostKeyApprover asks the user whether to trust an unknown host key.
+// Returning true records the key in the app's known_hosts file.
+type HostKeyApprover func(prompt HostKeyPrompt) bool
+
+// HostKeyMismatchError is returned when a host presents a key that differs
+// from the one on record, which may mean the connection is being intercepted
+type HostKeyMismatchError struct {
+	Host                 string
+	KnownKeys            []string // Fingerprints on record, with the file and line they came from
+	PresentedType        string
+	PresentedFingerprint string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key for %s has changed: server presented %s %s, expected %s",
+		e.Host, e.PresentedType, e.PresentedFingerprint, strings.Join(e.KnownKeys, ", "))
+}
+
+// knownHostsMu serialises writes to the app's known_hosts file
+var knownHostsMu sync.Mutex
+
+// knownHostsFiles returns the user's OpenSSH known_hosts file and the one
+// managed by the app
+func knownHostsFiles() (userFile, appFile string, err error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".ssh", "known_hosts"),
+		filepath.Join(homeDir, ".runedb", "known_hosts"), nil
+}
+
+// hostKeyCallback checks host keys against ~/.ssh/known_hosts and the app's
+// known_hosts file. Unknown hosts are passed to approve; a changed key is
+// always rejected.
+func hostKeyCallback(approve HostKeyApprover) (ssh.HostKeyCallback, error) {
+	userFile, appFile, err := knownHostsFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		// Re-read on every dial so keys approved by another session are seen
+		var files []string
+		for _, f := range []string{userFile, appFile} {
+			if _, err := os.Stat(f); err == nil {
+				files = append(files, f)
+			}
+		}
+
+		if len(files) > 0 {
+			check, err := knownhosts.New(files...)
+			if err != nil {
+				return fmt.Errorf("failed to read known_hosts: %w", err)
+			}
+
+			err = check(hostname, remote, key)
+			if err == nil {
+				return nil
+			}
+
+			var revoked *knownhosts.RevokedError
+			if errors.As(err, &revoked) {
+				return fmt.Errorf("host key for %s has been revoked", hostname)
+			}
+
+			var keyErr *knownhosts.KeyError
+			if !errors.As(err, &keyErr) {
+				return err
+			}
+			if len(keyErr.Want) > 0 {
+				known := make([]string, len(keyErr.Want))
+				for i, want := range keyErr.Want {
+					known[i] = fmt.Sprintf("%s %s (%s:%d)",
+						want.Key.Type(), ssh.FingerprintSHA256(want.Key), want.Filename, want.Line)
+				}
+				return &HostKeyMismatchError{
+					Host:                 hostname,
+					KnownKeys:            known,
+					PresentedType:        key.Type(),
+					PresentedFingerprint: ssh.FingerprintSHA256(key),
+				}
+			}
+		}
+
+		// Unknown host: trust on first use, but only with the user's approval
+		prompt := HostKeyPrompt{
+			Host:        hostname,
+			KeyType:     key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+		}
+		if approve == nil || !approve(prompt) {
+			return fmt.Errorf("host key for %s (%s) was not accepted", hostname, prompt.Fingerprint)
+		}
+
+		return addKnownHost(appFile, hostname, remote, key)
+	}, nil
+}
+
+// addKnownHost appends an approved host key to a known_hosts file
+func addKnownHost(path, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if remote != nil {
+		if addr := knownhosts.Normalize(remote.String()); addr != addresses[0] {
+			addresses = append(addresses, addr)
+		}
+	}
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line(addresses, key)); err != nil {
+		return fmt.Errorf("failed to save host key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// newTestHostKey returns a new ed25519 public key
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	const host = "bastion.example:22"
+	remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 22}
+	key := newTestHostKey(t)
+	otherKey := newTestHostKey(t)
+
+	tests := []struct {
+		name         string
+		userHosts    []string // Lines of ~/.ssh/known_hosts
+		approve      bool
+		noApprover   bool
+		wantPrompt   bool
+		wantErr      string
+		wantMismatch bool
+		wantSaved    bool // Key recorded in the app's known_hosts
+	}{
+		{name: "known in the user's file", userHosts: []string{knownhosts.Line([]string{host}, key)}},
+		{name: "first use approved", approve: true, wantPrompt: true, wantSaved: true},
+		{name: "first use declined", wantPrompt: true, wantErr: "was not accepted"},
+		{name: "first use without an approver", noApprover: true, wantErr: "was not accepted"},
+		{
+			name:         "changed key",
+			userHosts:    []string{knownhosts.Line([]string{host}, otherKey)},
+			approve:      true,
+			wantErr:      "has changed",
+			wantMismatch: true,
+		},
+		{
+			name:      "revoked key",
+			userHosts: []string{"@revoked * " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))},
+			approve:   true,
+			wantErr:   "revoked",
+		},
+		{
+			name:       "other hosts don't matter",
+			userHosts:  []string{knownhosts.Line([]string{"db.example:22"}, otherKey)},
+			approve:    true,
+			wantPrompt: true,
+			wantSaved:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			if len(tt.userHosts) > 0 {
+				path := filepath.Join(home, ".ssh", "known_hosts")
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(strings.Join(tt.userHosts, "\n")+"\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var prompts []HostKeyPrompt
+			var approver HostKeyApprover
+			if !tt.noApprover {
+				approver = func(prompt HostKeyPrompt) bool {
+					prompts = append(prompts, prompt)
+					return tt.approve
+				}
+			}
+			callback, err := hostKeyCallback(approver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = callback(host, remote, key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			var mismatch *HostKeyMismatchError
+			if errors.As(err, &mismatch) != tt.wantMismatch {
+				t.Errorf("mismatch error = %v, want %v", mismatch, tt.wantMismatch)
+			}
+			if tt.wantMismatch && mismatch.PresentedFingerprint != ssh.FingerprintSHA256(key) {
+				t.Errorf("presented fingerprint = %s", mismatch.PresentedFingerprint)
+			}
+
+			if tt.wantPrompt {
+				want := HostKeyPrompt{Host: host, KeyType: ssh.KeyAlgoED25519, Fingerprint: ssh.FingerprintSHA256(key)}
+				if len(prompts) != 1 || prompts[0] != want {
+					t.Errorf("prompts = %+v, want %+v", prompts, want)
+				}
+			} else if len(prompts) > 0 {
+				t.Errorf("prompted for a host that needs no approval: %+v", prompts)
+			}
+
+			_, statErr := os.Stat(filepath.Join(home, ".runedb", "known_hosts"))
+			if saved := statErr == nil; saved != tt.wantSaved {
+				t.Fatalf("app known_hosts written = %v, want %v", saved, tt.wantSaved)
+			}
+			if tt.wantSaved {
+				// Trusted from now on, under its name and address, without asking
+				again, err := hostKeyCallback(nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := again(host, remote, key); err != nil {
+					t.Errorf("approved key not trusted: %v", err)
+				}
+				if err := again(remote.String(), remote, key); err != nil {
+					t.Errorf("approved key not trusted by address: %v", err)
+				}
+				if err := again(host, remote, otherKey); err == nil {
+					t.Error("a different key was trusted for an approved host")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Tunnel health settings
+const (
+	keepaliveInterval   = 15 * time.Second
+	keepaliveTimeout    = 10 * time.Second
+	keepaliveMaxMissed  = 3
+	reconnectBaseDelay  = time.Second
+	reconnectMaxDelay   = 30 * time.Second
+	reconnectMaxRetries = 8
+	forwardWaitTimeout  = 30 * time.Second // How long a new connection waits for a reconnect
+)
+
+// Tunnel states reported through TunnelStatus
+const (
+	TunnelConnected    = "connected"
+	TunnelReconnecting = "reconnecting"
+	TunnelFailed       = "failed"
+)
+
+// TunnelStatus describes a change in an SSH tunnel's health
+type TunnelStatus struct {
+	ConnID  string `json:"connId"`
+	State   string `json:"state"`
+	Attempt int    `json:"attempt,omitempty"` // Reconnect attempt number while reconnecting
+	Error   string `json:"error,omitempty"`
+}
+
+// reportStatus passes a state change to the tunnel's status handler, if any
+func (t *SSHTunnel) reportStatus(state string, attempt int, err error) {
+	if t.onStatus != nil {
+		t.onStatus(state, attempt, err)
+	}
+}
+
+// supervise watches the current client and rebuilds the hop chain whenever it
+// dies. The local listener is kept, so the database pool keeps its address.
+func (t *SSHTunnel) supervise() {
+	defer t.wg.Done()
+
+	for {
+		t.mu.Lock()
+		client := t.client
+		t.mu.Unlock()
+
+		err := t.watch(client)
+		if err == nil {
+			return // Tunnel closed
+		}
+
+		t.closeClients()
+		if !t.reconnect(err) {
+			return
+		}
+	}
+}
+
+// watch blocks until client dies, misses too many keepalives or is reported
+// broken by a forwarder. It returns nil once the tunnel is closed.
+func (t *SSHTunnel) watch(client *ssh.Client) error {
+	closed := make(chan error, 1)
+	go func() {
+		closed <- client.Wait()
+	}()
+
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-t.done:
+			return nil
+		case err := <-closed:
+			if err == nil {
+				err = fmt.Errorf("SSH connection closed")
+			}
+			return err
+		case <-t.broken:
+			return fmt.Errorf("SSH connection stopped forwarding")
+		case <-ticker.C:
+			if err := sendKeepalive(client); err != nil {
+				missed++
+				if missed >= keepaliveMaxMissed {
+					return fmt.Errorf("SSH keepalive failed %d times: %w", missed, err)
+				}
+				continue
+			}
+			missed = 0
+		}
+	}
+}
+
+// sendKeepalive sends an OpenSSH keepalive request and waits for the reply
+func sendKeepalive(client *ssh.Client) error {
+	reply := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		reply <- err
+	}()
+
+	select {
+	case err := <-reply:
+		return err
+	case <-time.After(keepaliveTimeout):
+		return fmt.Errorf("no reply within %s", keepaliveTimeout)
+	}
+}
+
+// reconnect redials the hop chain with exponential backoff. After
+// reconnectMaxRetries failures it reports the tunnel as failed and waits for a
+// forwarder to ask for another round. It returns false once the tunnel is closed.
+func (t *SSHTunnel) reconnect(cause error) bool {
+	lastErr := cause
+	for {
+		delay := reconnectBaseDelay
+		for attempt := 1; attempt <= reconnectMaxRetries; attempt++ {
+			t.reportStatus(TunnelReconnecting, attempt, lastErr)
+
+			select {
+			case <-t.done:
+				return false
+			case <-time.After(delay):
+			}
+
+			clients, err := t.dial()
+			if err == nil {
+				if !t.setClients(clients) {
+					return false
+				}
+				// Forwarders that hit the old client may have signalled meanwhile
+				select {
+				case <-t.broken:
+				default:
+				}
+				t.reportStatus(TunnelConnected, 0, nil)
+				return true
+			}
+			lastErr = err
+
+			delay *= 2
+			if delay > reconnectMaxDelay {
+				delay = reconnectMaxDelay
+			}
+		}
+
+		t.reportStatus(TunnelFailed, 0, lastErr)
+
+		// Drop any stale signal, then wait for new demand before trying again
+		select {
+		case <-t.broken:
+		default:
+		}
+		select {
+		case <-t.done:
+			return false
+		case <-t.broken:
+		}
+	}
+}
+
+// markBroken tells the supervisor that forwarding through client failed
+func (t *SSHTunnel) markBroken() {
+	select {
+	case t.broken <- struct{}{}:
+	default:
+	}
+}
+
+// dialRemote opens a channel to the database through the current client. If
+// the client is dead it asks the supervisor to reconnect and waits for it.
+func (t *SSHTunnel) dialRemote() (net.Conn, error) {
+	deadline := time.NewTimer(forwardWaitTimeout)
+	defer deadline.Stop()
+
+	for {
+		t.mu.Lock()
+		client := t.client
+		ready := t.ready
+		t.mu.Unlock()
+
+		var wait <-chan struct{} = ready
+		if client != nil {
+			conn, err := client.Dial("tcp", t.remoteAddr)
+			if err == nil {
+				return conn, nil
+			}
+			// The server answered but refused the channel, so SSH itself is fine
+			var openErr *ssh.OpenChannelError
+			if errors.As(err, &openErr) {
+				return nil, err
+			}
+			// ready is still closed for the dead client; poll until it's replaced
+			wait = nil
+		}
+		t.markBroken()
+
+		select {
+		case <-t.done:
+			return nil, fmt.Errorf("tunnel closed")
+		case <-deadline.C:
+			return nil, fmt.Errorf("timed out waiting for SSH tunnel to reconnect")
+		case <-wait:
+		case <-time.After(time.Second):
+		}
+	}
+}
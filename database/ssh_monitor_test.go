@@ -0,0 +1,107 @@
+package database
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// startTestTunnel opens a password-authenticated tunnel through server to
+// port, reporting state changes on the returned channel
+func startTestTunnel(t *testing.T, server *testSSHServer, port int) (*SSHTunnel, string, <-chan TunnelStatus) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	config := ConnectionConfig{
+		Host:         "127.0.0.1",
+		Port:         port,
+		UseSSHTunnel: true,
+		SSHHost:      "127.0.0.1",
+		SSHPort:      server.port(),
+		SSHUser:      "app",
+		SSHPassword:  "secret",
+	}
+
+	tunnel, err := NewSSHTunnel(config, func(HostKeyPrompt) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(chan TunnelStatus, 16)
+	tunnel.onStatus = func(state string, attempt int, err error) {
+		statuses <- TunnelStatus{State: state, Attempt: attempt}
+	}
+
+	addr, err := tunnel.Start(config)
+	if err != nil {
+		tunnel.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tunnel.Close() })
+	if got := nextStatus(t, statuses); got.State != TunnelConnected {
+		t.Fatalf("status after Start = %+v", got)
+	}
+	return tunnel, addr, statuses
+}
+
+// nextStatus waits for the tunnel's next state change
+func nextStatus(t *testing.T, statuses <-chan TunnelStatus) TunnelStatus {
+	t.Helper()
+	select {
+	case status := <-statuses:
+		return status
+	case <-time.After(10 * time.Second):
+		t.Fatal("no tunnel status reported")
+		return TunnelStatus{}
+	}
+}
+
+func TestSSHTunnelReconnect(t *testing.T) {
+	server := newTestSSHServer(t, nil)
+	tunnel, addr, statuses := startTestTunnel(t, server, startEchoServer(t))
+	checkEcho(t, addr)
+
+	server.dropConnections()
+
+	want := []TunnelStatus{{State: TunnelReconnecting, Attempt: 1}, {State: TunnelConnected}}
+	for _, w := range want {
+		if got := nextStatus(t, statuses); got != w {
+			t.Fatalf("status = %+v, want %+v", got, w)
+		}
+	}
+
+	// The local address survives, so the database pool needn't change
+	if tunnel.LocalAddr() != addr {
+		t.Errorf("local address changed from %s to %s", addr, tunnel.LocalAddr())
+	}
+	checkEcho(t, addr)
+}
+
+func TestSSHTunnelRefusedTarget(t *testing.T) {
+	// Nothing listens on the target port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	server := newTestSSHServer(t, nil)
+	_, addr, statuses := startTestTunnel(t, server, port)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read from a refused forward = %v, want EOF", err)
+	}
+
+	// A refused channel says nothing about the SSH connection itself
+	select {
+	case status := <-statuses:
+		t.Errorf("tunnel reported %+v for a refused channel", status)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
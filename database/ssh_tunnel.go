@@ -1,108 +1,306 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"strconv"
 	"sync"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 // SSHTunnel represents an SSH tunnel connection
 type SSHTunnel struct {
 	localAddr  string
 	remoteAddr string
-	config     *ssh.ClientConfig
-	client     *ssh.Client
+	hostKeys   ssh.HostKeyCallback
+	hops       []sshHop
+	agentConn  net.Conn
 	listener   net.Listener
 	done       chan struct{}
+	closeOnce  sync.Once
 	wg         sync.WaitGroup
+
+	// Guarded by mu; replaced when the tunnel reconnects
+	mu      sync.Mutex
+	clients []*ssh.Client // One per hop; the last one reaches the database
+	client  *ssh.Client
+	ready   chan struct{} // Closed while client is usable
+	broken  chan struct{} // Signalled when forwarding finds client dead
+
+	onStatus func(state string, attempt int, err error)
 }
 
-// NewSSHTunnel creates a new SSH tunnel from the connection config
-func NewSSHTunnel(config ConnectionConfig) (*SSHTunnel, error) {
+// sshHop is one SSH server on the way to the database
+type sshHop struct {
+	addr   string
+	config *ssh.ClientConfig
+}
+
+// NewSSHTunnel creates a new SSH tunnel from the connection config. Host keys
+// are checked against known_hosts; approve is asked about unknown hosts.
+func NewSSHTunnel(config ConnectionConfig, approve HostKeyApprover) (*SSHTunnel, error) {
 	if !config.UseSSHTunnel {
 		return nil, nil
 	}
 
-	sshConfig := &ssh.ClientConfig{
-		User:            config.SSHUser,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // TODO: Add proper host key verification
+	hostKeys, err := hostKeyCallback(approve)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSHTunnel{
+		// Remote database address (what we're tunneling to)
+		remoteAddr: net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		hostKeys:   hostKeys,
+		done:       make(chan struct{}),
+		ready:      make(chan struct{}),
+		broken:     make(chan struct{}, 1),
+	}, nil
+}
+
+// buildHops resolves the SSH settings of config, including its ssh_config
+// alias, into the chain of servers to dial
+func (t *SSHTunnel) buildHops(config ConnectionConfig) error {
+	config, _, err := applySSHConfigHost(config)
+	if err != nil {
+		return err
+	}
+
+	target := SSHJumpHost{
+		Host:       config.SSHHost,
+		Port:       config.SSHPort,
+		User:       config.SSHUser,
+		Password:   config.SSHPassword,
+		PrivateKey: config.SSHPrivateKey,
+		Passphrase: config.SSHPassphrase,
+		UseAgent:   config.SSHUseAgent,
+	}
+
+	t.hops = nil
+	for _, host := range append(append([]SSHJumpHost{}, config.SSHJumpHosts...), target) {
+		hop, err := t.newHop(host)
+		if err != nil {
+			return err
+		}
+		t.hops = append(t.hops, hop)
+	}
+	return nil
+}
+
+// newHop builds the client config for one SSH server
+func (t *SSHTunnel) newHop(host SSHJumpHost) (sshHop, error) {
+	port := host.Port
+	if port == 0 {
+		port = 22
 	}
+	addr := net.JoinHostPort(host.Host, strconv.Itoa(port))
 
 	// Configure authentication
 	var authMethods []ssh.AuthMethod
 
-	// Private key authentication
-	if config.SSHPrivateKey != "" {
-		var signer ssh.Signer
-		var err error
-
-		if config.SSHPassphrase != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(config.SSHPrivateKey), []byte(config.SSHPassphrase))
-		} else {
-			signer, err = ssh.ParsePrivateKey([]byte(config.SSHPrivateKey))
+	// Agent authentication
+	if host.UseAgent {
+		signers, err := t.agentSigners()
+		if err != nil {
+			return sshHop{}, err
 		}
+		authMethods = append(authMethods, ssh.PublicKeysCallback(signers))
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse private key: %w", err)
+	// Private key authentication
+	if host.PrivateKey != "" {
+		signer, err := loadPrivateKey(host.PrivateKey, host.Passphrase)
+		switch {
+		case errors.Is(err, errPassphraseRequired) && host.UseAgent:
+			// The agent usually holds the unlocked copy of an encrypted key
+		case err != nil:
+			return sshHop{}, fmt.Errorf("%s: %w", addr, err)
+		default:
+			authMethods = append(authMethods, ssh.PublicKeys(signer))
 		}
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
 	}
 
 	// Password authentication
-	if config.SSHPassword != "" {
-		authMethods = append(authMethods, ssh.Password(config.SSHPassword))
+	if host.Password != "" {
+		authMethods = append(authMethods, ssh.Password(host.Password))
 	}
 
 	if len(authMethods) == 0 {
-		return nil, fmt.Errorf("no SSH authentication method provided")
+		return sshHop{}, fmt.Errorf("no SSH authentication method provided for %s", addr)
 	}
 
-	sshConfig.Auth = authMethods
+	return sshHop{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            host.User,
+			Auth:            authMethods,
+			HostKeyCallback: t.hostKeys,
+		},
+	}, nil
+}
+
+// agentSigners returns the signer source of the running ssh-agent. One agent
+// connection is shared by every hop of the tunnel.
+func (t *SSHTunnel) agentSigners() (func() ([]ssh.Signer, error), error) {
+	if t.agentConn == nil {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, fmt.Errorf("ssh-agent is not available: SSH_AUTH_SOCK is not set")
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+		}
+		t.agentConn = conn
+	}
+	return agent.NewClient(t.agentConn).Signers, nil
+}
 
-	// Remote database address (what we're tunneling to)
-	remoteAddr := fmt.Sprintf("%s:%d", config.Host, config.Port)
+// errPassphraseRequired is returned for an encrypted key without a passphrase
+var errPassphraseRequired = errors.New("private key is encrypted; a passphrase is required")
 
-	return &SSHTunnel{
-		remoteAddr: remoteAddr,
-		config:     sshConfig,
-		done:       make(chan struct{}),
-	}, nil
+// loadPrivateKey parses a private key given as PEM content or a file path
+func loadPrivateKey(key, passphrase string) (ssh.Signer, error) {
+	pemBytes, err := loadPEM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	var signer ssh.Signer
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(pemBytes)
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, errPassphraseRequired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return signer, nil
 }
 
 // Start starts the SSH tunnel and returns the local address to connect to
 func (t *SSHTunnel) Start(config ConnectionConfig) (string, error) {
-	sshPort := config.SSHPort
-	if sshPort == 0 {
-		sshPort = 22
+	if err := t.buildHops(config); err != nil {
+		return "", err
 	}
-	sshAddr := fmt.Sprintf("%s:%d", config.SSHHost, sshPort)
 
-	// Connect to SSH server
-	client, err := ssh.Dial("tcp", sshAddr, t.config)
+	clients, err := t.dial()
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to SSH server: %w", err)
+		return "", err
 	}
-	t.client = client
+	t.setClients(clients)
 
 	// Create local listener on random port
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		client.Close()
+		t.closeClients()
 		return "", fmt.Errorf("failed to create local listener: %w", err)
 	}
 	t.listener = listener
 	t.localAddr = listener.Addr().String()
 
 	// Start accepting connections
-	t.wg.Add(1)
+	t.wg.Add(2)
 	go t.acceptConnections()
+	go t.supervise()
 
+	t.reportStatus(TunnelConnected, 0, nil)
 	return t.localAddr, nil
 }
 
+// dial connects to each hop in turn, opening every hop after the first
+// through the previous one. The last client returned reaches the database.
+func (t *SSHTunnel) dial() ([]*ssh.Client, error) {
+	var clients []*ssh.Client
+	fail := func(err error) ([]*ssh.Client, error) {
+		for i := len(clients) - 1; i >= 0; i-- {
+			clients[i].Close()
+		}
+		return nil, err
+	}
+
+	for _, hop := range t.hops {
+		if len(clients) == 0 {
+			c, err := ssh.Dial("tcp", hop.addr, hop.config)
+			if err != nil {
+				return fail(fmt.Errorf("failed to connect to SSH server %s: %w", hop.addr, err))
+			}
+			clients = append(clients, c)
+			continue
+		}
+
+		conn, err := clients[len(clients)-1].Dial("tcp", hop.addr)
+		if err != nil {
+			return fail(fmt.Errorf("failed to reach SSH server %s through jump host: %w", hop.addr, err))
+		}
+		clientConn, chans, reqs, err := ssh.NewClientConn(conn, hop.addr, hop.config)
+		if err != nil {
+			conn.Close()
+			return fail(fmt.Errorf("failed to connect to SSH server %s: %w", hop.addr, err))
+		}
+		clients = append(clients, ssh.NewClient(clientConn, chans, reqs))
+	}
+	return clients, nil
+}
+
+// setClients installs a freshly dialed hop chain and wakes waiting forwarders.
+// It returns false, closing the clients, if the tunnel was closed meanwhile.
+func (t *SSHTunnel) setClients(clients []*ssh.Client) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		for i := len(clients) - 1; i >= 0; i-- {
+			clients[i].Close()
+		}
+		return false
+	default:
+	}
+
+	t.clients = clients
+	t.client = clients[len(clients)-1]
+	close(t.ready)
+	return true
+}
+
+// closeClients closes the hop clients, innermost first, and marks the tunnel
+// as not ready until the next setClients
+func (t *SSHTunnel) closeClients() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := len(t.clients) - 1; i >= 0; i-- {
+		t.clients[i].Close()
+	}
+	t.clients = nil
+	t.client = nil
+
+	select {
+	case <-t.ready:
+		t.ready = make(chan struct{})
+	default:
+	}
+}
+
+// closeAgent releases the ssh-agent connection, if any
+func (t *SSHTunnel) closeAgent() {
+	if t.agentConn != nil {
+		t.agentConn.Close()
+		t.agentConn = nil
+	}
+}
+
 // acceptConnections handles incoming connections to the tunnel
 func (t *SSHTunnel) acceptConnections() {
 	defer t.wg.Done()
@@ -134,8 +332,8 @@ func (t *SSHTunnel) handleConnection(localConn net.Conn) {
 	defer t.wg.Done()
 	defer localConn.Close()
 
-	// Connect to remote through SSH
-	remoteConn, err := t.client.Dial("tcp", t.remoteAddr)
+	// Connect to remote through SSH, waiting out a reconnect if needed
+	remoteConn, err := t.dialRemote()
 	if err != nil {
 		return
 	}
@@ -157,19 +355,20 @@ func (t *SSHTunnel) handleConnection(localConn net.Conn) {
 	<-done
 }
 
-// Close shuts down the SSH tunnel
+// Close shuts down the SSH tunnel. Calls after the first do nothing.
 func (t *SSHTunnel) Close() error {
-	close(t.done)
+	t.closeOnce.Do(func() {
+		close(t.done)
 
-	if t.listener != nil {
-		t.listener.Close()
-	}
+		if t.listener != nil {
+			t.listener.Close()
+		}
 
-	if t.client != nil {
-		t.client.Close()
-	}
+		t.closeClients()
+		t.closeAgent()
 
-	t.wg.Wait()
+		t.wg.Wait()
+	})
 	return nil
 }
 
@@ -0,0 +1,369 @@
+package database
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// testSSHServer is an in-process SSH server that accepts the password
+// "secret" or one authorized key and forwards direct-tcpip channels
+type testSSHServer struct {
+	addr net.Addr
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newTestSSHServer(t *testing.T, authorized ssh.PublicKey) *testSSHServer {
+	t.Helper()
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "app" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if authorized != nil && conn.User() == "app" && string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &testSSHServer{addr: ln.Addr()}
+	t.Cleanup(func() {
+		ln.Close()
+		srv.dropConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			srv.mu.Lock()
+			srv.conns = append(srv.conns, conn)
+			srv.mu.Unlock()
+			go srv.serve(conn, config)
+		}
+	}()
+	return srv
+}
+
+func (srv *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only forwarding is supported")
+			continue
+		}
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, fmt.Sprint(target.Port)))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			defer channel.Close()
+			defer remote.Close()
+			go io.Copy(remote, channel)
+			io.Copy(channel, remote)
+		}()
+	}
+}
+
+// dropConnections closes every SSH connection made so far, as a server
+// restart or network failure would
+func (srv *testSSHServer) dropConnections() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, conn := range srv.conns {
+		conn.Close()
+	}
+	srv.conns = nil
+}
+
+// port returns the port the server listens on
+func (srv *testSSHServer) port() int {
+	return srv.addr.(*net.TCPAddr).Port
+}
+
+// startEchoServer runs a TCP server that echoes what it receives and returns
+// its port
+func startEchoServer(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// checkEcho sends a line through addr and expects it back
+func checkEcho(t *testing.T, addr string) {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 5)
+	if _, err := io.ReadFull(conn, b); err != nil || string(b) != "ping\n" {
+		t.Fatalf("echo through the tunnel = %q, %v", b, err)
+	}
+}
+
+// newTestClientKey returns a new private key as PEM, optionally encrypted,
+// and its signer
+func newTestClientKey(t *testing.T, passphrase string) (string, ssh.Signer) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(block)), signer
+}
+
+// startTestAgent serves an ssh-agent holding key on a socket named by
+// SSH_AUTH_SOCK
+func startTestAgent(t *testing.T, key interface{}) {
+	t.Helper()
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	t.Setenv("SSH_AUTH_SOCK", sock)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	plain, _ := newTestClientKey(t, "")
+	encrypted, _ := newTestClientKey(t, "hunter2")
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, []byte(plain), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		passphrase string
+		wantErr    error
+		wantErrMsg string
+	}{
+		{name: "inline PEM", key: plain},
+		{name: "file path", key: path},
+		{name: "encrypted", key: encrypted, passphrase: "hunter2"},
+		{name: "encrypted without passphrase", key: encrypted, wantErr: errPassphraseRequired},
+		{name: "wrong passphrase", key: encrypted, passphrase: "nope", wantErrMsg: "failed to parse private key"},
+		{name: "missing file", key: filepath.Join(t.TempDir(), "missing"), wantErrMsg: "failed to read private key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := loadPrivateKey(tt.key, tt.passphrase)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantErrMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErrMsg)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case signer.PublicKey().Type() != ssh.KeyAlgoED25519:
+				t.Errorf("key type = %s", signer.PublicKey().Type())
+			}
+		})
+	}
+}
+
+func TestSSHTunnel(t *testing.T) {
+	keyPEM, signer := newTestClientKey(t, "")
+	encryptedPEM, encryptedSigner := newTestClientKey(t, "hunter2")
+	_, agentPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentSigner, err := ssh.NewSignerFromKey(agentPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     ssh.PublicKey // Key the servers accept
+		agent   bool          // Run an ssh-agent holding agentPriv
+		jump    bool          // Reach the server through a jump host
+		target  SSHJumpHost   // Credentials, applied to every hop
+		wantErr string
+	}{
+		{name: "password", target: SSHJumpHost{User: "app", Password: "secret"}},
+		{name: "key", key: signer.PublicKey(), target: SSHJumpHost{User: "app", PrivateKey: keyPEM}},
+		{name: "encrypted key", key: encryptedSigner.PublicKey(), target: SSHJumpHost{User: "app", PrivateKey: encryptedPEM, Passphrase: "hunter2"}},
+		{name: "agent", key: agentSigner.PublicKey(), agent: true, target: SSHJumpHost{User: "app", UseAgent: true}},
+		{
+			name:   "encrypted key left to the agent",
+			key:    agentSigner.PublicKey(),
+			agent:  true,
+			target: SSHJumpHost{User: "app", UseAgent: true, PrivateKey: encryptedPEM},
+		},
+		{name: "jump host", jump: true, key: signer.PublicKey(), target: SSHJumpHost{User: "app", PrivateKey: keyPEM}},
+		{name: "wrong password", target: SSHJumpHost{User: "app", Password: "wrong"}, wantErr: "unable to authenticate"},
+		{name: "no credentials", target: SSHJumpHost{User: "app"}, wantErr: "no SSH authentication method"},
+		{name: "agent not running", target: SSHJumpHost{User: "app", UseAgent: true}, wantErr: "SSH_AUTH_SOCK"},
+		{name: "encrypted key without passphrase", target: SSHJumpHost{User: "app", PrivateKey: encryptedPEM}, wantErr: "passphrase is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("SSH_AUTH_SOCK", "")
+			if tt.agent {
+				startTestAgent(t, agentPriv)
+			}
+			echoPort := startEchoServer(t)
+			server := newTestSSHServer(t, tt.key)
+
+			config := ConnectionConfig{
+				Host:          "127.0.0.1",
+				Port:          echoPort,
+				UseSSHTunnel:  true,
+				SSHHost:       "127.0.0.1",
+				SSHPort:       server.port(),
+				SSHUser:       tt.target.User,
+				SSHPassword:   tt.target.Password,
+				SSHPrivateKey: tt.target.PrivateKey,
+				SSHPassphrase: tt.target.Passphrase,
+				SSHUseAgent:   tt.target.UseAgent,
+			}
+			if tt.jump {
+				jump := newTestSSHServer(t, tt.key)
+				hop := tt.target
+				hop.Host = "127.0.0.1"
+				hop.Port = jump.port()
+				config.SSHJumpHosts = []SSHJumpHost{hop}
+			}
+
+			approve := func(HostKeyPrompt) bool { return true }
+			tunnel, err := NewSSHTunnel(config, approve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tunnel.Close()
+
+			addr, err := tunnel.Start(config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEcho(t, addr)
+		})
+	}
+}
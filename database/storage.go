@@ -1,15 +1,30 @@
 package database
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
 )
 
-// Storage handles saving and loading connections
+// Storage handles saving and loading connections. Secret fields never reach
+// connections.json; they live in the OS keyring or, when none is available,
+// in a vault encrypted with a master password.
 type Storage struct {
 	configPath string
+	lockPath   string
+	backupDir  string
+	secrets    SecretStore
+	vault      *Vault     // nil when secrets go to the OS keyring
+	mu         sync.Mutex // Serializes file access within this process; see withFileLock
+
+	// Mirrors connectionsFile.LegacySecretKeys between a read and the next write
+	legacySecretKeys bool
 }
 
 // NewStorage creates a new storage instance
@@ -24,119 +39,413 @@ func NewStorage() (*Storage, error) {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	return &Storage{
+	s := &Storage{
 		configPath: filepath.Join(configDir, "connections.json"),
-	}, nil
+		lockPath:   filepath.Join(configDir, "connections.lock"),
+		backupDir:  filepath.Join(configDir, "backups"),
+	}
+	if keyringAvailable() {
+		s.secrets = keyringStore{}
+	} else {
+		s.vault = NewVault(filepath.Join(configDir, "vault.json"))
+		s.secrets = s.vault
+	}
+
+	return s, nil
 }
 
-// SaveConnection saves a connection with a name
-func (s *Storage) SaveConnection(name string, config ConnectionConfig) error {
-	connections, err := s.LoadConnections()
+// SecretBackend reports where secrets are kept: "keyring" or "vault"
+func (s *Storage) SecretBackend() string {
+	if s.vault != nil {
+		return SecretBackendVault
+	}
+	return SecretBackendKeyring
+}
+
+// IsLocked reports whether secrets are unavailable until Unlock is called
+func (s *Storage) IsLocked() bool {
+	return s.vault != nil && s.vault.IsLocked()
+}
+
+// Unlock opens the vault with the master password, creating it on first use,
+// and moves any plaintext secrets still in connections.json into it
+func (s *Storage) Unlock(password string) error {
+	if s.vault == nil {
+		return nil
+	}
+	if err := s.vault.Unlock(password); err != nil {
+		return err
+	}
+	return s.withFileLock(func() error {
+		if _, err := s.readConnections(); err != nil {
+			return err
+		}
+		return s.migrateSecrets()
+	})
+}
+
+// Lock forgets the vault key; saved connections load without secrets until
+// the next Unlock
+func (s *Storage) Lock() {
+	if s.vault != nil {
+		s.vault.Lock()
+	}
+}
+
+// secretKey is the secret store entry holding a connection's secrets
+func secretKey(id string) string {
+	return "connection:" + id
+}
+
+// SaveConnection creates a connection when conn.ID is empty and otherwise
+// updates the connection with that ID. Timestamps are managed here; the
+// stored connection is returned.
+func (s *Storage) SaveConnection(conn SavedConnection) (*SavedConnection, error) {
+	var saved SavedConnection
+	err := s.withFileLock(func() error {
+		var err error
+		saved, err = s.saveConnection(conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (s *Storage) saveConnection(conn SavedConnection) (SavedConnection, error) {
+	if err := normalizeConnection(&conn); err != nil {
+		return conn, err
+	}
+
+	connections, err := s.readConnections()
 	if err != nil {
-		connections = []SavedConnection{}
+		return conn, err
 	}
 
-	// Update existing or add new
-	found := false
-	for i, c := range connections {
-		if c.Name == name {
-			connections[i].Config = config
-			found = true
-			break
+	index := -1
+	if conn.ID == "" {
+		conn.ID = uuid.NewString()
+		conn.CreatedAt = time.Now().UTC()
+		conn.LastUsedAt = nil
+		conn.SortOrder = nextSortOrder(connections, conn.Folder)
+	} else {
+		index = findConnection(connections, conn.ID)
+		if index < 0 {
+			return conn, fmt.Errorf("connection not found: %s", conn.ID)
+		}
+		existing := connections[index]
+		conn.CreatedAt = existing.CreatedAt
+		conn.LastUsedAt = existing.LastUsedAt
+		if conn.Folder != existing.Folder {
+			conn.SortOrder = nextSortOrder(connections, conn.Folder)
 		}
 	}
 
-	if !found {
-		connections = append(connections, SavedConnection{
-			Name:   name,
-			Config: config,
-		})
+	if len(conn.MissingSecrets) > 0 && !s.IsLocked() {
+		conn.MissingSecrets = missingSecrets(conn.Config, conn.MissingSecrets)
 	}
 
-	return s.saveConnections(connections)
+	withSecrets := conn
+	if err := s.storeSecrets(conn.ID, &conn.Config); err != nil {
+		return conn, err
+	}
+
+	if index < 0 {
+		connections = append(connections, conn)
+	} else {
+		connections[index] = conn
+	}
+
+	if err := s.saveConnections(connections); err != nil {
+		return conn, err
+	}
+	return withSecrets, nil
 }
 
-// LoadConnections loads all saved connections
-func (s *Storage) LoadConnections() ([]SavedConnection, error) {
-	data, err := os.ReadFile(s.configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []SavedConnection{}, nil
+// storeSecrets moves the secrets of config into the secret store under id
+func (s *Storage) storeSecrets(id string, config *ConnectionConfig) error {
+	secrets := extractSecrets(config)
+	if s.IsLocked() {
+		// Configs loaded while locked come without secrets; keep the stored ones
+		if !secrets.empty() {
+			return ErrVaultLocked
 		}
-		return nil, fmt.Errorf("failed to read connections: %w", err)
+		return nil
 	}
+	return saveSecrets(s.secrets, secretKey(id), secrets)
+}
 
+// LoadConnections loads all saved connections, ordered by folder and sort
+// order. Secrets are filled in from the secret store; while the vault is
+// locked they are left empty.
+func (s *Storage) LoadConnections() ([]SavedConnection, error) {
 	var connections []SavedConnection
-	if err := json.Unmarshal(data, &connections); err != nil {
-		return nil, fmt.Errorf("failed to parse connections: %w", err)
+	err := s.withFileLock(func() error {
+		var err error
+		connections, err = s.loadConnections()
+		return err
+	})
+	return connections, err
+}
+
+func (s *Storage) loadConnections() ([]SavedConnection, error) {
+	connections, err := s.readConnections()
+	if err != nil {
+		return nil, err
+	}
+	sortConnections(connections)
+
+	if s.IsLocked() {
+		return connections, nil
+	}
+
+	// Move secrets left by older versions: plaintext ones out of the file and
+	// name-keyed ones over to IDs
+	if err := s.migrateSecrets(); err != nil {
+		return nil, err
+	}
+	if err := s.migrateSecretKeys(connections); err != nil {
+		return nil, err
+	}
+
+	for i := range connections {
+		extractSecrets(&connections[i].Config)
+		secrets, err := loadSecrets(s.secrets, secretKey(connections[i].ID))
+		if err != nil {
+			return nil, fmt.Errorf("connection %s: %w", connections[i].Name, err)
+		}
+		secrets.apply(&connections[i].Config)
 	}
 
 	return connections, nil
 }
 
 // DeleteConnection removes a saved connection
-func (s *Storage) DeleteConnection(name string) error {
-	connections, err := s.LoadConnections()
+func (s *Storage) DeleteConnection(id string) error {
+	return s.withFileLock(func() error {
+		return s.deleteConnection(id)
+	})
+}
+
+func (s *Storage) deleteConnection(id string) error {
+	connections, err := s.readConnections()
 	if err != nil {
 		return err
 	}
 
-	var filtered []SavedConnection
-	for _, c := range connections {
-		if c.Name != name {
-			filtered = append(filtered, c)
-		}
+	index := findConnection(connections, id)
+	if index < 0 {
+		return fmt.Errorf("connection not found: %s", id)
+	}
+
+	if err := s.secrets.Delete(secretKey(id)); err != nil {
+		return fmt.Errorf("failed to delete secrets: %w", err)
 	}
 
-	return s.saveConnections(filtered)
+	connections = append(connections[:index], connections[index+1:]...)
+	return s.saveConnections(connections)
 }
 
 // GetConnection returns a specific saved connection
-func (s *Storage) GetConnection(name string) (*SavedConnection, error) {
+func (s *Storage) GetConnection(id string) (*SavedConnection, error) {
 	connections, err := s.LoadConnections()
 	if err != nil {
 		return nil, err
 	}
 
 	for _, c := range connections {
-		if c.Name == name {
+		if c.ID == id {
 			return &c, nil
 		}
 	}
 
-	return nil, fmt.Errorf("connection not found: %s", name)
+	return nil, fmt.Errorf("connection not found: %s", id)
+}
+
+// ConnectConfig prepares config for connecting to the saved connection id.
+// The settings that guard a connection, read-only and production, and
+// whether its ${NAME} references are expanded come from the stored
+// connection, not from config; config only supplies values such as secrets
+// entered when prompted. Secrets still missing after an import are refused
+// with a MissingSecretsError before anything is dialed.
+func (s *Storage) ConnectConfig(id string, config ConnectionConfig) (ConnectionConfig, error) {
+	conn, err := s.storedConnection(id)
+	if err != nil {
+		return config, err
+	}
+	if missing := missingSecrets(config, conn.MissingSecrets); len(missing) > 0 {
+		return config, &MissingSecretsError{Fields: missing}
+	}
+
+	config.ReadOnly = conn.Config.ReadOnly
+	config.Production = isProduction(&conn.Config)
+	config.Color = conn.Config.Color
+	config.EnvReferences = conn.Config.EnvReferences
+	return config, nil
+}
+
+// storedConnection returns the saved connection id as connections.json holds
+// it, without reading the secret store
+func (s *Storage) storedConnection(id string) (*SavedConnection, error) {
+	var conn SavedConnection
+	err := s.withFileLock(func() error {
+		connections, err := s.readConnections()
+		if err != nil {
+			return err
+		}
+		index := findConnection(connections, id)
+		if index < 0 {
+			return fmt.Errorf("connection not found: %s", id)
+		}
+		conn = connections[index]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	extractSecrets(&conn.Config)
+	return &conn, nil
 }
 
 // RenameConnection renames a saved connection
-func (s *Storage) RenameConnection(oldName, newName string) error {
-	connections, err := s.LoadConnections()
+func (s *Storage) RenameConnection(id, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("connection name is required")
+	}
+
+	return s.withFileLock(func() error {
+		connections, err := s.readConnections()
+		if err != nil {
+			return err
+		}
+
+		index := findConnection(connections, id)
+		if index < 0 {
+			return fmt.Errorf("connection not found: %s", id)
+		}
+		connections[index].Name = newName
+
+		return s.saveConnections(connections)
+	})
+}
+
+// readConnections reads connections.json as stored, without secrets. A file
+// written by an older version is upgraded on disk right away, so the IDs it
+// is given stay stable. Callers hold the file lock.
+func (s *Storage) readConnections() ([]SavedConnection, error) {
+	file, migrated, err := s.readFile()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	s.legacySecretKeys = file.LegacySecretKeys
+
+	// Connections saved before the production flag existed only have their
+	// environment
+	for i := range file.Connections {
+		if file.Connections[i].Environment == EnvironmentProduction {
+			file.Connections[i].Config.Production = true
+		}
 	}
 
-	found := false
-	for i, c := range connections {
-		if c.Name == oldName {
-			connections[i].Name = newName
-			found = true
-			break
+	if migrated {
+		if err := s.writeConnections(file.Connections); err != nil {
+			return nil, err
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("connection not found: %s", oldName)
+	return file.Connections, nil
+}
+
+// migrateSecrets moves plaintext secrets left in connections.json by older
+// versions into the secret store and rewrites the file without them
+func (s *Storage) migrateSecrets() error {
+	connections, err := s.readConnections()
+	if err != nil {
+		return err
 	}
 
+	migrated := false
+	for i := range connections {
+		secrets := extractSecrets(&connections[i].Config)
+		if secrets.empty() {
+			continue
+		}
+		if err := saveSecrets(s.secrets, secretKey(connections[i].ID), secrets); err != nil {
+			if errors.Is(err, ErrVaultLocked) {
+				return nil
+			}
+			return fmt.Errorf("failed to migrate secrets of %s: %w", connections[i].Name, err)
+		}
+		migrated = true
+	}
+
+	if !migrated {
+		return nil
+	}
 	return s.saveConnections(connections)
 }
 
+// migrateSecretKeys moves secrets stored under connection names, as older
+// versions did, to the connections' IDs
+func (s *Storage) migrateSecretKeys(connections []SavedConnection) error {
+	if !s.legacySecretKeys {
+		return nil
+	}
+
+	for _, c := range connections {
+		secrets, err := loadSecrets(s.secrets, secretKey(c.Name))
+		if err != nil {
+			return fmt.Errorf("failed to migrate secrets of %s: %w", c.Name, err)
+		}
+		if secrets.empty() {
+			continue
+		}
+
+		// Secrets just moved out of a plaintext file are newer
+		current, err := loadSecrets(s.secrets, secretKey(c.ID))
+		if err != nil {
+			return fmt.Errorf("failed to migrate secrets of %s: %w", c.Name, err)
+		}
+		if current.empty() {
+			if err := saveSecrets(s.secrets, secretKey(c.ID), secrets); err != nil {
+				return fmt.Errorf("failed to migrate secrets of %s: %w", c.Name, err)
+			}
+		}
+		if err := s.secrets.Delete(secretKey(c.Name)); err != nil {
+			return fmt.Errorf("failed to migrate secrets of %s: %w", c.Name, err)
+		}
+	}
+
+	connections, err := s.readConnections()
+	if err != nil {
+		return err
+	}
+	s.legacySecretKeys = false
+	return s.writeConnections(connections)
+}
+
+// saveConnections writes connections.json atomically after backing up the
+// current version. Callers hold the file lock.
 func (s *Storage) saveConnections(connections []SavedConnection) error {
-	data, err := json.MarshalIndent(connections, "", "  ")
+	if err := s.backupConnections(); err != nil {
+		return err
+	}
+	return s.writeConnections(connections)
+}
+
+// writeConnections writes connections.json atomically without taking a backup
+func (s *Storage) writeConnections(connections []SavedConnection) error {
+	data, err := encodeConnections(connectionsFile{
+		Connections:      connections,
+		LegacySecretKeys: s.legacySecretKeys,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to marshal connections: %w", err)
 	}
 
-	if err := os.WriteFile(s.configPath, data, 0644); err != nil {
+	if err := writeFileAtomic(s.configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write connections: %w", err)
 	}
 
@@ -0,0 +1,334 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// connectionsSchemaVersion is the version of connections.json written by this build
+const connectionsSchemaVersion = 3
+
+// maxConnectionBackups is how many previous versions of connections.json are kept
+const maxConnectionBackups = 10
+
+// Backup file names look like connections-20060102-150405.000000000.json
+const (
+	backupPrefix     = "connections-"
+	backupSuffix     = ".json"
+	backupTimeLayout = "20060102-150405.000000000"
+)
+
+// connectionsFile is the on-disk layout of connections.json
+type connectionsFile struct {
+	Version     int               `json:"version"`
+	Connections []SavedConnection `json:"connections"`
+
+	// Set while secrets are still filed under connection names rather than
+	// IDs; cleared once they have been moved
+	LegacySecretKeys bool `json:"legacySecretKeys,omitempty"`
+}
+
+// connectionsMigrations upgrade a raw connections.json from the keyed version
+// to the next one
+var connectionsMigrations = map[int]func([]byte) ([]byte, error){
+	1: migrateConnectionsV1,
+	2: migrateConnectionsV2,
+}
+
+// migrateConnectionsV1 wraps the bare array of version 1 in a versioned object
+func migrateConnectionsV1(data []byte) ([]byte, error) {
+	var connections []json.RawMessage
+	if err := json.Unmarshal(data, &connections); err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"version":     2,
+		"connections": connections,
+	})
+}
+
+// migrateConnectionsV2 gives every connection an ID, a creation time and a
+// sort order matching its position in the list
+func migrateConnectionsV2(data []byte) ([]byte, error) {
+	var file struct {
+		Connections []map[string]json.RawMessage `json:"connections"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	now, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range file.Connections {
+		id, err := json.Marshal(uuid.NewString())
+		if err != nil {
+			return nil, err
+		}
+		c["id"] = id
+		c["createdAt"] = now
+		c["sortOrder"] = json.RawMessage(strconv.Itoa(i))
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"version":          3,
+		"connections":      file.Connections,
+		"legacySecretKeys": true,
+	})
+}
+
+// BackupInfo describes a saved copy of connections.json
+type BackupInfo struct {
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Size        int64     `json:"size"`
+	Connections int       `json:"connections"`
+}
+
+// decodeConnections parses connections.json of any known version, running
+// the migrations needed to reach the current one. migrated reports whether
+// any migration ran.
+func decodeConnections(data []byte) (file *connectionsFile, migrated bool, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return &connectionsFile{Version: connectionsSchemaVersion, Connections: []SavedConnection{}}, false, nil
+	}
+
+	// Version 1 was a bare array
+	version := 1
+	if data[0] == '{' {
+		var header struct {
+			Version int `json:"version"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			return nil, false, err
+		}
+		version = header.Version
+	}
+
+	if version > connectionsSchemaVersion {
+		return nil, false, fmt.Errorf("connections file has schema version %d; this version of the app supports up to %d", version, connectionsSchemaVersion)
+	}
+
+	for ; version < connectionsSchemaVersion; version++ {
+		migrate, ok := connectionsMigrations[version]
+		if !ok {
+			return nil, false, fmt.Errorf("no migration from connections schema version %d", version)
+		}
+		if data, err = migrate(data); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate connections from schema version %d: %w", version, err)
+		}
+		migrated = true
+	}
+
+	file = &connectionsFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, false, err
+	}
+	if file.Connections == nil {
+		file.Connections = []SavedConnection{}
+	}
+	return file, migrated, nil
+}
+
+// encodeConnections renders file in the current schema version
+func encodeConnections(file connectionsFile) ([]byte, error) {
+	file.Version = connectionsSchemaVersion
+	if file.Connections == nil {
+		file.Connections = []SavedConnection{}
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// writeFileAtomic replaces path with data so readers see either the old or
+// the new content, never a partial write
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// withFileLock runs fn while holding both the in-process mutex and the lock
+// file shared with other app instances
+func (s *Storage) withFileLock(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock connections: %w", err)
+	}
+	defer unlockFile(f)
+
+	return fn()
+}
+
+// readFile reads and decodes connections.json without side effects
+func (s *Storage) readFile() (*connectionsFile, bool, error) {
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &connectionsFile{Version: connectionsSchemaVersion, Connections: []SavedConnection{}}, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read connections: %w", err)
+	}
+
+	file, migrated, err := decodeConnections(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse connections: %w", err)
+	}
+	return file, migrated, nil
+}
+
+// backupConnections saves the current connections.json into the backup
+// directory and drops the oldest backups beyond maxConnectionBackups. The
+// copy is re-encoded without secrets, so backups never hold plaintext ones.
+func (s *Storage) backupConnections() error {
+	file, _, err := s.readFile()
+	if err != nil || len(file.Connections) == 0 {
+		// Nothing worth keeping, or a file we can't parse
+		return nil
+	}
+	for i := range file.Connections {
+		extractSecrets(&file.Connections[i].Config)
+	}
+
+	data, err := encodeConnections(*file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup: %w", err)
+	}
+
+	if err := os.MkdirAll(s.backupDir, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	name := backupPrefix + time.Now().UTC().Format(backupTimeLayout) + backupSuffix
+	if err := writeFileAtomic(filepath.Join(s.backupDir, name), data, 0600); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	backups, err := s.backupNames()
+	if err != nil {
+		return err
+	}
+	for _, old := range backups[min(len(backups), maxConnectionBackups):] {
+		os.Remove(filepath.Join(s.backupDir, old))
+	}
+	return nil
+}
+
+// backupNames lists backup file names, newest first
+func (s *Storage) backupNames() ([]string, error) {
+	entries, err := os.ReadDir(s.backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			names = append(names, name)
+		}
+	}
+	// The timestamp layout sorts lexically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// ListBackups returns the available backups of connections.json, newest first
+func (s *Storage) ListBackups() ([]BackupInfo, error) {
+	var backups []BackupInfo
+	err := s.withFileLock(func() error {
+		names, err := s.backupNames()
+		if err != nil {
+			return err
+		}
+
+		backups = make([]BackupInfo, 0, len(names))
+		for _, name := range names {
+			path := filepath.Join(s.backupDir, name)
+			info := BackupInfo{Name: name}
+
+			stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+			if t, err := time.Parse(backupTimeLayout, stamp); err == nil {
+				info.CreatedAt = t
+			}
+			if stat, err := os.Stat(path); err == nil {
+				info.Size = stat.Size()
+			}
+			if data, err := os.ReadFile(path); err == nil {
+				if file, _, err := decodeConnections(data); err == nil {
+					info.Connections = len(file.Connections)
+				}
+			}
+
+			backups = append(backups, info)
+		}
+		return nil
+	})
+	return backups, err
+}
+
+// RestoreBackup replaces connections.json with the named backup. The current
+// file is backed up first, so a restore can itself be undone.
+func (s *Storage) RestoreBackup(name string) error {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+		return fmt.Errorf("invalid backup name: %s", name)
+	}
+
+	return s.withFileLock(func() error {
+		data, err := os.ReadFile(filepath.Join(s.backupDir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		file, _, err := decodeConnections(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse backup: %w", err)
+		}
+
+		// Make sure the current file's state is loaded before it is backed up
+		if _, err := s.readConnections(); err != nil {
+			return err
+		}
+		s.legacySecretKeys = s.legacySecretKeys || file.LegacySecretKeys
+		return s.saveConnections(file.Connections)
+	})
+}
@@ -0,0 +1,359 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memSecretStore is a SecretStore kept in memory
+type memSecretStore map[string]string
+
+func (m memSecretStore) Get(key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func (m memSecretStore) Set(key, value string) error {
+	m[key] = value
+	return nil
+}
+
+func (m memSecretStore) Delete(key string) error {
+	delete(m, key)
+	return nil
+}
+
+// newTestStorage returns a Storage keeping its files in a temporary directory
+// and its secrets in memory
+func newTestStorage(t *testing.T) (*Storage, memSecretStore) {
+	t.Helper()
+	dir := t.TempDir()
+	secrets := memSecretStore{}
+	return &Storage{
+		configPath: filepath.Join(dir, "connections.json"),
+		lockPath:   filepath.Join(dir, "connections.lock"),
+		backupDir:  filepath.Join(dir, "backups"),
+		secrets:    secrets,
+	}, secrets
+}
+
+func TestDecodeConnections(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantNames    []string
+		wantMigrated bool
+		wantLegacy   bool
+		wantErr      string
+	}{
+		{
+			name: "empty file",
+			data: "  \n",
+		},
+		{
+			name:         "version 1 bare array",
+			data:         `[{"name":"a","config":{"type":"mysql"}},{"name":"b","config":{"type":"sqlite"}}]`,
+			wantNames:    []string{"a", "b"},
+			wantMigrated: true,
+			wantLegacy:   true,
+		},
+		{
+			name:         "version 2",
+			data:         `{"version":2,"connections":[{"name":"a","config":{"type":"postgres"}}]}`,
+			wantNames:    []string{"a"},
+			wantMigrated: true,
+			wantLegacy:   true,
+		},
+		{
+			name:      "current version",
+			data:      `{"version":3,"connections":[{"id":"x","name":"a","sortOrder":4,"config":{"type":"postgres"}}]}`,
+			wantNames: []string{"a"},
+		},
+		{
+			name: "current version without connections",
+			data: `{"version":3}`,
+		},
+		{
+			name:    "newer version",
+			data:    `{"version":4,"connections":[]}`,
+			wantErr: "schema version 4",
+		},
+		{
+			name:    "corrupt",
+			data:    `{"version":`,
+			wantErr: "unexpected end",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, migrated, err := decodeConnections([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if migrated != tt.wantMigrated {
+				t.Errorf("migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if file.Version != connectionsSchemaVersion {
+				t.Errorf("version = %d, want %d", file.Version, connectionsSchemaVersion)
+			}
+			if file.LegacySecretKeys != tt.wantLegacy {
+				t.Errorf("legacySecretKeys = %v, want %v", file.LegacySecretKeys, tt.wantLegacy)
+			}
+			if file.Connections == nil {
+				t.Fatal("connections is nil, want an empty list")
+			}
+			if len(file.Connections) != len(tt.wantNames) {
+				t.Fatalf("got %d connections, want %d", len(file.Connections), len(tt.wantNames))
+			}
+
+			ids := map[string]bool{}
+			for i, c := range file.Connections {
+				if c.Name != tt.wantNames[i] {
+					t.Errorf("connection %d name = %q, want %q", i, c.Name, tt.wantNames[i])
+				}
+				if c.ID == "" || ids[c.ID] {
+					t.Errorf("connection %d has a missing or repeated ID %q", i, c.ID)
+				}
+				ids[c.ID] = true
+				if tt.wantMigrated {
+					if c.SortOrder != i {
+						t.Errorf("connection %d sortOrder = %d, want %d", i, c.SortOrder, i)
+					}
+					if c.CreatedAt.IsZero() {
+						t.Errorf("connection %d has no creation time", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeConnectionsRoundTrip(t *testing.T) {
+	data, err := encodeConnections(connectionsFile{Connections: []SavedConnection{{ID: "x", Name: "a"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, migrated, err := decodeConnections(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated || file.Version != connectionsSchemaVersion || len(file.Connections) != 1 || file.Connections[0].ID != "x" {
+		t.Errorf("round trip gave %+v (migrated %v)", file, migrated)
+	}
+}
+
+func TestStorageUpgradesVersion1(t *testing.T) {
+	s, secrets := newTestStorage(t)
+
+	// Version 1 kept passwords in the file and other secrets under the name
+	v1 := `[
+		{"name":"orders","config":{"type":"postgres","host":"pg","password":"pw"}},
+		{"name":"shop","environment":"prod","config":{"type":"mysql","host":"db"}}
+	]`
+	if err := os.WriteFile(s.configPath, []byte(v1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secrets[secretKey("shop")] = `{"sshPassword":"tunnel"}`
+
+	connections, err := s.LoadConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connections) != 2 {
+		t.Fatalf("got %d connections, want 2", len(connections))
+	}
+	byName := map[string]SavedConnection{}
+	for _, c := range connections {
+		byName[c.Name] = c
+	}
+	if got := byName["orders"].Config.Password; got != "pw" {
+		t.Errorf("orders password = %q, want %q", got, "pw")
+	}
+	if got := byName["shop"].Config.SSHPassword; got != "tunnel" {
+		t.Errorf("shop SSH password = %q, want %q", got, "tunnel")
+	}
+	if !byName["shop"].Config.Production {
+		t.Error("prod environment did not mark the connection as production")
+	}
+
+	// Secrets are filed under IDs now, and the file is current and clean
+	if _, ok := secrets[secretKey("shop")]; ok {
+		t.Error("name-keyed secret was not removed")
+	}
+	for _, c := range connections {
+		if _, ok := secrets[secretKey(c.ID)]; !ok {
+			t.Errorf("no secrets stored under the ID of %s", c.Name)
+		}
+	}
+
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var onDisk map[string]interface{}
+	if err := json.Unmarshal(data, &onDisk); err != nil {
+		t.Fatal(err)
+	}
+	if onDisk["version"] != float64(connectionsSchemaVersion) {
+		t.Errorf("file version = %v, want %d", onDisk["version"], connectionsSchemaVersion)
+	}
+	if _, ok := onDisk["legacySecretKeys"]; ok {
+		t.Error("legacySecretKeys is still set")
+	}
+	if strings.Contains(string(data), `"pw"`) {
+		t.Error("plaintext password left in connections.json")
+	}
+
+	// IDs given during the upgrade stay stable
+	again, err := s.LoadConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range again {
+		if again[i].ID != connections[i].ID {
+			t.Errorf("connection %s changed ID from %s to %s", again[i].Name, connections[i].ID, again[i].ID)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "connections.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Fatalf("content = %q, %v, want %q", data, err, content)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("mode = %o, want 600", perm)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestStorageConcurrentSaves(t *testing.T) {
+	s, secrets := newTestStorage(t)
+	// A second app instance sharing the same files
+	other := &Storage{configPath: s.configPath, lockPath: s.lockPath, backupDir: s.backupDir, secrets: secrets}
+
+	const perInstance = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*perInstance)
+	for i := 0; i < perInstance; i++ {
+		for _, storage := range []*Storage{s, other} {
+			wg.Add(1)
+			go func(storage *Storage, i int) {
+				defer wg.Done()
+				_, err := storage.SaveConnection(SavedConnection{Name: fmt.Sprintf("c%d", i), Config: ConnectionConfig{Type: "sqlite"}})
+				errs <- err
+			}(storage, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No save overwrote another
+	connections, err := s.LoadConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connections) != 2*perInstance {
+		t.Errorf("got %d connections, want %d", len(connections), 2*perInstance)
+	}
+}
+
+func TestStorageBackups(t *testing.T) {
+	s, _ := newTestStorage(t)
+
+	saved, err := s.SaveConnection(SavedConnection{Name: "shop", Config: ConnectionConfig{Type: "mysql", Password: "pw"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first save had no file to back up; each later one keeps the previous
+	for i := 0; i < maxConnectionBackups+2; i++ {
+		saved.Name = fmt.Sprintf("shop %d", i)
+		if saved, err = s.SaveConnection(*saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := s.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != maxConnectionBackups {
+		t.Fatalf("got %d backups, want %d", len(backups), maxConnectionBackups)
+	}
+	for i, b := range backups {
+		if b.Connections != 1 || b.Size == 0 || b.CreatedAt.IsZero() {
+			t.Errorf("backup %+v is incomplete", b)
+		}
+		if i > 0 && !b.CreatedAt.Before(backups[i-1].CreatedAt) {
+			t.Errorf("backups not newest first: %s after %s", b.Name, backups[i-1].Name)
+		}
+		data, err := os.ReadFile(filepath.Join(s.backupDir, b.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), `"pw"`) {
+			t.Errorf("backup %s holds the password", b.Name)
+		}
+	}
+
+	// The newest backup is the state before the last save
+	if err := s.RestoreBackup(backups[0].Name); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := s.GetConnection(saved.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("shop %d", maxConnectionBackups); restored.Name != want {
+		t.Errorf("restored name = %q, want %q", restored.Name, want)
+	}
+	if restored.Config.Password != "pw" {
+		t.Error("restoring a backup lost the password")
+	}
+
+	for _, name := range []string{"../connections.json", "other.json", ""} {
+		if err := s.RestoreBackup(name); err == nil {
+			t.Errorf("restored invalid backup name %q", name)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// normalizeConnection tidies the metadata of conn and rejects invalid values
+func normalizeConnection(conn *SavedConnection) error {
+	conn.Name = strings.TrimSpace(conn.Name)
+	if conn.Name == "" {
+		return fmt.Errorf("connection name is required")
+	}
+
+	conn.Folder = normalizeFolder(conn.Folder)
+
+	switch conn.Environment {
+	case "", EnvironmentDevelopment, EnvironmentStaging, EnvironmentProduction:
+	default:
+		return fmt.Errorf("unknown environment: %s (use %s, %s or %s)", conn.Environment,
+			EnvironmentDevelopment, EnvironmentStaging, EnvironmentProduction)
+	}
+	conn.Config.Production = conn.Environment == EnvironmentProduction
+
+	// Trim and de-duplicate tags, keeping their order
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range conn.Tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	conn.Tags = tags
+
+	return nil
+}
+
+// normalizeFolder cleans a slash-separated folder path; "" is the top level
+func normalizeFolder(folder string) string {
+	var parts []string
+	for _, part := range strings.Split(folder, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// findConnection returns the index of the connection with id, or -1
+func findConnection(connections []SavedConnection, id string) int {
+	for i, c := range connections {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextSortOrder returns the sort order that places a connection last in folder
+func nextSortOrder(connections []SavedConnection, folder string) int {
+	next := 0
+	for _, c := range connections {
+		if c.Folder == folder && c.SortOrder >= next {
+			next = c.SortOrder + 1
+		}
+	}
+	return next
+}
+
+// sortConnections orders connections by folder, then sort order, then name
+func sortConnections(connections []SavedConnection) {
+	sort.SliceStable(connections, func(i, j int) bool {
+		a, b := connections[i], connections[j]
+		if a.Folder != b.Folder {
+			return a.Folder < b.Folder
+		}
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder < b.SortOrder
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+}
+
+// MoveConnection moves a connection into folder at position among that
+// folder's connections. A negative position moves it to the end.
+func (s *Storage) MoveConnection(id, folder string, position int) error {
+	folder = normalizeFolder(folder)
+
+	return s.withFileLock(func() error {
+		connections, err := s.readConnections()
+		if err != nil {
+			return err
+		}
+
+		index := findConnection(connections, id)
+		if index < 0 {
+			return fmt.Errorf("connection not found: %s", id)
+		}
+		moved := &connections[index]
+		moved.Folder = folder
+
+		// Renumber the target folder with the moved connection at position
+		var siblings []*SavedConnection
+		for i := range connections {
+			if connections[i].Folder == folder && connections[i].ID != id {
+				siblings = append(siblings, &connections[i])
+			}
+		}
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].SortOrder < siblings[j].SortOrder
+		})
+		if position < 0 || position > len(siblings) {
+			position = len(siblings)
+		}
+		siblings = append(siblings[:position], append([]*SavedConnection{moved}, siblings[position:]...)...)
+		for i, c := range siblings {
+			c.SortOrder = i
+		}
+
+		return s.saveConnections(connections)
+	})
+}
+
+// DuplicateConnection copies a connection, secrets included, under a new ID.
+// The copy is placed right after the original.
+func (s *Storage) DuplicateConnection(id string) (*SavedConnection, error) {
+	var copied SavedConnection
+	err := s.withFileLock(func() error {
+		connections, err := s.readConnections()
+		if err != nil {
+			return err
+		}
+
+		index := findConnection(connections, id)
+		if index < 0 {
+			return fmt.Errorf("connection not found: %s", id)
+		}
+		original := connections[index]
+
+		copied = original
+		copied.ID = uuid.NewString()
+		copied.Name = original.Name + " (copy)"
+		copied.Tags = append([]string(nil), original.Tags...)
+		copied.CreatedAt = time.Now().UTC()
+		copied.LastUsedAt = nil
+		copied.SortOrder = original.SortOrder + 1
+		for i := range connections {
+			if connections[i].Folder == original.Folder && connections[i].SortOrder > original.SortOrder {
+				connections[i].SortOrder++
+			}
+		}
+
+		secrets, err := loadSecrets(s.secrets, secretKey(id))
+		if err != nil {
+			return err
+		}
+		if err := saveSecrets(s.secrets, secretKey(copied.ID), secrets); err != nil {
+			return err
+		}
+
+		if err := s.saveConnections(append(connections, copied)); err != nil {
+			return err
+		}
+		secrets.apply(&copied.Config)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// TouchConnection records that a connection was just used. It skips the
+// backup that other writes take, since it runs on every connect.
+func (s *Storage) TouchConnection(id string) error {
+	return s.withFileLock(func() error {
+		connections, err := s.readConnections()
+		if err != nil {
+			return err
+		}
+
+		index := findConnection(connections, id)
+		if index < 0 {
+			return fmt.Errorf("connection not found: %s", id)
+		}
+		now := time.Now().UTC()
+		connections[index].LastUsedAt = &now
+
+		return s.writeConnections(connections)
+	})
+}
+
+// SearchConnections returns the connections matching every term of query.
+// Terms may be qualified as tag:, env:, folder: or type:; bare terms match
+// the name, folder, tags, notes, host, database and file path.
+func (s *Storage) SearchConnections(query string) ([]SavedConnection, error) {
+	connections, err := s.LoadConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	var matches []SavedConnection
+	for _, c := range connections {
+		if matchesAllTerms(c, terms) {
+			matches = append(matches, c)
+		}
+	}
+	return matches, nil
+}
+
+// matchesAllTerms reports whether c satisfies each lowercased search term
+func matchesAllTerms(c SavedConnection, terms []string) bool {
+	for _, term := range terms {
+		key, value, qualified := strings.Cut(term, ":")
+		if !qualified || value == "" {
+			key, value = "", term
+		}
+
+		switch key {
+		case "tag":
+			found := false
+			for _, tag := range c.Tags {
+				if strings.ToLower(tag) == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case "env":
+			if strings.ToLower(c.Environment) != value {
+				return false
+			}
+		case "folder":
+			folder := strings.ToLower(c.Folder)
+			if folder != value && !strings.HasPrefix(folder, value+"/") {
+				return false
+			}
+		case "type":
+			if strings.ToLower(c.Config.Type) != value {
+				return false
+			}
+		default:
+			fields := []string{c.Name, c.Folder, c.Notes, c.Config.Host, c.Config.Database, c.Config.FilePath}
+			fields = append(fields, c.Tags...)
+			found := false
+			for _, field := range fields {
+				if strings.Contains(strings.ToLower(field), term) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConnectConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		saved       SavedConnection
+		config      ConnectionConfig // Passed by the frontend
+		want        ConnectionConfig
+		wantMissing []string
+	}{
+		{
+			name:   "guards come from the saved connection",
+			saved:  SavedConnection{Name: "a", Config: ConnectionConfig{Type: "mysql", ReadOnly: true, Color: "#3b82f6"}},
+			config: ConnectionConfig{Type: "mysql", Password: "typed"},
+			want:   ConnectionConfig{Type: "mysql", Password: "typed", ReadOnly: true, Color: "#3b82f6"},
+		},
+		{
+			name:   "the caller can't lift them",
+			saved:  SavedConnection{Name: "a", Environment: EnvironmentProduction, Config: ConnectionConfig{Type: "mysql", ReadOnly: true}},
+			config: ConnectionConfig{Type: "mysql", ReadOnly: false, Production: false, Color: "#22c55e"},
+			want:   ConnectionConfig{Type: "mysql", ReadOnly: true, Production: true},
+		},
+		{
+			name:   "nor add them",
+			saved:  SavedConnection{Name: "a", Environment: EnvironmentDevelopment, Config: ConnectionConfig{Type: "mysql"}},
+			config: ConnectionConfig{Type: "mysql", Production: true, ReadOnly: true, Color: productionColor},
+			want:   ConnectionConfig{Type: "mysql"},
+		},
+		{
+			name:   "production color",
+			saved:  SavedConnection{Name: "a", Config: ConnectionConfig{Type: "mysql", Color: productionColor}},
+			config: ConnectionConfig{Type: "mysql"},
+			want:   ConnectionConfig{Type: "mysql", Production: true, Color: productionColor},
+		},
+		{
+			name:   "references are expanded only when saved as such",
+			saved:  SavedConnection{Name: "a", Config: ConnectionConfig{Type: "mysql", Password: "${DB_PASS}", EnvReferences: true}},
+			config: ConnectionConfig{Type: "mysql", Password: "${DB_PASS}"},
+			want:   ConnectionConfig{Type: "mysql", Password: "${DB_PASS}", EnvReferences: true},
+		},
+		{
+			name:   "literal password",
+			saved:  SavedConnection{Name: "a", Config: ConnectionConfig{Type: "mysql", Password: "${X}"}},
+			config: ConnectionConfig{Type: "mysql", Password: "${X}", EnvReferences: true},
+			want:   ConnectionConfig{Type: "mysql", Password: "${X}"},
+		},
+		{
+			name:        "secrets left out of a bundle",
+			saved:       SavedConnection{Name: "a", MissingSecrets: []string{"password", "sshPassword"}, Config: ConnectionConfig{Type: "mysql"}},
+			config:      ConnectionConfig{Type: "mysql", Password: "typed"},
+			wantMissing: []string{"sshPassword"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestStorage(t)
+			saved, err := s.SaveConnection(tt.saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.ConnectConfig(saved.ID, tt.config)
+			if tt.wantMissing != nil {
+				var missing *MissingSecretsError
+				if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Fields, tt.wantMissing) {
+					t.Fatalf("error = %v, want missing %v", err, tt.wantMissing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	s, _ := newTestStorage(t)
+	if _, err := s.ConnectConfig("missing", ConnectionConfig{}); err == nil {
+		t.Error("connected to a connection that isn't saved")
+	}
+}
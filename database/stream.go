@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// defaultStreamBatchSize is used when StreamOptions.BatchSize is not set
+const defaultStreamBatchSize = 500
+
+// StreamOptions controls how StreamQuery delivers rows
+type StreamOptions struct {
+	BatchSize int `json:"batchSize"` // Rows per batch (default 500)
+	MaxRows   int `json:"maxRows"`   // Stop fetching after this many rows, 0 means no cap
+}
+
+// StreamBatch is a chunk of rows delivered while a query is streaming
+type StreamBatch struct {
+	QueryID     string          `json:"queryId"`
+	Columns     []string        `json:"columns,omitempty"` // Only set on the first batch
+	Rows        [][]interface{} `json:"rows"`
+	Offset      int             `json:"offset"`      // Index of the first row in this batch
+	RowsFetched int             `json:"rowsFetched"` // Total rows delivered so far
+	ElapsedMs   int64           `json:"elapsedMs"`
+}
+
+// StreamSummary reports the outcome of a streamed query
+type StreamSummary struct {
+	QueryID   string   `json:"queryId"`
+	Columns   []string `json:"columns"`
+	RowCount  int      `json:"rowCount"`
+	HasMore   bool     `json:"hasMore"` // The row cap was hit and more rows exist
+	ElapsedMs int64    `json:"elapsedMs"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// StreamQuery runs a query and hands its rows to onBatch in chunks instead of
+// buffering the whole result. The returned summary is non-nil even when the
+// query fails part-way, so callers can report how far it got. An editorID with
+// an open transaction streams from inside that transaction. Destructive
+// statements on a production connection need confirmToken, see CheckStatement.
+func (m *Manager) StreamQuery(ctx context.Context, connID, editorID, queryID, query, confirmToken string, opts StreamOptions, onBatch func(StreamBatch)) (*StreamSummary, error) {
+	start := time.Now()
+	summary := &StreamSummary{QueryID: queryID}
+
+	s, err := m.getSession(connID)
+	if err != nil {
+		return summary, err
+	}
+
+	if err := s.checkStatement(query); err != nil {
+		return summary, err
+	}
+	if err := s.checkTxControl(editorID, query); err != nil {
+		return summary, err
+	}
+	if err := s.confirmDestructive(query, confirmToken); err != nil {
+		return summary, err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultStreamBatchSize
+	}
+
+	ctx, q, release, err := m.trackQuery(ctx, s, editorID, queryID)
+	if err != nil {
+		return summary, err
+	}
+	defer release()
+
+	// Cancelling before rows.Close stops the driver from draining the rest of
+	// the result set when the row cap is hit. The MySQL driver does that by
+	// dropping the connection, so a transaction's rows are drained instead.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	_, inTx := q.(*sql.Tx)
+
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return summary, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return summary, fmt.Errorf("failed to get columns: %w", err)
+	}
+	summary.Columns = columns
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	batch := make([][]interface{}, 0, batchSize)
+	flush := func() {
+		b := StreamBatch{
+			QueryID:     queryID,
+			Rows:        batch,
+			Offset:      summary.RowCount - len(batch),
+			RowsFetched: summary.RowCount,
+			ElapsedMs:   time.Since(start).Milliseconds(),
+		}
+		if b.Offset == 0 {
+			b.Columns = columns
+		}
+		onBatch(b)
+		batch = make([][]interface{}, 0, batchSize)
+	}
+
+	for rows.Next() {
+		if opts.MaxRows > 0 && summary.RowCount >= opts.MaxRows {
+			summary.HasMore = true
+			if !inTx {
+				cancel()
+			}
+			break
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			summary.ElapsedMs = time.Since(start).Milliseconds()
+			return summary, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		batch = append(batch, convertRow(values))
+		summary.RowCount++
+
+		if len(batch) >= batchSize {
+			flush()
+		}
+	}
+
+	if !summary.HasMore {
+		if err := rows.Err(); err != nil {
+			summary.ElapsedMs = time.Since(start).Milliseconds()
+			return summary, fmt.Errorf("query failed: %w", err)
+		}
+	}
+
+	// Flush the remainder; an empty result still gets one batch carrying the columns
+	if len(batch) > 0 || summary.RowCount == 0 {
+		flush()
+	}
+
+	summary.ElapsedMs = time.Since(start).Milliseconds()
+	return summary, nil
+}
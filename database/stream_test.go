@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStreamQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		opts        StreamOptions
+		wantOffsets []int
+		wantCount   int
+		wantHasMore bool
+	}{
+		{name: "batches", query: "SELECT 5", opts: StreamOptions{BatchSize: 2}, wantOffsets: []int{0, 2, 4}, wantCount: 5},
+		{name: "exact batches", query: "SELECT 4", opts: StreamOptions{BatchSize: 2}, wantOffsets: []int{0, 2}, wantCount: 4},
+		{name: "default batch size", query: "SELECT 3", wantOffsets: []int{0}, wantCount: 3},
+		{name: "empty result", query: "SELECT 0", wantOffsets: []int{0}, wantCount: 0},
+		{name: "row cap", query: "SELECT 10", opts: StreamOptions{BatchSize: 2, MaxRows: 3}, wantOffsets: []int{0, 2}, wantCount: 3, wantHasMore: true},
+		{name: "cap not reached", query: "SELECT 3", opts: StreamOptions{MaxRows: 3}, wantOffsets: []int{0}, wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager()
+			addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+			var offsets []int
+			var rows []interface{}
+			summary, err := m.StreamQuery(context.Background(), "a", "", "q1", tt.query, "", tt.opts, func(b StreamBatch) {
+				offsets = append(offsets, b.Offset)
+				if (b.Offset == 0) != (b.Columns != nil) {
+					t.Errorf("batch at %d has columns %v", b.Offset, b.Columns)
+				}
+				for _, row := range b.Rows {
+					rows = append(rows, row[0])
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(offsets, tt.wantOffsets) {
+				t.Errorf("batch offsets = %v, want %v", offsets, tt.wantOffsets)
+			}
+			if summary.RowCount != tt.wantCount || len(rows) != tt.wantCount || summary.HasMore != tt.wantHasMore {
+				t.Errorf("summary = %+v with %d rows delivered, want %d rows and HasMore %v", summary, len(rows), tt.wantCount, tt.wantHasMore)
+			}
+			for i, v := range rows {
+				if v != int64(i+1) {
+					t.Fatalf("row %d = %v, rows out of order", i, v)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamQueryCapInTransaction(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+	if err := m.BeginTransaction(ctx, "a", "editor"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ExecuteStatement(ctx, "a", "editor", "", "INSERT INTO t VALUES (1)", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := m.StreamQuery(ctx, "a", "editor", "q1", "SELECT 10", "", StreamOptions{MaxRows: 2}, func(StreamBatch) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.HasMore {
+		t.Errorf("summary = %+v, want the cap hit", summary)
+	}
+
+	// Hitting the cap must not cost the transaction its connection
+	if err := m.Commit("a", "editor"); err != nil {
+		t.Fatalf("commit after a capped stream: %v", err)
+	}
+	want := []string{"BEGIN", "INSERT INTO t VALUES (1)", "COMMIT"}
+	if got := server.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// sslEnabled reports whether the config asks for an encrypted connection
+func sslEnabled(config ConnectionConfig) bool {
+	return config.UseSSL && config.SSLMode != "" && config.SSLMode != "disable"
+}
+
+// sslOptional reports whether the config accepts an unencrypted connection
+// when the server doesn't offer TLS
+func sslOptional(config ConnectionConfig) bool {
+	return config.SSLMode == "prefer" || config.SSLMode == "allow"
+}
+
+// buildTLSConfig turns the SSL fields of a connection config into a tls.Config.
+// It returns nil when SSL is disabled. Modes follow libpq semantics:
+//   - allow: try without TLS first and fall back to prefer
+//   - prefer: encrypt only if the server offers TLS, otherwise go unencrypted
+//   - require: encrypt only, unless a CA is given, which makes it verify-ca
+//   - verify-ca: the server certificate must chain to the CA
+//   - verify-full: verify-ca plus the certificate must match the host name
+func buildTLSConfig(config ConnectionConfig) (*tls.Config, error) {
+	if !sslEnabled(config) {
+		return nil, nil
+	}
+
+	serverName := config.Host
+	if config.tlsServerName != "" {
+		serverName = config.tlsServerName
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.SSLCACert != "" {
+		caPEM, err := loadPEM(config.SSLCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to load CA certificate: no PEM certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.SSLClientCert != "" || config.SSLClientKey != "" {
+		if config.SSLClientCert == "" || config.SSLClientKey == "" {
+			return nil, fmt.Errorf("client certificate and client key must be provided together")
+		}
+		certPEM, err := loadPEM(config.SSLClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		keyPEM, err := loadPEM(config.SSLClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch config.SSLMode {
+	case "allow", "prefer":
+		// Opportunistic, so the certificate is never checked
+		tlsConfig.InsecureSkipVerify = true
+	case "require":
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.InsecureSkipVerify = true
+			break
+		}
+		verifyChainOnly(tlsConfig)
+	case "verify-ca":
+		verifyChainOnly(tlsConfig)
+	case "verify-full":
+		// Standard verification checks both the chain and the host name
+	default:
+		return nil, fmt.Errorf("unsupported SSL mode: %s", config.SSLMode)
+	}
+
+	return tlsConfig, nil
+}
+
+// verifyChainOnly makes the handshake check the certificate chain against the
+// configured roots but not the host name
+func verifyChainOnly(tlsConfig *tls.Config) {
+	roots := tlsConfig.RootCAs
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("server presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
+
+// loadPEM returns PEM data given either the PEM content itself or a path to it
+func loadPEM(value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	path, err := expandHome(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// describeTLSError rewrites handshake failures into messages that say what
+// went wrong with the certificate exchange. Other errors are returned as-is.
+func describeTLSError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var recordHeader tls.RecordHeaderError
+	var alert tls.AlertError
+
+	var reason string
+	switch {
+	case errors.As(err, &unknownAuthority):
+		reason = "server certificate is not signed by a trusted CA"
+	case errors.As(err, &hostname):
+		reason = fmt.Sprintf("server certificate is not valid for host %s", hostname.Host)
+	case errors.As(err, &invalid):
+		switch invalid.Reason {
+		case x509.Expired:
+			reason = "server certificate has expired or is not yet valid"
+		default:
+			reason = "server certificate is invalid"
+		}
+	case errors.As(err, &recordHeader):
+		reason = "server did not respond with TLS"
+	case errors.As(err, &alert):
+		reason = fmt.Sprintf("server rejected the handshake (%s); check the client certificate", alert.Error())
+	case strings.Contains(err.Error(), "server does not support TLS"),
+		strings.Contains(err.Error(), "SSL is not enabled on the server"):
+		reason = "server does not support TLS"
+	default:
+		return err
+	}
+
+	return fmt.Errorf("TLS handshake failed: %s: %w", reason, err)
+}
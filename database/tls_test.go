@@ -0,0 +1,269 @@
+package database
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority with one server certificate for
+// db.example, for handshakes against a local listener
+type testCA struct {
+	certPEM    string
+	serverCert tls.Certificate
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "db.example"},
+		DNSNames:     []string{"db.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, caCert, &serverKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{
+		certPEM:    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+		serverCert: tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey},
+	}
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	ca := newTestCA(t)
+
+	tests := []struct {
+		name       string
+		config     ConnectionConfig
+		wantNil    bool
+		wantSkip   bool // InsecureSkipVerify
+		wantVerify bool // VerifyConnection checks the chain
+		wantRoots  bool
+		wantErr    string
+	}{
+		{name: "SSL off", config: ConnectionConfig{SSLMode: "require"}, wantNil: true},
+		{name: "disable", config: ConnectionConfig{UseSSL: true, SSLMode: "disable"}, wantNil: true},
+		{name: "no mode", config: ConnectionConfig{UseSSL: true}, wantNil: true},
+		{name: "allow", config: ConnectionConfig{UseSSL: true, SSLMode: "allow"}, wantSkip: true},
+		{name: "prefer", config: ConnectionConfig{UseSSL: true, SSLMode: "prefer", SSLCACert: ca.certPEM}, wantSkip: true, wantRoots: true},
+		{name: "require", config: ConnectionConfig{UseSSL: true, SSLMode: "require"}, wantSkip: true},
+		{name: "require with a CA", config: ConnectionConfig{UseSSL: true, SSLMode: "require", SSLCACert: ca.certPEM}, wantSkip: true, wantVerify: true, wantRoots: true},
+		{name: "verify-ca", config: ConnectionConfig{UseSSL: true, SSLMode: "verify-ca", SSLCACert: ca.certPEM}, wantSkip: true, wantVerify: true, wantRoots: true},
+		{name: "verify-full", config: ConnectionConfig{UseSSL: true, SSLMode: "verify-full", SSLCACert: ca.certPEM}, wantRoots: true},
+		{name: "verify-full with system roots", config: ConnectionConfig{UseSSL: true, SSLMode: "verify-full"}},
+		{name: "unknown mode", config: ConnectionConfig{UseSSL: true, SSLMode: "sometimes"}, wantErr: "unsupported SSL mode"},
+		{name: "not a certificate", config: ConnectionConfig{UseSSL: true, SSLMode: "verify-ca", SSLCACert: "-----BEGIN nothing"}, wantErr: "no PEM certificates"},
+		{name: "client cert without key", config: ConnectionConfig{UseSSL: true, SSLMode: "require", SSLClientCert: ca.certPEM}, wantErr: "must be provided together"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Host = "db.example"
+			got, err := buildTLSConfig(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("got a TLS config with SSL disabled")
+				}
+				return
+			}
+
+			if got.ServerName != "db.example" || got.MinVersion != tls.VersionTLS12 {
+				t.Errorf("server name %q, min version %x", got.ServerName, got.MinVersion)
+			}
+			if got.InsecureSkipVerify != tt.wantSkip {
+				t.Errorf("InsecureSkipVerify = %v, want %v", got.InsecureSkipVerify, tt.wantSkip)
+			}
+			if (got.VerifyConnection != nil) != tt.wantVerify {
+				t.Errorf("VerifyConnection set = %v, want %v", got.VerifyConnection != nil, tt.wantVerify)
+			}
+			if (got.RootCAs != nil) != tt.wantRoots {
+				t.Errorf("RootCAs set = %v, want %v", got.RootCAs != nil, tt.wantRoots)
+			}
+		})
+	}
+}
+
+// servePostgresTLS accepts one connection, answers its SSLRequest with reply
+// and, on 'S', completes a TLS handshake and echoes one byte
+func servePostgresTLS(t *testing.T, ca *testCA, reply byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		request := make([]byte, len(pgSSLRequest))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		conn.Write([]byte{reply})
+
+		var rw io.ReadWriter = conn
+		if reply == 'S' {
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{ca.serverCert}})
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			rw = tlsConn
+		}
+		b := make([]byte, 1)
+		if _, err := rw.Read(b); err == nil {
+			rw.Write(b)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestPostgresTLSDialer(t *testing.T) {
+	ca := newTestCA(t)
+
+	tests := []struct {
+		name    string
+		mode    string
+		host    string // Name the certificate is checked against
+		reply   byte
+		wantTLS bool
+		wantErr string
+	}{
+		{name: "verify-full", mode: "verify-full", host: "db.example", reply: 'S', wantTLS: true},
+		{name: "verify-full wrong host", mode: "verify-full", host: "other.example", reply: 'S', wantErr: "not valid for"},
+		{name: "verify-ca ignores the host", mode: "verify-ca", host: "other.example", reply: 'S', wantTLS: true},
+		{name: "require without TLS", mode: "require", host: "db.example", reply: 'N', wantErr: "SSL is not enabled"},
+		{name: "prefer with TLS", mode: "prefer", host: "db.example", reply: 'S', wantTLS: true},
+		{name: "prefer without TLS", mode: "prefer", host: "db.example", reply: 'N'},
+		{name: "allow without TLS", mode: "allow", host: "db.example", reply: 'N'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := servePostgresTLS(t, ca, tt.reply)
+			config := ConnectionConfig{Host: tt.host, UseSSL: true, SSLMode: tt.mode, SSLCACert: ca.certPEM}
+			tlsConfig, err := buildTLSConfig(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			dialer := &pgTLSDialer{tlsConfig: tlsConfig, optional: sslOptional(config)}
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if tt.wantErr != "" {
+				if err == nil {
+					conn.Close()
+					t.Fatal("dial succeeded")
+				}
+				if err := describeTLSError(err); !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if _, isTLS := conn.(*tls.Conn); isTLS != tt.wantTLS {
+				t.Errorf("TLS = %v, want %v", isTLS, tt.wantTLS)
+			}
+			// The connection is usable either way
+			if _, err := conn.Write([]byte{'x'}); err != nil {
+				t.Fatal(err)
+			}
+			b := make([]byte, 1)
+			if _, err := io.ReadFull(conn, b); err != nil || b[0] != 'x' {
+				t.Errorf("echo = %q, %v", b, err)
+			}
+		})
+	}
+}
+
+func TestMySQLTLSParams(t *testing.T) {
+	tests := []struct {
+		mode         string
+		wantTLS      bool
+		wantFallback bool
+	}{
+		{mode: "disable"},
+		{mode: "require", wantTLS: true},
+		{mode: "verify-full", wantTLS: true},
+		{mode: "prefer", wantTLS: true, wantFallback: true},
+		{mode: "allow", wantTLS: true, wantFallback: true},
+	}
+
+	for _, tt := range tests {
+		// Extra parameters can't weaken the TLS settings
+		config := ConnectionConfig{Host: "db.example", User: "app", UseSSL: true, SSLMode: tt.mode,
+			ExtraParams: map[string]string{"tls": "false", "allowFallbackToPlaintext": "true"}}
+		dsn, err := (&MySQLDriver{}).buildDSN(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(dsn, "tls=mergen-"); got != tt.wantTLS || strings.Contains(dsn, "tls=false") {
+			t.Errorf("%s: DSN %s, want TLS %v", tt.mode, dsn, tt.wantTLS)
+		}
+		if got := strings.Contains(dsn, "allowFallbackToPlaintext=true"); got != tt.wantFallback {
+			t.Errorf("%s: DSN %s, want fallback %v", tt.mode, dsn, tt.wantFallback)
+		}
+	}
+}
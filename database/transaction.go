@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// editorTx is a manual-commit transaction pinned to one query editor
+type editorTx struct {
+	conn         *sql.Conn
+	tx           *sql.Tx
+	connectionID int64
+	startedAt    time.Time
+	statements   atomic.Int32
+	mu           sync.Mutex
+}
+
+// TransactionInfo describes an open editor transaction
+type TransactionInfo struct {
+	ConnID     string `json:"connId"`
+	EditorID   string `json:"editorId"`
+	StartedAt  string `json:"startedAt"`
+	Statements int    `json:"statements"`
+}
+
+// getTx returns the open transaction of an editor, or nil
+func (s *session) getTx(editorID string) *editorTx {
+	if editorID == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.txs[editorID]
+}
+
+// takeTx removes and returns the open transaction of an editor
+func (s *session) takeTx(editorID string) *editorTx {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	et := s.txs[editorID]
+	delete(s.txs, editorID)
+	return et
+}
+
+// transactions returns the session's open transactions ordered by editor ID
+func (s *session) transactions() []TransactionInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]TransactionInfo, 0, len(s.txs))
+	for editorID, et := range s.txs {
+		infos = append(infos, TransactionInfo{
+			ConnID:     s.id,
+			EditorID:   editorID,
+			StartedAt:  et.startedAt.Format("2006-01-02 15:04:05"),
+			Statements: int(et.statements.Load()),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].EditorID < infos[j].EditorID
+	})
+	return infos
+}
+
+// finish commits or rolls back the transaction and releases its connection
+func (et *editorTx) finish(commit bool) error {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	if et.tx == nil {
+		return fmt.Errorf("transaction is no longer open")
+	}
+
+	var err error
+	if commit {
+		err = et.tx.Commit()
+	} else {
+		err = et.tx.Rollback()
+	}
+	et.tx = nil
+	et.conn.Close()
+	return err
+}
+
+// BeginTransaction opens a manual-commit transaction for an editor. Until it
+// is committed or rolled back, every statement the editor runs uses the same
+// pinned connection.
+func (m *Manager) BeginTransaction(ctx context.Context, connID, editorID string) error {
+	if editorID == "" {
+		return fmt.Errorf("editor id is required")
+	}
+
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
+	}
+
+	if s.getTx(editorID) != nil {
+		return fmt.Errorf("transaction already open")
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	connectionID, _ := s.driver.ConnectionID(ctx, conn)
+
+	// The transaction outlives this call, so it must not be bound to ctx's cancellation
+	tx, err := conn.BeginTx(context.WithoutCancel(ctx), &sql.TxOptions{ReadOnly: s.isReadOnly()})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.txs[editorID]; exists {
+		tx.Rollback()
+		conn.Close()
+		return fmt.Errorf("transaction already open")
+	}
+	s.txs[editorID] = &editorTx{
+		conn:         conn,
+		tx:           tx,
+		connectionID: connectionID,
+		startedAt:    time.Now(),
+	}
+
+	return nil
+}
+
+// Commit commits the editor's open transaction
+func (m *Manager) Commit(connID, editorID string) error {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
+	}
+
+	et := s.takeTx(editorID)
+	if et == nil {
+		return fmt.Errorf("no open transaction")
+	}
+
+	if err := et.finish(true); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// Rollback rolls back the editor's open transaction
+func (m *Manager) Rollback(connID, editorID string) error {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return err
+	}
+
+	et := s.takeTx(editorID)
+	if et == nil {
+		return fmt.Errorf("no open transaction")
+	}
+
+	if err := et.finish(false); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
+
+// IsTransactionOpen reports whether the editor has an open transaction
+func (m *Manager) IsTransactionOpen(connID, editorID string) bool {
+	s, err := m.getSession(connID)
+	if err != nil {
+		return false
+	}
+	return s.getTx(editorID) != nil
+}
+
+// OpenTransactions returns the open transactions of a session, or of every
+// session when connID is empty
+func (m *Manager) OpenTransactions(connID string) []TransactionInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := []TransactionInfo{}
+	for id, s := range m.sessions {
+		if connID == "" || id == connID {
+			infos = append(infos, s.transactions()...)
+		}
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].ConnID < infos[j].ConnID
+	})
+	return infos
+}
+
+// checkTxControl refuses a statement that would begin or end a transaction
+// from inside the editor's open one, which would then still be reported as
+// open. ExecuteStatement routes these to BeginTransaction, Commit and Rollback.
+func (s *session) checkTxControl(editorID, stmt string) error {
+	if control := transactionControl(stmt); control != "" && s.getTx(editorID) != nil {
+		return fmt.Errorf("%s is not allowed while a transaction is open; use Commit or Rollback", control)
+	}
+	return nil
+}
+
+// transactionControl maps BEGIN/START TRANSACTION/COMMIT/ROLLBACK statements
+// to the matching Manager call so they act on the editor's pinned transaction
+func transactionControl(stmt string) string {
+	fields := strings.Fields(strings.ToUpper(strings.TrimRight(strings.TrimSpace(stmt), ";")))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	switch fields[0] {
+	case "BEGIN":
+		if len(fields) == 1 || (len(fields) == 2 && (fields[1] == "WORK" || fields[1] == "TRANSACTION")) {
+			return "BEGIN"
+		}
+	case "START":
+		if len(fields) == 2 && fields[1] == "TRANSACTION" {
+			return "BEGIN"
+		}
+	case "COMMIT", "END":
+		if len(fields) == 1 || (len(fields) == 2 && (fields[1] == "WORK" || fields[1] == "TRANSACTION")) {
+			return "COMMIT"
+		}
+	case "ROLLBACK":
+		if len(fields) == 1 || (len(fields) == 2 && (fields[1] == "WORK" || fields[1] == "TRANSACTION")) {
+			return "ROLLBACK"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransactionControl(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"BEGIN", "BEGIN"},
+		{"begin work;", "BEGIN"},
+		{"START TRANSACTION", "BEGIN"},
+		{"START TRANSACTION READ ONLY", ""},
+		{"COMMIT", "COMMIT"},
+		{"  end transaction ; ", "COMMIT"},
+		{"ROLLBACK WORK", "ROLLBACK"},
+		{"ROLLBACK TO SAVEPOINT a", ""},
+		{"BEGIN TRY", ""},
+		{"SELECT 1", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := transactionControl(tt.stmt); got != tt.want {
+			t.Errorf("transactionControl(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestEditorTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		end    string // Statement ending the transaction
+		wantTx string
+	}{
+		{name: "commit", end: "COMMIT", wantTx: "COMMIT"},
+		{name: "rollback", end: "rollback;", wantTx: "ROLLBACK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewManager()
+			_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+			if _, err := m.ExecuteStatement(ctx, "a", "editor", "", "BEGIN", ""); err != nil {
+				t.Fatal(err)
+			}
+			if !m.IsTransactionOpen("a", "editor") || m.IsTransactionOpen("a", "other") {
+				t.Fatal("transaction not open for the editor alone")
+			}
+			if _, err := m.ExecuteStatement(ctx, "a", "editor", "", "BEGIN", ""); err == nil {
+				t.Error("a second BEGIN opened another transaction")
+			}
+
+			// Statements from the editor run inside the transaction, others don't
+			if _, err := m.ExecuteStatement(ctx, "a", "editor", "", "INSERT INTO t VALUES (1)", ""); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.ExecuteQuery(ctx, "a", "editor", "", "SELECT 1", ""); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.ExecuteStatement(ctx, "a", "other", "", "INSERT INTO t VALUES (2)", ""); err != nil {
+				t.Fatal(err)
+			}
+
+			infos := m.OpenTransactions("")
+			if len(infos) != 1 || infos[0].ConnID != "a" || infos[0].EditorID != "editor" || infos[0].Statements != 2 {
+				t.Errorf("OpenTransactions = %+v, want editor with 2 statements", infos)
+			}
+
+			if _, err := m.ExecuteStatement(ctx, "a", "editor", "", tt.end, ""); err != nil {
+				t.Fatal(err)
+			}
+			if m.IsTransactionOpen("a", "editor") {
+				t.Error("transaction still open")
+			}
+			want := []string{"BEGIN", "INSERT INTO t VALUES (1)", "INSERT INTO t VALUES (2)", tt.wantTx}
+			if got := server.statements(); !reflect.DeepEqual(got, want) {
+				t.Errorf("statements = %q, want %q", got, want)
+			}
+			if _, err := m.ExecuteStatement(ctx, "a", "editor", "", tt.end, ""); err == nil {
+				t.Error("ending a transaction that isn't open succeeded")
+			}
+		})
+	}
+}
+
+func TestEditorTransactionRefusesControlStatements(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+	if err := m.BeginTransaction(ctx, "a", "editor"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := m.ExecuteScript(ctx, "a", "editor", "", "INSERT INTO t VALUES (1);\nCOMMIT;\nINSERT INTO t VALUES (2);", ScriptOptions{})
+	if err == nil || !strings.Contains(err.Error(), "line 2: COMMIT is not allowed") {
+		t.Errorf("script error = %v, want COMMIT on line 2 refused", err)
+	}
+	if _, err := m.ExecuteQuery(ctx, "a", "editor", "", "ROLLBACK", ""); err == nil {
+		t.Error("ROLLBACK run as a query inside the transaction")
+	}
+
+	if !m.IsTransactionOpen("a", "editor") {
+		t.Fatal("transaction no longer open")
+	}
+	if got := server.statements(); !reflect.DeepEqual(got, []string{"BEGIN"}) {
+		t.Errorf("statements = %q, want nothing run after BEGIN", got)
+	}
+
+	// Outside a transaction a script may manage its own
+	if _, err := m.ExecuteScript(ctx, "a", "other", "", "BEGIN;\nCOMMIT;", ScriptOptions{}); err != nil {
+		t.Errorf("script with its own transaction: %v", err)
+	}
+}
+
+func TestCancelQueryInTransaction(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+	if err := m.BeginTransaction(ctx, "a", "editor"); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.ExecuteStatement(ctx, "a", "editor", "q1", "SLEEP", "")
+		done <- err
+	}()
+	server.waitSleeping(t)
+
+	if err := m.CancelQuery("q1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("cancelled statement succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("statement still running after CancelQuery")
+	}
+
+	// Only the statement is stopped; the transaction carries on
+	if err := m.Commit("a", "editor"); err != nil {
+		t.Fatalf("commit after cancelling a statement: %v", err)
+	}
+}
+
+func TestDisconnectRollsBackTransactions(t *testing.T) {
+	m := NewManager()
+	_, server := addFakeSession(t, m, "a", ConnectionConfig{Type: "mysql"})
+
+	if err := m.BeginTransaction(context.Background(), "a", "editor"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Disconnect("a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.statements(); !reflect.DeepEqual(got, []string{"BEGIN", "ROLLBACK"}) {
+		t.Errorf("statements = %q, want the transaction rolled back", got)
+	}
+	if n := server.openConns(); n != 0 {
+		t.Errorf("%d connections still open", n)
+	}
+}
@@ -1,5 +1,7 @@
 package database
 
+import "time"
+
 // ConnectionConfig holds database connection configuration
 type ConnectionConfig struct {
 	Type     string `json:"type"`
@@ -9,30 +11,102 @@ type ConnectionConfig struct {
 	Password string `json:"password"`
 	Database string `json:"database"`
 
+	// File-based databases (SQLite)
+	FilePath     string `json:"filePath"`     // Path to the database file
+	FileReadOnly bool   `json:"fileReadOnly"` // Open the file in read-only mode
+
+	// Refuse writes: the Manager rejects them and the server session is
+	// started read-only as well
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// Destructive statements need a confirmation token. Saved connections
+	// take it from their environment label.
+	Production bool `json:"production,omitempty"`
+
+	// Expand ${NAME} environment references in passwords and passphrases
+	// when connecting. Set by bundle import; a typed password may contain
+	// ${...} literally.
+	EnvReferences bool `json:"envReferences,omitempty"`
+
+	// Driver parameters with no field of their own, e.g. charset or
+	// application_name, passed to the driver as they are
+	ExtraParams map[string]string `json:"extraParams,omitempty"`
+
+	// Pool settings; zero keeps the default
+	MaxOpenConns    int `json:"maxOpenConns,omitempty"`
+	MaxIdleConns    int `json:"maxIdleConns,omitempty"`
+	ConnMaxLifetime int `json:"connMaxLifetime,omitempty"` // Seconds
+
+	// Session settings, applied to every new pooled connection
+	ConnectTimeout   int      `json:"connectTimeout,omitempty"`   // Seconds
+	StatementTimeout int      `json:"statementTimeout,omitempty"` // Seconds; 0 means no limit
+	TimeZone         string   `json:"timeZone,omitempty"`         // e.g. "UTC" or "+03:00"
+	Charset          string   `json:"charset,omitempty"`          // MySQL only
+	Collation        string   `json:"collation,omitempty"`        // MySQL only
+	ApplicationName  string   `json:"applicationName,omitempty"`  // Postgres only
+	SearchPath       string   `json:"searchPath,omitempty"`       // Postgres only, e.g. "app, public"
+	InitSQL          []string `json:"initSQL,omitempty"`          // Run in order on every new connection
+
 	// Connection Color Coding (for environment identification)
 	Color string `json:"color"` // hex color e.g. "#ef4444" for prod
 
 	// SSL/TLS Configuration
 	UseSSL        bool   `json:"useSSL"`
-	SSLMode       string `json:"sslMode"`       // disable, require, verify-ca, verify-full
+	SSLMode       string `json:"sslMode"`       // disable, allow, prefer, require, verify-ca, verify-full
 	SSLCACert     string `json:"sslCACert"`     // CA certificate path or content
 	SSLClientCert string `json:"sslClientCert"` // Client certificate
 	SSLClientKey  string `json:"sslClientKey"`  // Client key
+	tlsServerName string // Host name to verify when the dial address is an SSH tunnel
 
 	// SSH Tunnel Configuration
 	UseSSHTunnel  bool   `json:"useSSHTunnel"`
+	SSHConfigHost string `json:"sshConfigHost"` // Host alias from ~/.ssh/config; explicit SSH fields override it
 	SSHHost       string `json:"sshHost"`
 	SSHPort       int    `json:"sshPort"`
 	SSHUser       string `json:"sshUser"`
 	SSHPassword   string `json:"sshPassword"`   // Optional, for password auth
 	SSHPrivateKey string `json:"sshPrivateKey"` // PEM content or file path
 	SSHPassphrase string `json:"sshPassphrase"` // Key passphrase if encrypted
+	SSHUseAgent   bool   `json:"sshUseAgent"`   // Authenticate with keys from ssh-agent (SSH_AUTH_SOCK)
+
+	// Jump hosts dialed in order before SSHHost, like OpenSSH's ProxyJump
+	SSHJumpHosts []SSHJumpHost `json:"sshJumpHosts,omitempty"`
+}
+
+// SSHJumpHost is an intermediate SSH server on the way to the tunnel host
+type SSHJumpHost struct {
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	User       string `json:"user"`
+	Password   string `json:"password"`
+	PrivateKey string `json:"privateKey"` // PEM content or file path
+	Passphrase string `json:"passphrase"`
+	UseAgent   bool   `json:"useAgent"`
 }
 
+// Environment labels for saved connections
+const (
+	EnvironmentDevelopment = "dev"
+	EnvironmentStaging     = "staging"
+	EnvironmentProduction  = "prod"
+)
+
 // SavedConnection represents a saved connection with a name
 type SavedConnection struct {
-	Name   string           `json:"name"`
-	Config ConnectionConfig `json:"config"`
+	ID          string           `json:"id"` // Stable UUID; names may repeat and change
+	Name        string           `json:"name"`
+	Folder      string           `json:"folder,omitempty"` // Slash-separated path, e.g. "Clients/Acme"
+	Tags        []string         `json:"tags,omitempty"`
+	Environment string           `json:"environment,omitempty"` // dev, staging or prod
+	Notes       string           `json:"notes,omitempty"`
+	SortOrder   int              `json:"sortOrder"` // Position within the folder
+	CreatedAt   time.Time        `json:"createdAt"`
+	LastUsedAt  *time.Time       `json:"lastUsedAt,omitempty"`
+	Config      ConnectionConfig `json:"config"`
+
+	// Secret fields to ask for before connecting, e.g. "password" after
+	// importing a bundle that left it out
+	MissingSecrets []string `json:"missingSecrets,omitempty"`
 }
 
 // QueryResult holds the result of a SELECT query
@@ -79,6 +153,8 @@ type IndexInfo struct {
 	Columns   []string `json:"columns"`
 	IsUnique  bool     `json:"isUnique"`
 	IsPrimary bool     `json:"isPrimary"`
+	Method    string   `json:"method,omitempty"`    // Index access method, e.g. btree, hash, gin
+	Predicate string   `json:"predicate,omitempty"` // WHERE clause of a partial index
 }
 
 // TableDetails contains full table information
@@ -86,4 +162,19 @@ type TableDetails struct {
 	Name    string       `json:"name"`
 	Columns []ColumnInfo `json:"columns"`
 	Indexes []IndexInfo  `json:"indexes"`
+
+	// Read only for drivers that rebuild tables to alter them, see
+	// rebuildReader
+	foreignKeys []foreignKeyInfo
+	triggers    []string // CREATE TRIGGER statements
+	hasChecks   bool
+}
+
+// foreignKeyInfo is a foreign key of a table
+type foreignKeyInfo struct {
+	Columns    []string
+	RefTable   string
+	RefColumns []string // Empty when the parent's primary key is referenced
+	OnUpdate   string
+	OnDelete   string
 }
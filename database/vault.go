@@ -0,0 +1,313 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters for deriving the vault key from the master password
+const (
+	vaultArgonTime    = 3
+	vaultArgonMemory  = 64 * 1024 // KiB
+	vaultArgonThreads = 4
+	vaultKeyLen       = 32
+	vaultSaltLen      = 16
+	vaultNonceLen     = 12 // Standard AES-GCM nonce size
+)
+
+// Upper bounds on the Argon2id parameters read from a file, so a crafted
+// vault or bundle can't make key derivation exhaust memory or CPU
+const (
+	vaultMaxArgonTime    = 10
+	vaultMaxArgonMemory  = 256 * 1024 // KiB
+	vaultMaxArgonThreads = 16
+)
+
+var (
+	// ErrVaultLocked is returned when the vault is used before Unlock
+	ErrVaultLocked = errors.New("credential vault is locked")
+	// ErrWrongMasterPassword is returned when the vault cannot be decrypted
+	ErrWrongMasterPassword = errors.New("incorrect master password")
+)
+
+// vaultFile is the on-disk layout of the vault
+type vaultFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"` // AES-256-GCM sealed JSON object of key -> secret
+}
+
+// Vault is a master-password-encrypted secret store used when no OS secret
+// service is available. Secrets are only readable while it is unlocked.
+type Vault struct {
+	path    string
+	key     []byte
+	salt    []byte
+	params  vaultFile
+	entries map[string]string
+	mu      sync.Mutex
+}
+
+// NewVault returns a locked vault backed by path
+func NewVault(path string) *Vault {
+	return &Vault{path: path}
+}
+
+// Exists reports whether the vault file has been created
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.path)
+	return err == nil
+}
+
+// IsLocked reports whether the vault needs Unlock before use
+func (v *Vault) IsLocked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key == nil
+}
+
+// Unlock derives the key from the master password and decrypts the vault.
+// The first Unlock creates the vault with that password.
+func (v *Vault) Unlock(password string) error {
+	if password == "" {
+		return fmt.Errorf("master password is required")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, vaultSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		v.params = vaultFile{
+			Version: 1,
+			KDF:     "argon2id",
+			Time:    vaultArgonTime,
+			Memory:  vaultArgonMemory,
+			Threads: vaultArgonThreads,
+		}
+		v.salt = salt
+		v.key = deriveVaultKey(password, salt, v.params)
+		v.entries = make(map[string]string)
+		return v.save()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse vault: %w", err)
+	}
+	if err := file.check(); err != nil {
+		return fmt.Errorf("invalid vault: %w", err)
+	}
+
+	key := deriveVaultKey(password, file.Salt, file)
+	gcm, err := newVaultCipher(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return ErrWrongMasterPassword
+	}
+
+	entries := make(map[string]string)
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return fmt.Errorf("failed to parse vault contents: %w", err)
+	}
+
+	v.params = file
+	v.salt = file.Salt
+	v.key = key
+	v.entries = entries
+	return nil
+}
+
+// Lock forgets the key and the decrypted secrets
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for i := range v.key {
+		v.key[i] = 0
+	}
+	v.key = nil
+	v.entries = nil
+}
+
+func (v *Vault) Get(key string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		// A vault that was never created holds nothing
+		if !v.Exists() {
+			return "", ErrSecretNotFound
+		}
+		return "", ErrVaultLocked
+	}
+	value, ok := v.entries[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func (v *Vault) Set(key, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return ErrVaultLocked
+	}
+	v.entries[key] = value
+	return v.save()
+}
+
+func (v *Vault) Delete(key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		if !v.Exists() {
+			return nil
+		}
+		return ErrVaultLocked
+	}
+	if _, ok := v.entries[key]; !ok {
+		return nil
+	}
+	delete(v.entries, key)
+	return v.save()
+}
+
+// save re-encrypts every entry under a fresh nonce. Callers hold v.mu.
+func (v *Vault) save() error {
+	plain, err := json.Marshal(v.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+
+	gcm, err := newVaultCipher(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	file := v.params
+	file.Salt = v.salt
+	file.Nonce = nonce
+	file.Data = gcm.Seal(nil, nonce, plain, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+	if err := writeFileAtomic(v.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	return nil
+}
+
+// sealWithPassword encrypts plain under a key derived from password, in the
+// same envelope the vault file uses
+func sealWithPassword(password string, plain []byte) (*vaultFile, error) {
+	salt := make([]byte, vaultSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	file := &vaultFile{
+		Version: 1,
+		KDF:     "argon2id",
+		Salt:    salt,
+		Time:    vaultArgonTime,
+		Memory:  vaultArgonMemory,
+		Threads: vaultArgonThreads,
+	}
+
+	gcm, err := newVaultCipher(deriveVaultKey(password, salt, *file))
+	if err != nil {
+		return nil, err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plain, nil)
+	return file, nil
+}
+
+// openWithPassword decrypts an envelope made by sealWithPassword
+func openWithPassword(password string, file *vaultFile) ([]byte, error) {
+	if err := file.check(); err != nil {
+		return nil, err
+	}
+	gcm, err := newVaultCipher(deriveVaultKey(password, file.Salt, *file))
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrWrongMasterPassword
+	}
+	return plain, nil
+}
+
+// check rejects an envelope whose key derivation this build doesn't use or
+// whose parameters are out of bounds. The argon2 package panics on zero time
+// or threads, and GCM on a nonce of the wrong size.
+func (f *vaultFile) check() error {
+	if f.KDF != "argon2id" {
+		return fmt.Errorf("unsupported key derivation: %s", f.KDF)
+	}
+	if f.Time < 1 || f.Time > vaultMaxArgonTime {
+		return fmt.Errorf("key derivation time %d is out of range", f.Time)
+	}
+	if f.Memory < 8*uint32(f.Threads) || f.Memory > vaultMaxArgonMemory {
+		return fmt.Errorf("key derivation memory %d KiB is out of range", f.Memory)
+	}
+	if f.Threads < 1 || f.Threads > vaultMaxArgonThreads {
+		return fmt.Errorf("key derivation threads %d is out of range", f.Threads)
+	}
+	if len(f.Nonce) != vaultNonceLen {
+		return fmt.Errorf("nonce has the wrong size")
+	}
+	return nil
+}
+
+func deriveVaultKey(password string, salt []byte, params vaultFile) []byte {
+	return argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, vaultKeyLen)
+}
+
+func newVaultCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
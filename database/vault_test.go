@@ -0,0 +1,171 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVaultLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v := NewVault(path)
+
+	// A vault that was never created is empty rather than locked
+	if _, err := v.Get("a"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("Get before creation: %v, want ErrSecretNotFound", err)
+	}
+	if err := v.Set("a", "x"); !errors.Is(err, ErrVaultLocked) {
+		t.Fatalf("Set while locked: %v, want ErrVaultLocked", err)
+	}
+	if err := v.Unlock(""); err == nil {
+		t.Fatal("Unlock accepted an empty master password")
+	}
+
+	if err := v.Unlock("master"); err != nil {
+		t.Fatalf("creating the vault: %v", err)
+	}
+	if err := v.Set("connection:1", `{"password":"s3cret"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("connection:2", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Delete("connection:2"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cret")) {
+		t.Fatal("vault file holds a plaintext secret")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("vault file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	v.Lock()
+	if !v.IsLocked() {
+		t.Fatal("vault is unlocked after Lock")
+	}
+	if _, err := v.Get("connection:1"); !errors.Is(err, ErrVaultLocked) {
+		t.Fatalf("Get while locked: %v, want ErrVaultLocked", err)
+	}
+	if err := v.Delete("connection:1"); !errors.Is(err, ErrVaultLocked) {
+		t.Fatalf("Delete while locked: %v, want ErrVaultLocked", err)
+	}
+
+	// A fresh instance reads what the first one wrote
+	v = NewVault(path)
+	if err := v.Unlock("wrong"); !errors.Is(err, ErrWrongMasterPassword) {
+		t.Fatalf("Unlock with the wrong password: %v, want ErrWrongMasterPassword", err)
+	}
+	if err := v.Unlock("master"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("connection:1"); err != nil || got != `{"password":"s3cret"}` {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+	if _, err := v.Get("connection:2"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("deleted entry: %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestSealWithPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		plain    []byte
+		password string
+		open     string
+		tamper   func(*vaultFile)
+		wantErr  error
+	}{
+		{name: "round trip", plain: []byte(`{"a":"b"}`), password: "pass", open: "pass"},
+		{name: "empty payload", plain: []byte{}, password: "pass", open: "pass"},
+		{name: "wrong password", plain: []byte("x"), password: "pass", open: "Pass", wantErr: ErrWrongMasterPassword},
+		{
+			name: "tampered data", plain: []byte("secret"), password: "pass", open: "pass",
+			tamper:  func(f *vaultFile) { f.Data[0] ^= 1 },
+			wantErr: ErrWrongMasterPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := sealWithPassword(tt.password, tt.plain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(file)
+			}
+
+			got, err := openWithPassword(tt.open, file)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.plain) {
+				t.Errorf("opened %q, want %q", got, tt.plain)
+			}
+		})
+	}
+}
+
+func TestVaultRejectsHostileKDF(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(*vaultFile)
+	}{
+		{name: "other KDF", tamper: func(f *vaultFile) { f.KDF = "scrypt" }},
+		{name: "zero time", tamper: func(f *vaultFile) { f.Time = 0 }},
+		{name: "huge time", tamper: func(f *vaultFile) { f.Time = 1 << 30 }},
+		{name: "huge memory", tamper: func(f *vaultFile) { f.Memory = 1 << 31 }},
+		{name: "zero threads", tamper: func(f *vaultFile) { f.Threads = 0 }},
+		{name: "many threads", tamper: func(f *vaultFile) { f.Threads = 255 }},
+		{name: "short nonce", tamper: func(f *vaultFile) { f.Nonce = f.Nonce[:1] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vault.json")
+			if err := NewVault(path).Unlock("master"); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var file vaultFile
+			if err := json.Unmarshal(data, &file); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(&file)
+			if data, err = json.Marshal(file); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			err = NewVault(path).Unlock("master")
+			if err == nil || errors.Is(err, ErrWrongMasterPassword) {
+				t.Fatalf("error = %v, want the parameters refused", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("refusing the vault took %s", elapsed)
+			}
+		})
+	}
+}
@@ -11,8 +11,8 @@ import { DataEditor } from './components/DataEditor';
 import { ConnectionModal } from './components/ConnectionModal';
 import { CommandPalette, useCommandPalette } from './components/CommandPalette';
 import { ThemeToggle } from './components/ThemeToggle';
-import { ConnectionConfig, UpdateInfo } from './types';
-import { ToggleFullscreen, CheckForUpdate, GetAppVersion } from '../wailsjs/go/main/App';
+import { ConnectionConfig, SavedConnection, UpdateInfo } from './types';
+import { ToggleFullscreen, CheckForUpdate, GetAppVersion, GetDatabases } from '../wailsjs/go/main/App';
 import { WindowToggleMaximise, WindowIsMaximised } from '../wailsjs/runtime/runtime';
 import { UpdateModal } from './components/UpdateModal';
 import { WindowControls } from './components/WindowControls';
@@ -40,6 +40,7 @@ import { cn } from '@/lib/utils';
 
 function App() {
     const {
+        connId,
         connected,
         loading,
         error,
@@ -86,9 +87,9 @@ function App() {
     const viewMode = !connected ? 'hub' : (activeTab?.type === 'query' ? 'query' : (activeTab?.type === 'table' ? 'data' : 'hub'));
 
     const [modalOpen, setModalOpen] = useState(false);
-    const [modalData, setModalData] = useState<{ config?: ConnectionConfig; name?: string }>({});
+    const [modalData, setModalData] = useState<{ conn?: SavedConnection }>({});
 
-    const [activeConnectionName, setActiveConnectionName] = useState<string | undefined>(undefined);
+    const [activeConnectionId, setActiveConnectionId] = useState<string | undefined>(undefined);
     const [isFullscreen, setIsFullscreen] = useState(false);
     const [updateInfo, setUpdateInfo] = useState<UpdateInfo | null>(null);
     const [appVersion, setAppVersion] = useState("V0.1.0-ALPHA");
@@ -228,24 +229,27 @@ function App() {
         setActiveTabId(newTab.id);
     }, [t]);
 
-    const handleOpenModal = (config?: ConnectionConfig, name?: string) => {
-        setModalData({ config, name });
+    const handleOpenModal = (conn?: SavedConnection) => {
+        setModalData({ conn });
         setModalOpen(true);
     };
 
     const handleSaveModal = async (name: string, config: ConnectionConfig) => {
-        if (modalData.name && modalData.name !== name) {
-            await renameConnection(modalData.name, name);
+        const existing = modalData.conn;
+        if (!existing) {
+            return await saveConnection(name, config);
         }
-        return await saveConnection(name, config);
+        if (existing.name !== name) {
+            await renameConnection(existing.id, name);
+        }
+        return await updateConnection({ ...existing, name, config });
     };
 
-    const handleConnect = async (config: ConnectionConfig) => {
-        const success = await connect(config);
+    const handleConnect = async (conn: SavedConnection) => {
+        const config = conn.config;
+        const success = await connect(conn);
         if (success) {
-            // Find current name if any
-            const sc = savedConnections.find(c => c.config.host === config.host && c.config.database === config.database);
-            setActiveConnectionName(sc?.name);
+            setActiveConnectionId(conn.id);
 
             // Fetch schema / Auto-select database
             if (config.database) {
@@ -261,8 +265,7 @@ function App() {
                 // AUTO-SELECT LOGIC: If no DB specified, try to find a user DB
                 try {
                     // We need to fetch databases explicitly here because the state update in hook might be pending
-                    const { GetDatabases } = await import('../wailsjs/go/main/App');
-                    const dbs = await GetDatabases();
+                    const dbs = await GetDatabases(conn.id);
                     if (dbs && dbs.length > 0) {
                         const systemDbs = ['information_schema', 'mysql', 'performance_schema', 'sys'];
                         const userDbs = dbs.filter(d => !systemDbs.includes(d.name));
@@ -288,9 +291,10 @@ function App() {
     };
 
     // Get active connection color
-    const activeConnection = savedConnections.find(c => c.name === activeConnectionName);
+    const activeConnection = savedConnections.find(c => c.id === activeConnectionId);
+    const activeConnectionName = activeConnection?.name;
     const connectionColor = activeConnection?.config.color;
-    const isProdEnv = connectionColor === '#ef4444';
+    const isProdEnv = activeConnection?.config.production || connectionColor === '#ef4444';
 
     return (
         <div
@@ -386,6 +390,7 @@ function App() {
                             onDisconnect={disconnect}
                             onOpenModal={handleOpenModal}
                             activeName={activeConnectionName}
+                            activeId={activeConnectionId}
                             onGoToHub={() => {
                                 // Maybe add a hub tab or just reset?
                                 // For now, just reset to query
@@ -440,6 +445,7 @@ function App() {
                                 </ResizablePanelGroup>
                             ) : activeTab?.type === 'table' && activeTab.data ? (
                                 <DataEditor
+                                    connId={connId}
                                     database={activeTab.data.db}
                                     table={activeTab.data.table}
                                     onClose={() => {
@@ -494,9 +500,9 @@ function App() {
             {
                 modalOpen && (
                     <ConnectionModal
-                        title={modalData.name ? t('app.editConnection') : t('app.newConnection')}
-                        initialConfig={modalData.config}
-                        initialName={modalData.name}
+                        title={modalData.conn ? t('app.editConnection') : t('app.newConnection')}
+                        initialConfig={modalData.conn?.config}
+                        initialName={modalData.conn?.name}
                         onSave={handleSaveModal}
                         onClose={() => setModalOpen(false)}
                         onTest={testConnection}
@@ -1,5 +1,5 @@
 import React from 'react';
-import { ConnectionConfig, SavedConnection } from '../types';
+import { SavedConnection } from '../types';
 import { useTranslation } from 'react-i18next';
 import {
     Plus,
@@ -21,9 +21,9 @@ import { Separator } from '@/components/ui/separator';
 
 interface Props {
     savedConnections: SavedConnection[];
-    onConnect: (config: ConnectionConfig) => Promise<boolean>;
-    onOpenModal: (config?: ConnectionConfig, name?: string) => void;
-    onDelete: (name: string) => void;
+    onConnect: (conn: SavedConnection) => Promise<boolean>;
+    onOpenModal: (conn?: SavedConnection) => void;
+    onDelete: (id: string) => void;
     loading: boolean;
 }
 
@@ -74,7 +74,7 @@ export function ConnectionHub({ savedConnections, onConnect, onOpenModal, onDele
                         const driver = getDriverDisplay(conn.config.type);
                         return (
                             <Card
-                                key={conn.name}
+                                key={conn.id}
                                 className="group overflow-hidden transition-all hover:shadow-2xl hover:-translate-y-1 border-border/40 bg-card/50 backdrop-blur-sm h-48 flex flex-col justify-between"
                             >
                                 <CardContent className="p-5 flex flex-col h-full">
@@ -87,7 +87,7 @@ export function ConnectionHub({ savedConnections, onConnect, onOpenModal, onDele
                                                 variant="ghost"
                                                 size="icon"
                                                 className="h-8 w-8 hover:bg-muted"
-                                                onClick={(e) => { e.stopPropagation(); onOpenModal(conn.config, conn.name); }}
+                                                onClick={(e) => { e.stopPropagation(); onOpenModal(conn); }}
                                             >
                                                 <Settings2 size={14} />
                                             </Button>
@@ -95,7 +95,7 @@ export function ConnectionHub({ savedConnections, onConnect, onOpenModal, onDele
                                                 variant="ghost"
                                                 size="icon"
                                                 className="h-8 w-8 hover:bg-destructive/10 hover:text-destructive"
-                                                onClick={(e) => { e.stopPropagation(); onDelete(conn.name); }}
+                                                onClick={(e) => { e.stopPropagation(); onDelete(conn.id); }}
                                             >
                                                 <Trash2 size={14} />
                                             </Button>
@@ -121,7 +121,7 @@ export function ConnectionHub({ savedConnections, onConnect, onOpenModal, onDele
 
                                     <Button
                                         className="w-full h-8 text-[11px] font-black uppercase tracking-widest group-hover:bg-primary transition-all mt-4"
-                                        onClick={() => onConnect(conn.config)}
+                                        onClick={() => onConnect(conn)}
                                         disabled={loading}
                                     >
                                         {loading ? t('connectionHub.initializing') : (
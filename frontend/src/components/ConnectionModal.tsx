@@ -74,6 +74,8 @@ const ENV_COLORS = [
 
 const SSL_MODES = [
     { id: 'disable', name: 'Disable', desc: 'No SSL' },
+    { id: 'allow', name: 'Allow', desc: 'SSL only if plain fails' },
+    { id: 'prefer', name: 'Prefer', desc: 'SSL if offered, no verify' },
     { id: 'require', name: 'Require', desc: 'SSL required, no verify' },
     { id: 'verify-ca', name: 'Verify CA', desc: 'Verify server certificate' },
     { id: 'verify-full', name: 'Verify Full', desc: 'Verify cert + hostname' },
@@ -1,5 +1,5 @@
 import React from 'react';
-import { ConnectionConfig, SavedConnection } from '../types';
+import { SavedConnection } from '../types';
 import { useTranslation } from 'react-i18next';
 import { LanguageSwitcher } from './LanguageSwitcher';
 import {
@@ -21,14 +21,15 @@ import { Separator } from '@/components/ui/separator';
 import { ScrollArea } from '@/components/ui/scroll-area';
 
 interface Props {
-    onConnect: (config: ConnectionConfig) => Promise<boolean>;
+    onConnect: (conn: SavedConnection) => Promise<boolean>;
     savedConnections: SavedConnection[];
-    onDeleteConnection: (name: string) => void;
+    onDeleteConnection: (id: string) => void;
     loading: boolean;
     connected: boolean;
     onDisconnect: () => void;
-    onOpenModal: (config?: ConnectionConfig, name?: string) => void;
+    onOpenModal: (conn?: SavedConnection) => void;
     activeName?: string;
+    activeId?: string;
     onGoToHub: () => void;
 }
 
@@ -57,6 +58,7 @@ export function ConnectionSidebar({
     onDisconnect,
     onOpenModal,
     activeName,
+    activeId,
     onGoToHub
 }: Props) {
     const { t } = useTranslation();
@@ -134,12 +136,12 @@ export function ConnectionSidebar({
                     </div>
                     {savedConnections.map((conn) => (
                         <div
-                            key={conn.name}
+                            key={conn.id}
                             className={cn(
                                 "group flex items-center justify-between p-2 rounded-lg transition-all cursor-pointer border border-transparent",
-                                connected && activeName === conn.name ? "bg-primary/10 text-primary border-primary/20" : "hover:bg-accent/50 text-muted-foreground hover:text-foreground hover:border-border/40"
+                                connected && activeId === conn.id ? "bg-primary/10 text-primary border-primary/20" : "hover:bg-accent/50 text-muted-foreground hover:text-foreground hover:border-border/40"
                             )}
-                            onClick={() => onConnect(conn.config)}
+                            onClick={() => onConnect(conn)}
                         >
                             <div className="flex items-center gap-2 min-w-0">
                                 <div className="relative">
@@ -155,7 +157,7 @@ export function ConnectionSidebar({
                                     variant="ghost"
                                     size="icon"
                                     className="h-6 w-6"
-                                    onClick={(e) => { e.stopPropagation(); onOpenModal(conn.config, conn.name); }}
+                                    onClick={(e) => { e.stopPropagation(); onOpenModal(conn); }}
                                 >
                                     <Settings2 size={12} />
                                 </Button>
@@ -163,7 +165,7 @@ export function ConnectionSidebar({
                                     variant="ghost"
                                     size="icon"
                                     className="h-6 w-6 text-destructive/60 hover:text-destructive hover:bg-destructive/10"
-                                    onClick={(e) => { e.stopPropagation(); onDeleteConnection(conn.name); }}
+                                    onClick={(e) => { e.stopPropagation(); onDeleteConnection(conn.id); }}
                                 >
                                     <Trash2 size={12} />
                                 </Button>
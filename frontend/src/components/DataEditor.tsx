@@ -1,7 +1,8 @@
 import React, { useState, useEffect, useCallback } from 'react';
-import { ColumnInfo, TableDataResponse } from '../types';
+import { ColumnInfo, Filter as DataFilter, TableDataResponse } from '../types';
 import { useTranslation } from 'react-i18next';
-import { GetTableData, InsertRow, UpdateRow, DeleteRow, SelectExportPath, ExportTable } from '../../wailsjs/go/main/App';
+import { GetTableData, InsertRow, UpdateRow, DeleteRows, SelectExportPath, ExportTable } from '../../wailsjs/go/main/App';
+import { database as models } from '../../wailsjs/go/models';
 import {
     Plus,
     Trash2,
@@ -84,14 +85,15 @@ import {
 } from "@/components/ui/alert-dialog";
 
 interface Props {
+    connId: string;
     database: string;
     table: string;
     onClose: () => void;
 }
 
-export function DataEditor({ database, table, onClose }: Props) {
+export function DataEditor({ connId, database, table, onClose }: Props) {
     const { t } = useTranslation();
-    const { truncateTable, dropTable, alterTable } = useDatabase();
+    const { truncateTable, dropTable, alterTable } = useDatabase(connId);
     const [data, setData] = useState<TableDataResponse | null>(null);
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState<string | null>(null);
@@ -111,67 +113,61 @@ export function DataEditor({ database, table, onClose }: Props) {
     const [sortColumn, setSortColumn] = useState<string>('');
     const [sortDirection, setSortDirection] = useState<'ASC' | 'DESC'>('ASC');
 
-    // Filtering State
-    const [activeFilter, setActiveFilter] = useState('');
+    // Filtering State: column filters and global search build a structured
+    // filter; the raw editor and history set a WHERE clause instead
+    const [filter, setFilter] = useState<DataFilter | null>(null);
+    const [rawWhere, setRawWhere] = useState('');
+    const activeFilter = filter !== null || rawWhere !== '';
     const [columnFilters, setColumnFilters] = useState<Record<string, string>>({});
     const [globalSearch, setGlobalSearch] = useState('');
     const [showChart, setShowChart] = useState(false);
 
-    // Parse filter value to SQL condition
-    const getSqlCondition = (colName: string, value: string): string | null => {
+    // Parse a column filter value to a filter condition
+    const parseColumnFilter = (colName: string, value: string): DataFilter | null => {
         if (!value) return null;
 
         value = value.trim();
-        if (value.startsWith('>=')) return `\`${colName}\` >= '${value.substring(2).trim()}'`;
-        if (value.startsWith('<=')) return `\`${colName}\` <= '${value.substring(2).trim()}'`;
-        if (value.startsWith('>')) return `\`${colName}\` > '${value.substring(1).trim()}'`;
-        if (value.startsWith('<')) return `\`${colName}\` < '${value.substring(1).trim()}'`;
-        if (value.startsWith('!=') || value.startsWith('<>')) return `\`${colName}\` != '${value.substring(2).trim()}'`;
-        if (value.startsWith('=')) return `\`${colName}\` = '${value.substring(1).trim()}'`;
-
-        if (value.toLowerCase() === 'null') return `\`${colName}\` IS NULL`;
-        if (value.toLowerCase() === '!null') return `\`${colName}\` IS NOT NULL`;
-
-        // Default to LIKE for strings, = for numbers if simple value
-        // But for simplicity/flexibility, LIKE is safer for text search, = for numbers
-        // Let's guess: if it looks like a number, use =
-        if (/^-?\d+(\.\d+)?$/.test(value)) return `\`${colName}\` = ${value}`;
-
-        // Otherwise partial match
-        return `\`${colName}\` LIKE '%${value.replace(/'/g, "''")}%'`;
+        for (const op of ['>=', '<=', '!=', '<>', '>', '<', '=']) {
+            if (value.startsWith(op)) {
+                return { column: colName, operator: op === '<>' ? '!=' : op, value: value.substring(op.length).trim() };
+            }
+        }
+
+        if (value.toLowerCase() === 'null') return { column: colName, operator: 'is null' };
+        if (value.toLowerCase() === '!null') return { column: colName, operator: 'is not null' };
+
+        // Numbers match exactly, anything else partially
+        if (/^-?\d+(\.\d+)?$/.test(value)) return { column: colName, operator: '=', value };
+        if (value.startsWith('START')) return { column: colName, operator: 'like', value: `${value.substring(5).trim()}%` };
+        if (value.startsWith('LIKE')) value = value.substring(4).trim();
+        return { column: colName, operator: 'like', value: `%${value}%` };
     };
 
     const applyFilters = (colFilters: Record<string, string> = columnFilters, globalVal: string = globalSearch) => {
         const conditions = Object.entries(colFilters)
-            .map(([col, val]) => getSqlCondition(col, val))
-            .filter(Boolean);
+            .map(([col, val]) => parseColumnFilter(col, val))
+            .filter((f): f is DataFilter => f !== null);
 
-        const colSql = conditions.length > 0 ? `(${conditions.join(' AND ')})` : '';
-
-        // Global Search Logic
-        let globalSql = '';
+        // Global search matches text columns only
         if (globalVal && data?.columns) {
-            const orConditions = data.columns.map(col => {
-                // Heuristic: only search text/numeric, ignore blob etc if needed. 
-                // For now, simple LIKE on mostly everything.
-                return `\`${col.name}\` LIKE '%${globalVal.replace(/'/g, "''")}%'`;
-            });
-            globalSql = `(${orConditions.join(' OR ')})`;
+            const orConditions = data.columns
+                .filter(col => /char|text|clob|enum/i.test(col.type))
+                .map(col => ({ column: col.name, operator: 'like', value: `%${globalVal}%` }));
+            if (orConditions.length > 0) {
+                conditions.unshift({ logic: 'or', children: orConditions });
+            }
         }
 
-        const parts = [globalSql, colSql].filter(Boolean);
-        const finalSql = parts.length > 0 ? parts.join(' AND ') : '';
-
-        setActiveFilter(finalSql);
-        return finalSql; // Return the computed filter string
+        const next = conditions.length > 0 ? { logic: 'and' as const, children: conditions } : null;
+        setFilter(next);
+        setRawWhere('');
+        return next;
     };
 
     const handleGlobalSearchKeyDown = (e: React.KeyboardEvent) => {
         if (e.key === 'Enter') {
-            const currentFilter = applyFilters(columnFilters, globalSearch);
+            applyFilters(columnFilters, globalSearch);
             setPage(1);
-            loadData();
-            if (currentFilter) addToHistory(table, currentFilter);
         }
     };
 
@@ -184,10 +180,8 @@ export function DataEditor({ database, table, onClose }: Props) {
 
     const handleFilterKeyDown = (e: React.KeyboardEvent) => {
         if (e.key === 'Enter') {
-            const currentFilter = applyFilters(columnFilters, globalSearch);
+            applyFilters(columnFilters, globalSearch);
             setPage(1);
-            loadData();
-            if (currentFilter) addToHistory(table, currentFilter);
         }
     };
 
@@ -206,7 +200,8 @@ export function DataEditor({ database, table, onClose }: Props) {
     const clearAllFilters = () => {
         setColumnFilters({});
         setGlobalSearch('');
-        setActiveFilter('');
+        setFilter(null);
+        setRawWhere('');
         setPage(1);
     };
 
@@ -218,10 +213,8 @@ export function DataEditor({ database, table, onClose }: Props) {
 
         const newFilters = { ...columnFilters, [colName]: value === null ? 'NULL' : `=${valStr}` };
         setColumnFilters(newFilters);
-        const currentFilter = applyFilters(newFilters, globalSearch);
+        applyFilters(newFilters, globalSearch);
         setPage(1);
-        loadData();
-        if (currentFilter) addToHistory(table, currentFilter);
         toast.success(`Filtered by ${colName} = ${cleanVal}`);
     };
 
@@ -229,10 +222,8 @@ export function DataEditor({ database, table, onClose }: Props) {
         const valStr = value === null ? 'NULL' : String(value);
         const newFilters = { ...columnFilters, [colName]: value === null ? '!NULL' : `!=${valStr}` };
         setColumnFilters(newFilters);
-        const currentFilter = applyFilters(newFilters, globalSearch);
+        applyFilters(newFilters, globalSearch);
         setPage(1);
-        loadData();
-        if (currentFilter) addToHistory(table, currentFilter);
         toast.success(`Excluded ${colName} = ${valStr}`);
     };
 
@@ -240,23 +231,24 @@ export function DataEditor({ database, table, onClose }: Props) {
         setLoading(true);
         setError(null);
         try {
-            const result = await GetTableData({
+            const result = await GetTableData(connId, models.TableDataRequest.createFrom({
                 database,
                 table,
                 page,
                 pageSize,
                 orderBy: sortColumn,
                 orderDir: sortDirection,
-                filters: activeFilter // This is mainly constructed in applyFilters now
-            });
-            setData(result);
+                filter: filter ?? undefined,
+                rawWhere: rawWhere || undefined
+            }));
+            setData(result as TableDataResponse);
         } catch (err: any) {
             setError(err.message || 'Failed to load data');
             toast.error(`Error loading ${table}: ${err.message}`);
         } finally {
             setLoading(false);
         }
-    }, [database, table, page, pageSize, activeFilter, sortColumn, sortDirection]);
+    }, [connId, database, table, page, pageSize, filter, rawWhere, sortColumn, sortDirection]);
 
     useEffect(() => {
         loadData();
@@ -293,12 +285,10 @@ export function DataEditor({ database, table, onClose }: Props) {
         if (!editingCell || !data) return;
 
         const column = data.columns[editingCell.col];
-        const row = data.rows[editingCell.row];
-        const pkIndex = data.columns.findIndex(c => c.name === data.primaryKey);
-        const pkValue = row[pkIndex];
+        const key = data.rowKeys[editingCell.row];
 
         try {
-            await UpdateRow(database, table, data.primaryKey, pkValue, {
+            await UpdateRow(connId, database, '', table, key, {
                 [column.name]: editValue === '' ? null : editValue
             });
             toast.success("Row updated successfully");
@@ -322,13 +312,10 @@ export function DataEditor({ database, table, onClose }: Props) {
         if (!data || selectedRows.size === 0) return;
 
         const count = selectedRows.size;
-        const pkIndex = data.columns.findIndex(c => c.name === data.primaryKey);
 
         try {
-            const pkValues = Array.from(selectedRows).map(idx => data.rows[idx][pkIndex]);
-            for (const pkValue of pkValues) {
-                await DeleteRow(database, table, data.primaryKey, pkValue);
-            }
+            const keys = Array.from(selectedRows).map(idx => data.rowKeys[idx]);
+            await DeleteRows(connId, database, '', table, keys);
             setSelectedRows(new Set());
             toast.success(`${count} row(s) deleted`);
             await loadData();
@@ -348,7 +335,7 @@ export function DataEditor({ database, table, onClose }: Props) {
         }
 
         try {
-            await InsertRow(database, table, rowData);
+            await InsertRow(connId, database, '', table, rowData);
             setNewRowData({});
             if (!keepOpen) {
                 setShowAddRow(false);
@@ -435,7 +422,7 @@ export function DataEditor({ database, table, onClose }: Props) {
             if (!path) return; // Cancelled
             // Need to pass translated strings to toast promise if possible, or handle individually
             // For now, simpler messages:
-            toast.promise(ExportTable(database, table, format, path), {
+            toast.promise(ExportTable(connId, `export-${Date.now()}`, database, '', table, format, path), {
                 loading: t('dataEditor.exporting'),
                 success: t('dataEditor.exportSuccess'),
                 error: (err) => `${t('dataEditor.exportFailed')}: ${err}`
@@ -528,25 +515,26 @@ export function DataEditor({ database, table, onClose }: Props) {
 
                             <FilterHistory
                                 table={table}
-                                currentFilter={activeFilter}
+                                currentFilter={rawWhere}
                                 onSelectFilter={(f) => {
-                                    setActiveFilter(f);
+                                    setRawWhere(f);
+                                    setFilter(null);
                                     setColumnFilters({}); // Clear column filters
                                     setGlobalSearch(''); // Clear global search
                                     setPage(1);
-                                    loadData();
                                     toast.success(t('dataEditor.appliedFilterHistory'));
                                 }}
                             />
 
                             <RawFilterEditor
-                                currentFilter={activeFilter}
+                                currentFilter={rawWhere}
                                 onSave={(f) => {
-                                    setActiveFilter(f);
+                                    setRawWhere(f);
+                                    setFilter(null);
                                     setColumnFilters({});
                                     setGlobalSearch('');
                                     setPage(1);
-                                    setPage(1);
+                                    if (f) addToHistory(table, f);
                                     // loadData handled by effect
                                     toast.success(t('dataEditor.rawFilterApplied'));
                                 }}
@@ -764,6 +752,7 @@ export function DataEditor({ database, table, onClose }: Props) {
                                                         {/* Compact Filter Input */}
                                                         <div className="relative bg-background/50" onClick={(e) => e.stopPropagation()}>
                                                             <FilterInput
+                                                                connId={connId}
                                                                 database={database}
                                                                 table={table}
                                                                 colName={col.name}
@@ -33,6 +33,7 @@ import { toast } from "sonner";
 import { cn } from "@/lib/utils";
 
 interface FilterInputProps {
+    connId: string;
     database: string;
     table: string;
     colName: string;
@@ -51,7 +52,7 @@ const OPERATORS = [
     { label: "startsWith", value: "START", icon: AlignLeft },
 ];
 
-export function FilterInput({ database, table, colName, value, onChange, onKeyDown, className }: FilterInputProps) {
+export function FilterInput({ connId, database, table, colName, value, onChange, onKeyDown, className }: FilterInputProps) {
     const { t } = useTranslation();
     const [suggestions, setSuggestions] = useState<string[]>([]);
     const [loading, setLoading] = useState(false);
@@ -66,7 +67,7 @@ export function FilterInput({ database, table, colName, value, onChange, onKeyDo
     const fetchSuggestions = async () => {
         setLoading(true);
         try {
-            const vals = await GetDistinctValues(database, table, colName);
+            const vals = await GetDistinctValues(connId, database, '', table, colName);
             setSuggestions(vals || []);
         } catch (err) {
             console.error("Failed to fetch distinct values:", err);
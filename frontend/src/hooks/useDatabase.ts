@@ -1,18 +1,24 @@
 import { useState, useCallback } from 'react';
 import {
-    Connect, Disconnect, TestConnection, IsConnected, ExecuteQuery,
+    Connect, Disconnect, TestConnection, ExecuteQuery,
     GetDatabases, GetTables, GetColumns, SaveConnection, LoadConnections,
     DeleteConnection, UseDatabase, RenameConnection, UpdateConnection,
-    GetTableData, InsertRow, UpdateRow, DeleteRow, DeleteRows,
     AlterTable, TruncateTable, DropTable, GetDatabaseSchema
 } from '../../wailsjs/go/main/App';
+import { database } from '../../wailsjs/go/models';
 import {
     ConnectionConfig, SavedConnection, QueryResult, DatabaseInfo,
-    TableInfo, ColumnInfo, TableDataRequest, TableAlteration
+    TableInfo, ColumnInfo, TableAlteration
 } from '../types';
+import { withConfirmation } from '../lib/confirm';
+import { missingSecrets, promptSecrets } from '../lib/secrets';
 import { toast } from "sonner";
 
-export function useDatabase() {
+// useDatabase manages one backend session. Components working on an already
+// open session pass its ID as sessionId.
+export function useDatabase(sessionId?: string) {
+    const [ownConnId, setConnId] = useState('');
+    const connId = sessionId ?? ownConnId;
     const [connected, setConnected] = useState(false);
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState<string | null>(null);
@@ -27,13 +33,13 @@ export function useDatabase() {
         setLoading(true);
         setError(null);
         try {
-            const result = await TestConnection(config);
-            if (result) {
+            const result = await TestConnection(database.ConnectionConfig.createFrom(config));
+            if (result.success) {
                 toast.success("Connection test successful!");
             } else {
-                toast.error("Connection test failed.");
+                toast.error(`Connection test failed: ${result.error || 'Unknown error'}`);
             }
-            return result;
+            return result.success;
         } catch (err: any) {
             toast.error(`Connection error: ${err.message || 'Unknown error'}`);
             setError(err.message || 'Connection test failed');
@@ -43,18 +49,32 @@ export function useDatabase() {
         }
     }, []);
 
-    const connect = useCallback(async (config: ConnectionConfig) => {
+    const connect = useCallback(async (conn: SavedConnection) => {
+        let config = conn.config;
         setLoading(true);
         setError(null);
         try {
-            await Connect(config);
+            try {
+                await Connect(conn.id, database.ConnectionConfig.createFrom(config));
+            } catch (err: any) {
+                // Secrets left out of an imported bundle are asked for on first connect
+                const fields = missingSecrets(err);
+                const filled = fields && promptSecrets(config, fields);
+                if (!filled) {
+                    throw err;
+                }
+                config = filled;
+                await Connect(conn.id, database.ConnectionConfig.createFrom(config));
+                await UpdateConnection(database.SavedConnection.createFrom({ ...conn, config }));
+            }
+            setConnId(conn.id);
             setConnected(true);
             if (config.database) {
                 setCurrentDb(config.database);
             }
-            toast.success(`Connected to ${config.host}`);
+            toast.success(`Connected to ${config.host || config.filePath}`);
             // Load databases after connecting
-            const dbs = await GetDatabases();
+            const dbs = await GetDatabases(conn.id);
             setDatabases(dbs || []);
             return true;
         } catch (err: any) {
@@ -68,7 +88,8 @@ export function useDatabase() {
 
     const disconnect = useCallback(async () => {
         try {
-            await Disconnect();
+            await Disconnect(connId, false);
+            setConnId('');
             setConnected(false);
             setDatabases([]);
             setCurrentDb('');
@@ -78,67 +99,63 @@ export function useDatabase() {
             toast.error(`Disconnect failed: ${err.message}`);
             setError(err.message || 'Disconnect failed');
         }
-    }, []);
+    }, [connId]);
 
     const refreshDatabases = useCallback(async () => {
         try {
-            const dbs = await GetDatabases();
+            const dbs = await GetDatabases(connId);
             setDatabases(dbs || []);
         } catch (err: any) {
             setError(err.message || 'Failed to load databases');
         }
-    }, []);
+    }, [connId]);
 
     const getTables = useCallback(async (database: string): Promise<TableInfo[]> => {
         try {
-            const tables = await GetTables(database);
+            const tables = await GetTables(connId, database, '');
             return tables || [];
         } catch (err: any) {
             setError(err.message || 'Failed to load tables');
             return [];
         }
-    }, []);
+    }, [connId]);
 
     const getColumns = useCallback(async (database: string, table: string): Promise<ColumnInfo[]> => {
         try {
-            const columns = await GetColumns(database, table);
+            const columns = await GetColumns(connId, database, '', table);
             return columns || [];
         } catch (err: any) {
             setError(err.message || 'Failed to load columns');
             return [];
         }
-    }, []);
+    }, [connId]);
 
     const getDatabaseSchema = useCallback(async (database: string): Promise<Record<string, string[]> | null> => {
         try {
-            const schema = await GetDatabaseSchema(database);
+            const schema = await GetDatabaseSchema(connId, database, '');
             return schema || null;
         } catch (err: any) {
             console.error('Failed to get schema for autocomplete:', err);
             return null;
         }
-    }, []);
+    }, [connId]);
 
     const useDb = useCallback(async (database: string) => {
         try {
-            await UseDatabase(database);
+            await UseDatabase(connId, database);
             setCurrentDb(database);
             toast.info(`Switched to database: ${database}`);
         } catch (err: any) {
             toast.error(`Failed to switch database: ${err.message}`);
             setError(err.message || 'Failed to switch database');
         }
-    }, []);
+    }, [connId]);
 
     const [queryResults, setQueryResults] = useState<QueryResult[]>([]);
 
     // Backward compatibility for single result views
     const queryResult = queryResults.length > 0 ? queryResults[0] : null;
 
-    const executeQuery = useCallback(async (query: string) => {
-        return executeQueries([query]);
-    }, []);
-
     const executeQueries = useCallback(async (queries: string[]) => {
         setLoading(true);
         setError(null);
@@ -149,7 +166,10 @@ export function useDatabase() {
         try {
             for (const q of queries) {
                 if (!q.trim()) continue;
-                const res = await ExecuteQuery(q);
+                const res = await withConfirmation(
+                    token => ExecuteQuery(connId, '', `query-${Date.now()}`, q, token),
+                    message => window.confirm(message)
+                );
                 if (res) {
                     results.push(res);
                 }
@@ -176,7 +196,11 @@ export function useDatabase() {
         } finally {
             setLoading(false);
         }
-    }, []);
+    }, [connId]);
+
+    const executeQuery = useCallback(async (query: string) => {
+        return executeQueries([query]);
+    }, [executeQueries]);
 
     const loadSavedConnections = useCallback(async () => {
         try {
@@ -189,7 +213,7 @@ export function useDatabase() {
 
     const saveConnection = useCallback(async (name: string, config: ConnectionConfig) => {
         try {
-            await SaveConnection(name, config);
+            await SaveConnection(database.SavedConnection.createFrom({ name, config }));
             await loadSavedConnections();
             toast.success(`Connection "${name}" saved.`);
             return true;
@@ -200,11 +224,11 @@ export function useDatabase() {
         }
     }, [loadSavedConnections]);
 
-    const updateConnection = useCallback(async (name: string, config: ConnectionConfig) => {
+    const updateConnection = useCallback(async (conn: SavedConnection) => {
         try {
-            await UpdateConnection(name, config);
+            await UpdateConnection(database.SavedConnection.createFrom(conn));
             await loadSavedConnections();
-            toast.success(`Connection "${name}" updated.`);
+            toast.success(`Connection "${conn.name}" updated.`);
             return true;
         } catch (err: any) {
             toast.error(`Failed to update connection: ${err.message}`);
@@ -213,9 +237,9 @@ export function useDatabase() {
         }
     }, [loadSavedConnections]);
 
-    const renameConnection = useCallback(async (oldName: string, newName: string) => {
+    const renameConnection = useCallback(async (id: string, newName: string) => {
         try {
-            await RenameConnection(oldName, newName);
+            await RenameConnection(id, newName);
             await loadSavedConnections();
             toast.success(`Connection renamed to "${newName}".`);
             return true;
@@ -226,11 +250,11 @@ export function useDatabase() {
         }
     }, [loadSavedConnections]);
 
-    const deleteConnection = useCallback(async (name: string) => {
+    const deleteConnection = useCallback(async (id: string) => {
         try {
-            await DeleteConnection(name);
+            await DeleteConnection(id);
             await loadSavedConnections();
-            toast.info(`Connection "${name}" deleted.`);
+            toast.info("Connection deleted.");
         } catch (err: any) {
             toast.error(`Failed to delete connection: ${err.message}`);
             setError(err.message || 'Failed to delete connection');
@@ -240,7 +264,10 @@ export function useDatabase() {
     const alterTable = useCallback(async (database: string, table: string, alteration: TableAlteration) => {
         setLoading(true);
         try {
-            await AlterTable(database, table, alteration as any);
+            await withConfirmation(
+                token => AlterTable(connId, database, '', table, alteration as any, token),
+                message => window.confirm(message)
+            );
             toast.success(`Table "${table}" modified successfully.`);
             return true;
         } catch (err: any) {
@@ -249,38 +276,41 @@ export function useDatabase() {
         } finally {
             setLoading(false);
         }
-    }, []);
+    }, [connId]);
 
     const truncateTable = useCallback(async (database: string, table: string) => {
         setLoading(true);
         try {
-            await TruncateTable(database, table);
+            // The caller has already asked the user to confirm
+            await withConfirmation(token => TruncateTable(connId, database, '', table, token));
             toast.success(`Table "${table}" truncated.`);
             return true;
         } catch (err: any) {
-            toast.error(`Failed to truncate table: ${err.message}`);
+            toast.error(`Failed to truncate table: ${err.message || err}`);
             return false;
         } finally {
             setLoading(false);
         }
-    }, []);
+    }, [connId]);
 
     const dropTable = useCallback(async (database: string, table: string) => {
         setLoading(true);
         try {
-            await DropTable(database, table);
+            // The caller has already asked the user to confirm
+            await withConfirmation(token => DropTable(connId, database, '', table, token));
             toast.success(`Table "${table}" dropped.`);
             return true;
         } catch (err: any) {
-            toast.error(`Failed to drop table: ${err.message}`);
+            toast.error(`Failed to drop table: ${err.message || err}`);
             return false;
         } finally {
             setLoading(false);
         }
-    }, []);
+    }, [connId]);
 
     return {
         // State
+        connId,
         connected,
         loading,
         error,
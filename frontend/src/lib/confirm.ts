@@ -0,0 +1,21 @@
+// Destructive calls on production connections are refused until they are
+// repeated with the token the backend returns in its error. withConfirmation
+// repeats the call once with that token; ask, when given, is shown the error
+// first and can refuse. Callers that have already asked the user omit it.
+const tokenPattern = /confirm with token ([0-9a-f]+)/;
+
+export async function withConfirmation<T>(
+    call: (token: string) => Promise<T>,
+    ask?: (message: string) => boolean
+): Promise<T> {
+    try {
+        return await call('');
+    } catch (err: any) {
+        const message = typeof err === 'string' ? err : (err?.message || '');
+        const match = tokenPattern.exec(message);
+        if (!match || (ask && !ask(message.replace(match[0], '').trim()))) {
+            throw err;
+        }
+        return call(match[1]);
+    }
+}
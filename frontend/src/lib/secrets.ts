@@ -0,0 +1,33 @@
+import { ConnectionConfig } from '../types';
+
+// Connecting to a saved connection whose secrets were left out of an
+// imported bundle fails with "missing secrets: <fields>" before anything is
+// dialed. missingSecrets returns those field names, or null for other errors.
+const missingPattern = /missing secrets: ([\w.]+(?:, [\w.]+)*)/;
+
+export function missingSecrets(err: any): string[] | null {
+    const message = typeof err === 'string' ? err : (err?.message || '');
+    const match = missingPattern.exec(message);
+    return match ? match[1].split(', ') : null;
+}
+
+// promptSecrets asks for each field and returns config with the answers in
+// place, or null when the user cancels. Fields are named like "password" or
+// "jumpHosts.0.passphrase".
+export function promptSecrets(config: ConnectionConfig, fields: string[]): ConnectionConfig | null {
+    const filled: any = { ...config, sshJumpHosts: [...(config.sshJumpHosts || [])] };
+    for (const field of fields) {
+        const value = window.prompt(`Enter ${field} for this connection`);
+        if (value === null) {
+            return null;
+        }
+        const jump = /^jumpHosts\.(\d+)\.(\w+)$/.exec(field);
+        if (jump) {
+            const index = Number(jump[1]);
+            filled.sshJumpHosts[index] = { ...filled.sshJumpHosts[index], [jump[2]]: value };
+        } else {
+            filled[field] = value;
+        }
+    }
+    return filled;
+}
@@ -7,13 +7,18 @@ export interface ConnectionConfig {
   user: string;
   password: string;
   database: string;
+  filePath?: string; // SQLite database file
+  fileReadOnly?: boolean;
+  readOnly?: boolean;
+  production?: boolean;
+  envReferences?: boolean; // Expand ${NAME} in passwords; set by bundle import
 
   // Connection Color Coding
   color: string; // hex color e.g. "#ef4444" for prod
 
   // SSL/TLS Configuration
   useSSL: boolean;
-  sslMode: string; // disable, require, verify-ca, verify-full
+  sslMode: string; // disable, allow, prefer, require, verify-ca, verify-full
   sslCACert: string;
   sslClientCert: string;
   sslClientKey: string;
@@ -26,11 +31,37 @@ export interface ConnectionConfig {
   sshPassword: string;
   sshPrivateKey: string;
   sshPassphrase: string;
+  sshConfigHost?: string;
+  sshUseAgent?: boolean;
+  sshJumpHosts?: SSHJumpHost[]; // Hops before sshHost, in order
+}
+
+export interface SSHJumpHost {
+  host: string;
+  port: number;
+  user: string;
+  password?: string;
+  privateKey?: string;
+  passphrase?: string;
+  useAgent?: boolean;
 }
 
 export interface SavedConnection {
+  id: string; // Stable ID; names may repeat
   name: string;
+  folder?: string;
+  tags?: string[];
+  environment?: string; // dev, staging or prod
+  notes?: string;
+  sortOrder?: number;
   config: ConnectionConfig;
+  missingSecrets?: string[];
+}
+
+export interface ConnectionTestResult {
+  success: boolean;
+  diagnostics: string[];
+  error?: string;
 }
 
 export interface QueryResult {
@@ -95,15 +126,37 @@ export interface TableAlteration {
   renameTo: string;
 }
 
+// Structured table data filter: a group of children or a condition on a column
+export interface Filter {
+  logic?: 'and' | 'or';
+  children?: Filter[];
+  column?: string;
+  jsonPath?: string[];
+  operator?: string; // =, !=, <, <=, >, >=, like, not like, in, not in, between, is null, is not null
+  value?: any;
+  values?: any[];
+}
+
 // Data editor request
 export interface TableDataRequest {
   database: string;
+  schema?: string; // Postgres schema; '' means public
   table: string;
   page: number;
   pageSize: number;
   orderBy: string;
   orderDir: 'ASC' | 'DESC';
-  filters?: string; // SQL-like filter string
+  filter?: Filter;
+  rawWhere?: string; // SQL pasted into WHERE; can't be combined with filter
+}
+
+// Column values identifying one row
+export type RowKey = Record<string, any>;
+
+// How the rows of a table are identified: primary, unique, locator or row
+export interface RowIdentity {
+  kind: string;
+  columns: string[];
 }
 
 // Data editor response
@@ -114,7 +167,8 @@ export interface TableDataResponse {
   page: number;
   pageSize: number;
   totalPages: number;
-  primaryKey: string;
+  key: RowIdentity;
+  rowKeys: RowKey[];
 }
 
 // Tree node for database explorer
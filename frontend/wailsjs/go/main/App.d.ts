@@ -2,70 +2,140 @@
 // This file is automatically generated. DO NOT EDIT
 import {database} from '../models';
 
-export function AlterTable(arg1:string,arg2:string,arg3:database.TableAlteration):Promise<void>;
+export function ActivateSession(arg1:string):Promise<void>;
+
+export function AlterTable(arg1:string,arg2:string,arg3:string,arg4:string,arg5:database.TableAlteration,arg6:string):Promise<void>;
+
+export function ApplyChangeSet(arg1:string,arg2:database.ChangeSet):Promise<database.ChangeSetResult>;
+
+export function ApplyImport(arg1:database.ImportRequest):Promise<database.ImportResult>;
 
 export function ApplyUpdate(arg1:string):Promise<void>;
 
+export function BeginTransaction(arg1:string,arg2:string):Promise<void>;
+
+export function CancelQuery(arg1:string):Promise<void>;
+
 export function CheckForUpdate():Promise<database.UpdateInfo>;
 
-export function Connect(arg1:database.ConnectionConfig):Promise<void>;
+export function CheckStatement(arg1:string,arg2:string):Promise<database.StatementCheck>;
+
+export function CloseSession(arg1:string,arg2:boolean):Promise<void>;
+
+export function Commit(arg1:string,arg2:string):Promise<void>;
+
+export function Connect(arg1:string,arg2:database.ConnectionConfig):Promise<void>;
 
 export function DeleteConnection(arg1:string):Promise<void>;
 
-export function DeleteRow(arg1:string,arg2:string,arg3:string,arg4:any):Promise<database.ExecuteResult>;
+export function DeleteRow(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Record<string, any>):Promise<database.ExecuteResult>;
+
+export function DeleteRows(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Array<Record<string, any>>):Promise<database.ExecuteResult>;
 
-export function DeleteRows(arg1:string,arg2:string,arg3:string,arg4:Array<any>):Promise<database.ExecuteResult>;
+export function Disconnect(arg1:string,arg2:boolean):Promise<void>;
 
-export function Disconnect():Promise<void>;
+export function DropTable(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<void>;
 
-export function DropTable(arg1:string,arg2:string):Promise<void>;
+export function DryRunStatement(arg1:string,arg2:string):Promise<database.DryRunResult>;
 
-export function ExecuteQuery(arg1:string):Promise<database.QueryResult>;
+export function DuplicateConnection(arg1:string):Promise<database.SavedConnection>;
 
-export function ExecuteStatement(arg1:string):Promise<database.ExecuteResult>;
+export function ExecuteQuery(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<database.QueryResult>;
 
-export function ExportTable(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function ExecuteScript(arg1:string,arg2:string,arg3:string,arg4:string,arg5:database.ScriptOptions):Promise<database.ScriptResult>;
+
+export function ExecuteStatement(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<database.ExecuteResult>;
+
+export function ExportConnections(arg1:database.ExportOptions,arg2:string):Promise<void>;
+
+export function ExportTable(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string):Promise<void>;
+
+export function GetActiveSession():Promise<string>;
 
 export function GetAppVersion():Promise<string>;
 
-export function GetColumns(arg1:string,arg2:string):Promise<Array<database.ColumnInfo>>;
+export function GetColumns(arg1:string,arg2:string,arg3:string,arg4:string):Promise<Array<database.ColumnInfo>>;
+
+export function GetConnectionURI(arg1:string,arg2:boolean):Promise<string>;
+
+export function GetDatabaseSchema(arg1:string,arg2:string,arg3:string):Promise<Record<string, Array<string>>>;
+
+export function GetDatabases(arg1:string):Promise<Array<database.DatabaseInfo>>;
+
+export function GetDistinctValues(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<Array<string>>;
+
+export function GetOpenTransactions(arg1:string):Promise<Array<database.TransactionInfo>>;
 
-export function GetDatabaseSchema(arg1:string):Promise<Record<string, Array<string>>>;
+export function GetSSHConfigHosts():Promise<Array<string>>;
 
-export function GetDatabases():Promise<Array<database.DatabaseInfo>>;
+export function GetSchemas(arg1:string):Promise<Array<string>>;
 
-export function GetDistinctValues(arg1:string,arg2:string,arg3:string):Promise<Array<string>>;
+export function GetSecretBackend():Promise<string>;
 
-export function GetTableData(arg1:database.TableDataRequest):Promise<database.TableDataResponse>;
+export function GetTableData(arg1:string,arg2:database.TableDataRequest):Promise<database.TableDataResponse>;
 
-export function GetTableInfo(arg1:string,arg2:string):Promise<database.TableDetails>;
+export function GetTableInfo(arg1:string,arg2:string,arg3:string,arg4:string):Promise<database.TableDetails>;
 
-export function GetTables(arg1:string):Promise<Array<database.TableInfo>>;
+export function GetTables(arg1:string,arg2:string,arg3:string):Promise<Array<database.TableInfo>>;
 
-export function InsertRow(arg1:string,arg2:string,arg3:Record<string, any>):Promise<database.ExecuteResult>;
+export function InsertRow(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Record<string, any>):Promise<database.ExecuteResult>;
 
-export function IsConnected():Promise<boolean>;
+export function IsConnected(arg1:string):Promise<boolean>;
 
 export function IsFullscreen():Promise<boolean>;
 
+export function IsTransactionOpen(arg1:string,arg2:string):Promise<boolean>;
+
+export function IsVaultLocked():Promise<boolean>;
+
+export function ListConnectionBackups():Promise<Array<database.BackupInfo>>;
+
+export function ListSessions():Promise<Array<database.SessionInfo>>;
+
 export function LoadConnections():Promise<Array<database.SavedConnection>>;
 
+export function LockVault():Promise<void>;
+
+export function MarkConnectionUsed(arg1:string):Promise<void>;
+
+export function MoveConnection(arg1:string,arg2:string,arg3:number):Promise<void>;
+
+export function ParseConnectionURI(arg1:string):Promise<database.ConnectionConfig>;
+
+export function PreviewImport(arg1:database.ImportRequest):Promise<database.ImportPreview>;
+
 export function RenameConnection(arg1:string,arg2:string):Promise<void>;
 
+export function ResolveSSHConfigHost(arg1:string):Promise<database.SSHConfigHost>;
+
 export function RestartApp():Promise<void>;
 
-export function SaveConnection(arg1:string,arg2:database.ConnectionConfig):Promise<void>;
+export function RestoreConnectionBackup(arg1:string):Promise<void>;
+
+export function Rollback(arg1:string,arg2:string):Promise<void>;
+
+export function SaveConnection(arg1:database.SavedConnection):Promise<database.SavedConnection>;
+
+export function SearchConnections(arg1:string):Promise<Array<database.SavedConnection>>;
 
 export function SelectExportPath(arg1:string):Promise<string>;
 
-export function TestConnection(arg1:database.ConnectionConfig):Promise<boolean>;
+export function SelectImportFile():Promise<string>;
+
+export function StreamQuery(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:database.StreamOptions):Promise<void>;
+
+export function TestConnection(arg1:database.ConnectionConfig):Promise<database.ConnectionTestResult>;
 
 export function ToggleFullscreen():Promise<void>;
 
-export function TruncateTable(arg1:string,arg2:string):Promise<void>;
+export function TruncateTable(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<void>;
+
+export function UndoChangeSet(arg1:string,arg2:string):Promise<database.ChangeSetResult>;
+
+export function UnlockVault(arg1:string):Promise<void>;
 
-export function UpdateConnection(arg1:string,arg2:database.ConnectionConfig):Promise<void>;
+export function UpdateConnection(arg1:database.SavedConnection):Promise<void>;
 
-export function UpdateRow(arg1:string,arg2:string,arg3:string,arg4:any,arg5:Record<string, any>):Promise<database.ExecuteResult>;
+export function UpdateRow(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Record<string, any>,arg6:Record<string, any>):Promise<database.ExecuteResult>;
 
-export function UseDatabase(arg1:string):Promise<void>;
+export function UseDatabase(arg1:string,arg2:string):Promise<void>;
@@ -2,118 +2,250 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function AlterTable(arg1, arg2, arg3) {
-  return window['go']['main']['App']['AlterTable'](arg1, arg2, arg3);
+export function ActivateSession(arg1) {
+  return window['go']['main']['App']['ActivateSession'](arg1);
+}
+
+export function AlterTable(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['AlterTable'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function ApplyChangeSet(arg1, arg2) {
+  return window['go']['main']['App']['ApplyChangeSet'](arg1, arg2);
+}
+
+export function ApplyImport(arg1) {
+  return window['go']['main']['App']['ApplyImport'](arg1);
 }
 
 export function ApplyUpdate(arg1) {
   return window['go']['main']['App']['ApplyUpdate'](arg1);
 }
 
+export function BeginTransaction(arg1, arg2) {
+  return window['go']['main']['App']['BeginTransaction'](arg1, arg2);
+}
+
+export function CancelQuery(arg1) {
+  return window['go']['main']['App']['CancelQuery'](arg1);
+}
+
 export function CheckForUpdate() {
   return window['go']['main']['App']['CheckForUpdate']();
 }
 
-export function Connect(arg1) {
-  return window['go']['main']['App']['Connect'](arg1);
+export function CheckStatement(arg1, arg2) {
+  return window['go']['main']['App']['CheckStatement'](arg1, arg2);
+}
+
+export function CloseSession(arg1, arg2) {
+  return window['go']['main']['App']['CloseSession'](arg1, arg2);
+}
+
+export function Commit(arg1, arg2) {
+  return window['go']['main']['App']['Commit'](arg1, arg2);
+}
+
+export function Connect(arg1, arg2) {
+  return window['go']['main']['App']['Connect'](arg1, arg2);
 }
 
 export function DeleteConnection(arg1) {
   return window['go']['main']['App']['DeleteConnection'](arg1);
 }
 
-export function DeleteRow(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['DeleteRow'](arg1, arg2, arg3, arg4);
+export function DeleteRow(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['DeleteRow'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function DeleteRows(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['DeleteRows'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function DeleteRows(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['DeleteRows'](arg1, arg2, arg3, arg4);
+export function Disconnect(arg1, arg2) {
+  return window['go']['main']['App']['Disconnect'](arg1, arg2);
 }
 
-export function Disconnect() {
-  return window['go']['main']['App']['Disconnect']();
+export function DropTable(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['DropTable'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function DropTable(arg1, arg2) {
-  return window['go']['main']['App']['DropTable'](arg1, arg2);
+export function DryRunStatement(arg1, arg2) {
+  return window['go']['main']['App']['DryRunStatement'](arg1, arg2);
 }
 
-export function ExecuteQuery(arg1) {
-  return window['go']['main']['App']['ExecuteQuery'](arg1);
+export function DuplicateConnection(arg1) {
+  return window['go']['main']['App']['DuplicateConnection'](arg1);
 }
 
-export function ExecuteStatement(arg1) {
-  return window['go']['main']['App']['ExecuteStatement'](arg1);
+export function ExecuteQuery(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ExecuteQuery'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function ExportTable(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['ExportTable'](arg1, arg2, arg3, arg4);
+export function ExecuteScript(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ExecuteScript'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ExecuteStatement(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ExecuteStatement'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ExportConnections(arg1, arg2) {
+  return window['go']['main']['App']['ExportConnections'](arg1, arg2);
+}
+
+export function ExportTable(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['ExportTable'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function GetActiveSession() {
+  return window['go']['main']['App']['GetActiveSession']();
 }
 
 export function GetAppVersion() {
   return window['go']['main']['App']['GetAppVersion']();
 }
 
-export function GetColumns(arg1, arg2) {
-  return window['go']['main']['App']['GetColumns'](arg1, arg2);
+export function GetColumns(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetColumns'](arg1, arg2, arg3, arg4);
+}
+
+export function GetConnectionURI(arg1, arg2) {
+  return window['go']['main']['App']['GetConnectionURI'](arg1, arg2);
+}
+
+export function GetDatabaseSchema(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetDatabaseSchema'](arg1, arg2, arg3);
+}
+
+export function GetDatabases(arg1) {
+  return window['go']['main']['App']['GetDatabases'](arg1);
+}
+
+export function GetDistinctValues(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['GetDistinctValues'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function GetOpenTransactions(arg1) {
+  return window['go']['main']['App']['GetOpenTransactions'](arg1);
 }
 
-export function GetDatabaseSchema(arg1) {
-  return window['go']['main']['App']['GetDatabaseSchema'](arg1);
+export function GetSSHConfigHosts() {
+  return window['go']['main']['App']['GetSSHConfigHosts']();
 }
 
-export function GetDatabases() {
-  return window['go']['main']['App']['GetDatabases']();
+export function GetSchemas(arg1) {
+  return window['go']['main']['App']['GetSchemas'](arg1);
 }
 
-export function GetDistinctValues(arg1, arg2, arg3) {
-  return window['go']['main']['App']['GetDistinctValues'](arg1, arg2, arg3);
+export function GetSecretBackend() {
+  return window['go']['main']['App']['GetSecretBackend']();
 }
 
-export function GetTableData(arg1) {
-  return window['go']['main']['App']['GetTableData'](arg1);
+export function GetTableData(arg1, arg2) {
+  return window['go']['main']['App']['GetTableData'](arg1, arg2);
 }
 
-export function GetTableInfo(arg1, arg2) {
-  return window['go']['main']['App']['GetTableInfo'](arg1, arg2);
+export function GetTableInfo(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetTableInfo'](arg1, arg2, arg3, arg4);
 }
 
-export function GetTables(arg1) {
-  return window['go']['main']['App']['GetTables'](arg1);
+export function GetTables(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetTables'](arg1, arg2, arg3);
 }
 
-export function InsertRow(arg1, arg2, arg3) {
-  return window['go']['main']['App']['InsertRow'](arg1, arg2, arg3);
+export function InsertRow(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['InsertRow'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function IsConnected() {
-  return window['go']['main']['App']['IsConnected']();
+export function IsConnected(arg1) {
+  return window['go']['main']['App']['IsConnected'](arg1);
 }
 
 export function IsFullscreen() {
   return window['go']['main']['App']['IsFullscreen']();
 }
 
+export function IsTransactionOpen(arg1, arg2) {
+  return window['go']['main']['App']['IsTransactionOpen'](arg1, arg2);
+}
+
+export function IsVaultLocked() {
+  return window['go']['main']['App']['IsVaultLocked']();
+}
+
+export function ListConnectionBackups() {
+  return window['go']['main']['App']['ListConnectionBackups']();
+}
+
+export function ListSessions() {
+  return window['go']['main']['App']['ListSessions']();
+}
+
 export function LoadConnections() {
   return window['go']['main']['App']['LoadConnections']();
 }
 
+export function LockVault() {
+  return window['go']['main']['App']['LockVault']();
+}
+
+export function MarkConnectionUsed(arg1) {
+  return window['go']['main']['App']['MarkConnectionUsed'](arg1);
+}
+
+export function MoveConnection(arg1, arg2, arg3) {
+  return window['go']['main']['App']['MoveConnection'](arg1, arg2, arg3);
+}
+
+export function ParseConnectionURI(arg1) {
+  return window['go']['main']['App']['ParseConnectionURI'](arg1);
+}
+
+export function PreviewImport(arg1) {
+  return window['go']['main']['App']['PreviewImport'](arg1);
+}
+
 export function RenameConnection(arg1, arg2) {
   return window['go']['main']['App']['RenameConnection'](arg1, arg2);
 }
 
+export function ResolveSSHConfigHost(arg1) {
+  return window['go']['main']['App']['ResolveSSHConfigHost'](arg1);
+}
+
 export function RestartApp() {
   return window['go']['main']['App']['RestartApp']();
 }
 
-export function SaveConnection(arg1, arg2) {
-  return window['go']['main']['App']['SaveConnection'](arg1, arg2);
+export function RestoreConnectionBackup(arg1) {
+  return window['go']['main']['App']['RestoreConnectionBackup'](arg1);
+}
+
+export function Rollback(arg1, arg2) {
+  return window['go']['main']['App']['Rollback'](arg1, arg2);
+}
+
+export function SaveConnection(arg1) {
+  return window['go']['main']['App']['SaveConnection'](arg1);
+}
+
+export function SearchConnections(arg1) {
+  return window['go']['main']['App']['SearchConnections'](arg1);
 }
 
 export function SelectExportPath(arg1) {
   return window['go']['main']['App']['SelectExportPath'](arg1);
 }
 
+export function SelectImportFile() {
+  return window['go']['main']['App']['SelectImportFile']();
+}
+
+export function StreamQuery(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['StreamQuery'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
 export function TestConnection(arg1) {
   return window['go']['main']['App']['TestConnection'](arg1);
 }
@@ -122,18 +254,26 @@ export function ToggleFullscreen() {
   return window['go']['main']['App']['ToggleFullscreen']();
 }
 
-export function TruncateTable(arg1, arg2) {
-  return window['go']['main']['App']['TruncateTable'](arg1, arg2);
+export function TruncateTable(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['TruncateTable'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function UndoChangeSet(arg1, arg2) {
+  return window['go']['main']['App']['UndoChangeSet'](arg1, arg2);
+}
+
+export function UnlockVault(arg1) {
+  return window['go']['main']['App']['UnlockVault'](arg1);
 }
 
-export function UpdateConnection(arg1, arg2) {
-  return window['go']['main']['App']['UpdateConnection'](arg1, arg2);
+export function UpdateConnection(arg1) {
+  return window['go']['main']['App']['UpdateConnection'](arg1);
 }
 
-export function UpdateRow(arg1, arg2, arg3, arg4, arg5) {
-  return window['go']['main']['App']['UpdateRow'](arg1, arg2, arg3, arg4, arg5);
+export function UpdateRow(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['UpdateRow'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
-export function UseDatabase(arg1) {
-  return window['go']['main']['App']['UseDatabase'](arg1);
+export function UseDatabase(arg1, arg2) {
+  return window['go']['main']['App']['UseDatabase'](arg1, arg2);
 }
@@ -1,5 +1,154 @@
 export namespace database {
 	
+	export class BackupInfo {
+	    name: string;
+	    // Go type: time
+	    createdAt: any;
+	    size: number;
+	    connections: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.size = source["size"];
+	        this.connections = source["connections"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ChangeResult {
+	    index: number;
+	    kind: string;
+	    rowsAffected: number;
+	    lastInsertId?: number;
+	    error?: string;
+	    skipped: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.kind = source["kind"];
+	        this.rowsAffected = source["rowsAffected"];
+	        this.lastInsertId = source["lastInsertId"];
+	        this.error = source["error"];
+	        this.skipped = source["skipped"];
+	    }
+	}
+	export class RowChange {
+	    kind: string;
+	    key?: Record<string, any>;
+	    data?: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new RowChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.key = source["key"];
+	        this.data = source["data"];
+	    }
+	}
+	export class ChangeSet {
+	    database: string;
+	    schema?: string;
+	    table: string;
+	    changes: RowChange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangeSet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.database = source["database"];
+	        this.schema = source["schema"];
+	        this.table = source["table"];
+	        this.changes = this.convertValues(source["changes"], RowChange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ChangeSetResult {
+	    changes: ChangeResult[];
+	    committed: boolean;
+	    elapsedMs: number;
+	    undoId?: string;
+	    undoUnavailable?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangeSetResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.changes = this.convertValues(source["changes"], ChangeResult);
+	        this.committed = source["committed"];
+	        this.elapsedMs = source["elapsedMs"];
+	        this.undoId = source["undoId"];
+	        this.undoUnavailable = source["undoUnavailable"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class ColumnInfo {
 	    name: string;
 	    type: string;
@@ -24,6 +173,30 @@ export namespace database {
 	        this.oldName = source["oldName"];
 	    }
 	}
+	export class SSHJumpHost {
+	    host: string;
+	    port: number;
+	    user: string;
+	    password: string;
+	    privateKey: string;
+	    passphrase: string;
+	    useAgent: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SSHJumpHost(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.user = source["user"];
+	        this.password = source["password"];
+	        this.privateKey = source["privateKey"];
+	        this.passphrase = source["passphrase"];
+	        this.useAgent = source["useAgent"];
+	    }
+	}
 	export class ConnectionConfig {
 	    type: string;
 	    host: string;
@@ -31,6 +204,23 @@ export namespace database {
 	    user: string;
 	    password: string;
 	    database: string;
+	    filePath: string;
+	    fileReadOnly: boolean;
+	    readOnly?: boolean;
+	    production?: boolean;
+	    envReferences?: boolean;
+	    extraParams?: Record<string, string>;
+	    maxOpenConns?: number;
+	    maxIdleConns?: number;
+	    connMaxLifetime?: number;
+	    connectTimeout?: number;
+	    statementTimeout?: number;
+	    timeZone?: string;
+	    charset?: string;
+	    collation?: string;
+	    applicationName?: string;
+	    searchPath?: string;
+	    initSQL?: string[];
 	    color: string;
 	    useSSL: boolean;
 	    sslMode: string;
@@ -38,12 +228,15 @@ export namespace database {
 	    sslClientCert: string;
 	    sslClientKey: string;
 	    useSSHTunnel: boolean;
+	    sshConfigHost: string;
 	    sshHost: string;
 	    sshPort: number;
 	    sshUser: string;
 	    sshPassword: string;
 	    sshPrivateKey: string;
 	    sshPassphrase: string;
+	    sshUseAgent: boolean;
+	    sshJumpHosts?: SSHJumpHost[];
 	
 	    static createFrom(source: any = {}) {
 	        return new ConnectionConfig(source);
@@ -57,6 +250,23 @@ export namespace database {
 	        this.user = source["user"];
 	        this.password = source["password"];
 	        this.database = source["database"];
+	        this.filePath = source["filePath"];
+	        this.fileReadOnly = source["fileReadOnly"];
+	        this.readOnly = source["readOnly"];
+	        this.production = source["production"];
+	        this.envReferences = source["envReferences"];
+	        this.extraParams = source["extraParams"];
+	        this.maxOpenConns = source["maxOpenConns"];
+	        this.maxIdleConns = source["maxIdleConns"];
+	        this.connMaxLifetime = source["connMaxLifetime"];
+	        this.connectTimeout = source["connectTimeout"];
+	        this.statementTimeout = source["statementTimeout"];
+	        this.timeZone = source["timeZone"];
+	        this.charset = source["charset"];
+	        this.collation = source["collation"];
+	        this.applicationName = source["applicationName"];
+	        this.searchPath = source["searchPath"];
+	        this.initSQL = source["initSQL"];
 	        this.color = source["color"];
 	        this.useSSL = source["useSSL"];
 	        this.sslMode = source["sslMode"];
@@ -64,12 +274,49 @@ export namespace database {
 	        this.sslClientCert = source["sslClientCert"];
 	        this.sslClientKey = source["sslClientKey"];
 	        this.useSSHTunnel = source["useSSHTunnel"];
+	        this.sshConfigHost = source["sshConfigHost"];
 	        this.sshHost = source["sshHost"];
 	        this.sshPort = source["sshPort"];
 	        this.sshUser = source["sshUser"];
 	        this.sshPassword = source["sshPassword"];
 	        this.sshPrivateKey = source["sshPrivateKey"];
 	        this.sshPassphrase = source["sshPassphrase"];
+	        this.sshUseAgent = source["sshUseAgent"];
+	        this.sshJumpHosts = this.convertValues(source["sshJumpHosts"], SSHJumpHost);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ConnectionTestResult {
+	    success: boolean;
+	    diagnostics: string[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConnectionTestResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.diagnostics = source["diagnostics"];
+	        this.error = source["error"];
 	    }
 	}
 	export class DatabaseInfo {
@@ -84,6 +331,24 @@ export namespace database {
 	        this.name = source["name"];
 	    }
 	}
+	export class DryRunResult {
+	    rowsAffected: number;
+	    kind?: string;
+	    reason?: string;
+	    elapsedMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DryRunResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rowsAffected = source["rowsAffected"];
+	        this.kind = source["kind"];
+	        this.reason = source["reason"];
+	        this.elapsedMs = source["elapsedMs"];
+	    }
+	}
 	export class ExecuteResult {
 	    rowsAffected: number;
 	    lastInsertId: number;
@@ -98,11 +363,241 @@ export namespace database {
 	        this.lastInsertId = source["lastInsertId"];
 	    }
 	}
+	export class ExportOptions {
+	    ids?: string[];
+	    format: string;
+	    passphrase?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ids = source["ids"];
+	        this.format = source["format"];
+	        this.passphrase = source["passphrase"];
+	    }
+	}
+	export class Filter {
+	    logic?: string;
+	    children?: Filter[];
+	    column?: string;
+	    jsonPath?: string[];
+	    operator?: string;
+	    value?: any;
+	    values?: any[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Filter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.logic = source["logic"];
+	        this.children = this.convertValues(source["children"], Filter);
+	        this.column = source["column"];
+	        this.jsonPath = source["jsonPath"];
+	        this.operator = source["operator"];
+	        this.value = source["value"];
+	        this.values = source["values"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SavedConnection {
+	    id: string;
+	    name: string;
+	    folder?: string;
+	    tags?: string[];
+	    environment?: string;
+	    notes?: string;
+	    sortOrder: number;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    lastUsedAt?: any;
+	    config: ConnectionConfig;
+	    missingSecrets?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SavedConnection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.folder = source["folder"];
+	        this.tags = source["tags"];
+	        this.environment = source["environment"];
+	        this.notes = source["notes"];
+	        this.sortOrder = source["sortOrder"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.lastUsedAt = this.convertValues(source["lastUsedAt"], null);
+	        this.config = this.convertValues(source["config"], ConnectionConfig);
+	        this.missingSecrets = source["missingSecrets"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImportItem {
+	    index: number;
+	    action: string;
+	    reason?: string;
+	    existingId?: string;
+	    hasPassword: boolean;
+	    connection: SavedConnection;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.action = source["action"];
+	        this.reason = source["reason"];
+	        this.existingId = source["existingId"];
+	        this.hasPassword = source["hasPassword"];
+	        this.connection = this.convertValues(source["connection"], SavedConnection);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImportPreview {
+	    source: string;
+	    path?: string;
+	    items: ImportItem[];
+	    warnings?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.path = source["path"];
+	        this.items = this.convertValues(source["items"], ImportItem);
+	        this.warnings = source["warnings"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImportRequest {
+	    source: string;
+	    path?: string;
+	    content?: string;
+	    folder?: string;
+	    exclude?: number[];
+	    passphrase?: string;
+	    overwrite?: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.path = source["path"];
+	        this.content = source["content"];
+	        this.folder = source["folder"];
+	        this.exclude = source["exclude"];
+	        this.passphrase = source["passphrase"];
+	        this.overwrite = source["overwrite"];
+	    }
+	}
+	export class ImportResult {
+	    added: number;
+	    updated: number;
+	    skipped: number;
+	    warnings?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = source["added"];
+	        this.updated = source["updated"];
+	        this.skipped = source["skipped"];
+	        this.warnings = source["warnings"];
+	    }
+	}
 	export class IndexInfo {
 	    name: string;
 	    columns: string[];
 	    isUnique: boolean;
 	    isPrimary: boolean;
+	    method?: string;
+	    predicate?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new IndexInfo(source);
@@ -114,6 +609,8 @@ export namespace database {
 	        this.columns = source["columns"];
 	        this.isUnique = source["isUnique"];
 	        this.isPrimary = source["isPrimary"];
+	        this.method = source["method"];
+	        this.predicate = source["predicate"];
 	    }
 	}
 	export class QueryResult {
@@ -132,18 +629,83 @@ export namespace database {
 	        this.rowCount = source["rowCount"];
 	    }
 	}
-	export class SavedConnection {
-	    name: string;
-	    config: ConnectionConfig;
+	
+	export class RowIdentity {
+	    kind: string;
+	    columns: string[];
 	
 	    static createFrom(source: any = {}) {
-	        return new SavedConnection(source);
+	        return new RowIdentity(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.config = this.convertValues(source["config"], ConnectionConfig);
+	        this.kind = source["kind"];
+	        this.columns = source["columns"];
+	    }
+	}
+	export class SSHConfigHost {
+	    alias: string;
+	    hostName: string;
+	    port: number;
+	    user: string;
+	    identityFiles: string[];
+	    proxyJump: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SSHConfigHost(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.alias = source["alias"];
+	        this.hostName = source["hostName"];
+	        this.port = source["port"];
+	        this.user = source["user"];
+	        this.identityFiles = source["identityFiles"];
+	        this.proxyJump = source["proxyJump"];
+	    }
+	}
+	
+	
+	export class ScriptOptions {
+	    continueOnError: boolean;
+	    confirmToken?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.continueOnError = source["continueOnError"];
+	        this.confirmToken = source["confirmToken"];
+	    }
+	}
+	export class StatementResult {
+	    index: number;
+	    statement: string;
+	    line: number;
+	    result?: QueryResult;
+	    rowsAffected: number;
+	    error?: string;
+	    skipped: boolean;
+	    elapsedMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new StatementResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.statement = source["statement"];
+	        this.line = source["line"];
+	        this.result = this.convertValues(source["result"], QueryResult);
+	        this.rowsAffected = source["rowsAffected"];
+	        this.error = source["error"];
+	        this.skipped = source["skipped"];
+	        this.elapsedMs = source["elapsedMs"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -164,6 +726,103 @@ export namespace database {
 		    return a;
 		}
 	}
+	export class ScriptResult {
+	    statements: StatementResult[];
+	    failed: number;
+	    elapsedMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.statements = this.convertValues(source["statements"], StatementResult);
+	        this.failed = source["failed"];
+	        this.elapsedMs = source["elapsedMs"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionInfo {
+	    id: string;
+	    type: string;
+	    host: string;
+	    port: number;
+	    user: string;
+	    database: string;
+	    color: string;
+	    readOnly: boolean;
+	    active: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.type = source["type"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.user = source["user"];
+	        this.database = source["database"];
+	        this.color = source["color"];
+	        this.readOnly = source["readOnly"];
+	        this.active = source["active"];
+	    }
+	}
+	export class StatementCheck {
+	    destructive: boolean;
+	    kind?: string;
+	    reason?: string;
+	    requiresConfirmation: boolean;
+	    confirmToken?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StatementCheck(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.destructive = source["destructive"];
+	        this.kind = source["kind"];
+	        this.reason = source["reason"];
+	        this.requiresConfirmation = source["requiresConfirmation"];
+	        this.confirmToken = source["confirmToken"];
+	    }
+	}
+	
+	export class StreamOptions {
+	    batchSize: number;
+	    maxRows: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new StreamOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.batchSize = source["batchSize"];
+	        this.maxRows = source["maxRows"];
+	    }
+	}
 	export class TableAlteration {
 	    addColumns: ColumnInfo[];
 	    modifyColumns: ColumnInfo[];
@@ -202,12 +861,14 @@ export namespace database {
 	}
 	export class TableDataRequest {
 	    database: string;
+	    schema?: string;
 	    table: string;
 	    page: number;
 	    pageSize: number;
 	    orderBy: string;
 	    orderDir: string;
-	    filters: string;
+	    filter?: Filter;
+	    rawWhere?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new TableDataRequest(source);
@@ -216,13 +877,33 @@ export namespace database {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.database = source["database"];
+	        this.schema = source["schema"];
 	        this.table = source["table"];
 	        this.page = source["page"];
 	        this.pageSize = source["pageSize"];
 	        this.orderBy = source["orderBy"];
 	        this.orderDir = source["orderDir"];
-	        this.filters = source["filters"];
+	        this.filter = this.convertValues(source["filter"], Filter);
+	        this.rawWhere = source["rawWhere"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class TableDataResponse {
 	    columns: ColumnInfo[];
@@ -231,7 +912,8 @@ export namespace database {
 	    page: number;
 	    pageSize: number;
 	    totalPages: number;
-	    primaryKey: string;
+	    key: RowIdentity;
+	    rowKeys: any[];
 	
 	    static createFrom(source: any = {}) {
 	        return new TableDataResponse(source);
@@ -245,7 +927,8 @@ export namespace database {
 	        this.page = source["page"];
 	        this.pageSize = source["pageSize"];
 	        this.totalPages = source["totalPages"];
-	        this.primaryKey = source["primaryKey"];
+	        this.key = this.convertValues(source["key"], RowIdentity);
+	        this.rowKeys = source["rowKeys"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -320,6 +1003,24 @@ export namespace database {
 	        this.createTime = source["createTime"];
 	    }
 	}
+	export class TransactionInfo {
+	    connId: string;
+	    editorId: string;
+	    startedAt: string;
+	    statements: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransactionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connId = source["connId"];
+	        this.editorId = source["editorId"];
+	        this.startedAt = source["startedAt"];
+	        this.statements = source["statements"];
+	    }
+	}
 	export class UpdateInfo {
 	    currentVersion: string;
 	    latestVersion: string;